@@ -70,6 +70,42 @@ func (c *Client) AliasChain(chain, alias string) (bool, error) {
 	return res.Success, err
 }
 
+// StopChain ...
+func (c *Client) StopChain(chain string) (bool, error) {
+	res := &api.SuccessResponse{}
+	err := c.requester.SendRequest("stopChain", &StopChainArgs{
+		Chain: chain,
+	}, res)
+	return res.Success, err
+}
+
+// GetConsensusState ...
+func (c *Client) GetConsensusState(chain string) (interface{}, error) {
+	res := &GetConsensusStateReply{}
+	err := c.requester.SendRequest("getConsensusState", &GetConsensusStateArgs{
+		Chain: chain,
+	}, res)
+	return res.State, err
+}
+
+// SetLoggerLevel ...
+func (c *Client) SetLoggerLevel(loggerName, logLevel, displayLevel string) (bool, error) {
+	res := &api.SuccessResponse{}
+	err := c.requester.SendRequest("setLoggerLevel", &SetLoggerLevelArgs{
+		LoggerName:   loggerName,
+		LogLevel:     logLevel,
+		DisplayLevel: displayLevel,
+	}, res)
+	return res.Success, err
+}
+
+// GetLoggerNames ...
+func (c *Client) GetLoggerNames() ([]string, error) {
+	res := &GetLoggerNamesReply{}
+	err := c.requester.SendRequest("getLoggerNames", struct{}{}, res)
+	return res.LoggerNames, err
+}
+
 // GetChainAliases ...
 func (c *Client) GetChainAliases(chain string) ([]string, error) {
 	res := &GetChainAliasesReply{}
@@ -221,3 +221,22 @@ func TestStacktrace(t *testing.T) {
 		}
 	}
 }
+
+func TestSetLoggerLevel(t *testing.T) {
+	tests := GetSuccessResponseTests()
+
+	for _, test := range tests {
+		mockClient := Client{requester: NewMockClient(api.SuccessResponse{Success: test.Success}, test.Err)}
+		success, err := mockClient.SetLoggerLevel("main", "DEBUG", "")
+		// if there is error as expected, the test passes
+		if err != nil && test.Err != nil {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Unexepcted error: %s", err)
+		}
+		if success != test.Success {
+			t.Fatalf("Expected success response to be: %v, but found: %v", test.Success, success)
+		}
+	}
+}
@@ -28,6 +28,7 @@ const (
 
 var (
 	errAliasTooLong = errors.New("alias length is too long")
+	errNoLogLevel   = errors.New("need to specify either the log level or the display level")
 )
 
 // Admin is the API service for node admin management
@@ -36,10 +37,11 @@ type Admin struct {
 	performance  *Performance
 	chainManager chains.Manager
 	httpServer   *api.Server
+	logFactory   logging.Factory
 }
 
 // NewService returns a new admin API service
-func NewService(log logging.Logger, chainManager chains.Manager, httpServer *api.Server) (*common.HTTPHandler, error) {
+func NewService(log logging.Logger, chainManager chains.Manager, httpServer *api.Server, logFactory logging.Factory) (*common.HTTPHandler, error) {
 	newServer := rpc.NewServer()
 	codec := cjson.NewCodec()
 	newServer.RegisterCodec(codec, "application/json")
@@ -48,6 +50,7 @@ func NewService(log logging.Logger, chainManager chains.Manager, httpServer *api
 		log:          log,
 		chainManager: chainManager,
 		httpServer:   httpServer,
+		logFactory:   logFactory,
 		performance:  NewDefaultPerformanceService(),
 	}, "admin"); err != nil {
 		return nil, err
@@ -153,6 +156,107 @@ func (service *Admin) GetChainAliases(r *http.Request, args *GetChainAliasesArgs
 	return nil
 }
 
+// StopChainArgs are the arguments for calling StopChain
+type StopChainArgs struct {
+	Chain string `json:"chain"`
+}
+
+// StopChain shuts down the chain
+func (service *Admin) StopChain(_ *http.Request, args *StopChainArgs, reply *api.SuccessResponse) error {
+	service.log.Info("Admin: StopChain called with Chain: %s", args.Chain)
+
+	chainID, err := service.chainManager.Lookup(args.Chain)
+	if err != nil {
+		return err
+	}
+
+	if err := service.chainManager.StopChain(chainID); err != nil {
+		return err
+	}
+
+	reply.Success = true
+	return nil
+}
+
+// GetConsensusStateArgs are the arguments for calling GetConsensusState
+type GetConsensusStateArgs struct {
+	Chain string `json:"chain"`
+}
+
+// GetConsensusStateReply is the consensus state of a chain
+type GetConsensusStateReply struct {
+	State interface{} `json:"state"`
+}
+
+// GetConsensusState returns a summary of the consensus state of the chain,
+// including its outstanding polls, for debugging
+func (service *Admin) GetConsensusState(_ *http.Request, args *GetConsensusStateArgs, reply *GetConsensusStateReply) error {
+	service.log.Info("Admin: GetConsensusState called with Chain: %s", args.Chain)
+
+	chainID, err := service.chainManager.Lookup(args.Chain)
+	if err != nil {
+		return err
+	}
+
+	reply.State, err = service.chainManager.ConsensusState(chainID)
+	return err
+}
+
+// SetLoggerLevelArgs are the arguments for calling SetLoggerLevel
+type SetLoggerLevelArgs struct {
+	LoggerName   string `json:"loggerName"`
+	LogLevel     string `json:"logLevel"`
+	DisplayLevel string `json:"displayLevel"`
+}
+
+// SetLoggerLevel sets the log level and/or display level of the logger with
+// the given name. Levels that aren't provided are left unchanged.
+func (service *Admin) SetLoggerLevel(_ *http.Request, args *SetLoggerLevelArgs, reply *api.SuccessResponse) error {
+	service.log.Info("Admin: SetLoggerLevel called with LoggerName: %s, LogLevel: %s, DisplayLevel: %s",
+		args.LoggerName,
+		args.LogLevel,
+		args.DisplayLevel)
+
+	if args.LogLevel == "" && args.DisplayLevel == "" {
+		return errNoLogLevel
+	}
+
+	if args.LogLevel != "" {
+		level, err := logging.ToLevel(args.LogLevel)
+		if err != nil {
+			return err
+		}
+		if err := service.logFactory.SetLogLevel(args.LoggerName, level); err != nil {
+			return err
+		}
+	}
+	if args.DisplayLevel != "" {
+		level, err := logging.ToLevel(args.DisplayLevel)
+		if err != nil {
+			return err
+		}
+		if err := service.logFactory.SetDisplayLevel(args.LoggerName, level); err != nil {
+			return err
+		}
+	}
+
+	reply.Success = true
+	return nil
+}
+
+// GetLoggerNamesReply are the names of the node's loggers
+type GetLoggerNamesReply struct {
+	LoggerNames []string `json:"loggerNames"`
+}
+
+// GetLoggerNames returns the names of the node's loggers
+func (service *Admin) GetLoggerNames(_ *http.Request, _ *struct{}, reply *GetLoggerNamesReply) error {
+	service.log.Info("Admin: GetLoggerNames called")
+
+	reply.LoggerNames = service.logFactory.GetLoggerNames()
+	return nil
+}
+
 // Stacktrace returns the current global stacktrace
 func (service *Admin) Stacktrace(_ *http.Request, _ *struct{}, reply *api.SuccessResponse) error {
 	service.log.Info("Admin: Stacktrace called")
@@ -105,6 +105,42 @@ func (r *router) forceAddRouter(base, endpoint string, handler http.Handler) err
 	return err
 }
 
+// RemoveRouter removes every endpoint of [base], along with the endpoints of
+// [base]'s aliases. The aliases remain reserved, so endpoints that are later
+// added to [base] are aliased again.
+func (r *router) RemoveRouter(base string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.routeLock.Lock()
+	defer r.routeLock.Unlock()
+
+	r.removeRouter(base, make(map[string]bool))
+
+	// Routes can't be removed from a mux.Router, so the remaining routes are
+	// added to a new one
+	r.router = mux.NewRouter()
+	for base, endpoints := range r.routes {
+		for endpoint, handler := range endpoints {
+			url := base + endpoint
+			r.router.Handle(url, handler).Name(url)
+		}
+	}
+}
+
+// removeRouter removes the endpoints of [base] and its aliases, skipping the
+// routes in [removed], as a route may be aliased to itself
+func (r *router) removeRouter(base string, removed map[string]bool) {
+	if removed[base] {
+		return
+	}
+	removed[base] = true
+
+	delete(r.routes, base)
+	for _, alias := range r.aliases[base] {
+		r.removeRouter(alias, removed)
+	}
+}
+
 func (r *router) AddAlias(base string, aliases ...string) error {
 	r.lock.Lock()
 	defer r.lock.Unlock()
@@ -5,6 +5,7 @@ package api
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -75,3 +76,55 @@ func TestBlock(t *testing.T) {
 		t.Fatalf("Permanently locked %s", "1")
 	}
 }
+
+func TestRemoveRouter(t *testing.T) {
+	r := newRouter()
+
+	if err := r.AddAlias("/1", "/2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.AddAlias("/2", "/3"); err != nil {
+		t.Fatal(err)
+	}
+
+	handler1 := &testHandler{}
+	if err := r.AddRouter("/1", "/a", handler1); err != nil {
+		t.Fatal(err)
+	}
+	handler4 := &testHandler{}
+	if err := r.AddRouter("/4", "", handler4); err != nil {
+		t.Fatal(err)
+	}
+
+	r.RemoveRouter("/1")
+	for _, base := range []string{"/1", "/2", "/3"} {
+		if _, err := r.GetHandler(base, "/a"); err == nil {
+			t.Fatalf("Should have removed %s", base)
+		}
+	}
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/1/a", nil))
+	if handler1.called {
+		t.Fatalf("Shouldn't have called the removed handler")
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/4", nil))
+	if !handler4.called {
+		t.Fatalf("Should have kept the other handlers")
+	}
+
+	// The aliases remain, so an endpoint added again is aliased again
+	if err := r.AddRouter("/1", "/a", handler1); err != nil {
+		t.Fatal(err)
+	}
+	if handler, err := r.GetHandler("/3", "/a"); err != nil {
+		t.Fatalf("Should have aliased the endpoint again")
+	} else if handler != handler1 {
+		t.Fatalf("Registered unknown handler")
+	}
+
+	// A route aliased to itself can be removed
+	if err := r.AddAlias("/5", "/5"); err != nil {
+		t.Fatal(err)
+	}
+	r.RemoveRouter("/5")
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/rs/cors"
 
 	"github.com/ava-labs/avalanchego/api/auth"
+	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ava-labs/avalanchego/snow"
 	"github.com/ava-labs/avalanchego/snow/engine/common"
 	"github.com/ava-labs/avalanchego/utils/logging"
@@ -145,6 +146,13 @@ func (s *Server) RegisterChain(chainName string, ctx *snow.Context, vmIntf inter
 	}
 }
 
+// DeregisterChain removes the API endpoints of the chain with [chainID], so
+// that its handlers aren't called once the chain has been stopped
+func (s *Server) DeregisterChain(chainID ids.ID) {
+	s.log.Info("removing routes of chain %s", chainID)
+	s.router.RemoveRouter(fmt.Sprintf("%s/bc/%s", baseURL, chainID))
+}
+
 // AddChainRoute registers a route to a chain's handler
 func (s *Server) AddChainRoute(handler *common.HTTPHandler, ctx *snow.Context, base, endpoint string, loggingWriter io.Writer) error {
 	url := fmt.Sprintf("%s/%s", baseURL, base)
@@ -9,6 +9,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+
 	"github.com/ava-labs/avalanchego/api"
 	"github.com/ava-labs/avalanchego/api/health"
 	"github.com/ava-labs/avalanchego/api/keystore"
@@ -18,6 +20,7 @@ import (
 	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ava-labs/avalanchego/network"
 	"github.com/ava-labs/avalanchego/snow"
+	"github.com/ava-labs/avalanchego/snow/consensus/metrics"
 	"github.com/ava-labs/avalanchego/snow/consensus/snowball"
 	"github.com/ava-labs/avalanchego/snow/engine/avalanche/state"
 	"github.com/ava-labs/avalanchego/snow/engine/avalanche/vertex"
@@ -38,6 +41,7 @@ import (
 	avbootstrap "github.com/ava-labs/avalanchego/snow/engine/avalanche/bootstrap"
 
 	smcon "github.com/ava-labs/avalanchego/snow/consensus/snowman"
+	smpoll "github.com/ava-labs/avalanchego/snow/consensus/snowman/poll"
 	smeng "github.com/ava-labs/avalanchego/snow/engine/snowman"
 	smbootstrap "github.com/ava-labs/avalanchego/snow/engine/snowman/bootstrap"
 )
@@ -53,6 +57,7 @@ const (
 //     RegisterChain with the new chain as the argument.
 //   * Get the aliases associated with a given chain.
 //   * Get the ID of the chain associated with a given alias.
+//   * Stop a chain.
 type Manager interface {
 	// Return the router this Manager is using to route consensus messages to chains
 	Router() router.Router
@@ -85,6 +90,12 @@ type Manager interface {
 	// Returns true iff the chain with the given ID exists and is finished bootstrapping
 	IsBootstrapped(ids.ID) bool
 
+	// Stop the chain with the given ID. Critical chains can't be stopped.
+	StopChain(ids.ID) error
+
+	// Returns a summary of the consensus state of the chain with the given ID
+	ConsensusState(ids.ID) (interface{}, error)
+
 	Shutdown()
 }
 
@@ -106,6 +117,13 @@ type chain struct {
 	Ctx     *snow.Context
 	VM      interface{}
 	Beacons validators.Set
+
+	// DB is the chain's database, which is closed when the chain is stopped
+	DB database.Database
+
+	// registerer tracks the chain's metrics, which are unregistered when the
+	// chain is stopped
+	registerer *chainRegisterer
 }
 
 // ManagerConfig ...
@@ -137,6 +155,7 @@ type ManagerConfig struct {
 	CriticalChains            ids.Set          // Chains that can't exit gracefully
 	WhitelistedSubnets        ids.Set          // Subnets to validate
 	TimeoutManager            *timeout.Manager // Manages request timeouts when sending messages to other validators
+	MaximumTimeout            time.Duration    // The longest a request to another validator can take to time out
 	HealthService             health.Service
 	RetryBootstrap            bool // Should Bootstrap be retried
 	RetryBootstrapMaxAttempts int  // Max number of times to retry bootstrap
@@ -153,14 +172,29 @@ type manager struct {
 	unblocked     bool
 	blockedChains []ChainParameters
 
+	chainsLock sync.Mutex
+
 	// Key: Subnet's ID
 	// Value: Subnet description
 	subnets map[ids.ID]Subnet
 
-	chainsLock sync.Mutex
 	// Key: Chain's ID
 	// Value: The chain
-	chains map[ids.ID]*router.Handler
+	chains map[ids.ID]*chain
+
+	// healthChecks are the IDs of the chains whose health checks have been
+	// registered. A chain's health check outlives the chain, so that the
+	// chain can be stopped and created again.
+	healthChecks ids.Set
+
+	// pollDurations, pollOutcomes, and pollResponses are the poll statistics
+	// shared by the engines of every chain, which are labeled by chain
+	pollDurations *prometheus.HistogramVec
+	pollOutcomes  *prometheus.CounterVec
+	pollResponses *prometheus.CounterVec
+
+	// pollAggregator reports the number of pending polls across every chain
+	pollAggregator metrics.PollAggregator
 }
 
 // New returns a new Manager
@@ -168,9 +202,29 @@ func New(config *ManagerConfig) Manager {
 	m := &manager{
 		ManagerConfig: *config,
 		subnets:       make(map[ids.ID]Subnet),
-		chains:        make(map[ids.ID]*router.Handler),
+		chains:        make(map[ids.ID]*chain),
 	}
 	m.Initialize()
+
+	// If the shared poll statistics can't be registered, each engine falls
+	// back to registering its own
+	var err error
+	m.pollDurations, err = smpoll.NewSharedDurations(constants.PlatformName, m.ConsensusParams.Metrics)
+	if err != nil {
+		m.Log.Error("%s", err)
+	}
+	m.pollOutcomes, err = smpoll.NewSharedOutcomes(constants.PlatformName, m.ConsensusParams.Metrics)
+	if err != nil {
+		m.Log.Error("%s", err)
+	}
+	m.pollResponses, err = smpoll.NewSharedResponses(constants.PlatformName, m.ConsensusParams.Metrics)
+	if err != nil {
+		m.Log.Error("%s", err)
+	}
+	m.pollAggregator, err = metrics.NewPollAggregator(constants.PlatformName, m.ConsensusParams.Metrics)
+	if err != nil {
+		m.Log.Error("%s", err)
+	}
 	return m
 }
 
@@ -213,7 +267,9 @@ func (m *manager) ForceCreateChain(chainParams ChainParameters) {
 		chainParams.VMAlias,
 	)
 
+	m.chainsLock.Lock()
 	sb, exists := m.subnets[chainParams.SubnetID]
+	m.chainsLock.Unlock()
 	if !exists {
 		sb = &subnet{}
 	}
@@ -227,12 +283,11 @@ func (m *manager) ForceCreateChain(chainParams ChainParameters) {
 		return
 	}
 
+	m.chainsLock.Lock()
 	if !exists {
 		m.subnets[chainParams.SubnetID] = sb
 	}
-
-	m.chainsLock.Lock()
-	m.chains[chainParams.ID] = chain.Handler
+	m.chains[chainParams.ID] = chain
 	m.chainsLock.Unlock()
 
 	// Associate the newly created chain with its default alias
@@ -260,6 +315,10 @@ func (m *manager) buildChain(chainParams ChainParameters, sb Subnet) (*chain, er
 		return nil, fmt.Errorf("error while creating chain's log %w", err)
 	}
 
+	// Track the chain's metrics so they can be unregistered if the chain is
+	// stopped
+	registerer := newChainRegisterer(m.ConsensusParams.Metrics)
+
 	ctx := &snow.Context{
 		NetworkID:            m.NetworkID,
 		SubnetID:             chainParams.SubnetID,
@@ -275,7 +334,7 @@ func (m *manager) buildChain(chainParams ChainParameters, sb Subnet) (*chain, er
 		BCLookup:             m,
 		SNLookup:             m,
 		Namespace:            fmt.Sprintf("%s_%s_vm", constants.PlatformName, primaryAlias),
-		Metrics:              m.ConsensusParams.Metrics,
+		Metrics:              registerer,
 		EpochFirstTransition: m.EpochFirstTransition,
 		EpochDuration:        m.EpochDuration,
 	}
@@ -320,6 +379,7 @@ func (m *manager) buildChain(chainParams ChainParameters, sb Subnet) (*chain, er
 
 	consensusParams := m.ConsensusParams
 	consensusParams.Namespace = fmt.Sprintf("%s_%s", constants.PlatformName, primaryAlias)
+	consensusParams.Metrics = registerer
 
 	// The validators of this blockchain
 	var vdrs validators.Set // Validators validating this blockchain
@@ -375,6 +435,7 @@ func (m *manager) buildChain(chainParams ChainParameters, sb Subnet) (*chain, er
 	default:
 		return nil, fmt.Errorf("the vm should have type avalanche.DAGVM or snowman.ChainVM. Chain not created")
 	}
+	chain.registerer = registerer
 
 	// Register the chain with the timeout manager
 	if err := m.TimeoutManager.RegisterChain(ctx, consensusParams.Namespace); err != nil {
@@ -395,6 +456,38 @@ func (m *manager) buildChain(chainParams ChainParameters, sb Subnet) (*chain, er
 	return chain, nil
 }
 
+// registerHealthCheck registers the health check of the chain with ID
+// [chainID], unless it has already been registered. The check reports the
+// health of the chain's current engine, if the chain is running.
+func (m *manager) registerHealthCheck(chainID ids.ID, chainAlias string) error {
+	m.chainsLock.Lock()
+	defer m.chainsLock.Unlock()
+
+	if m.healthChecks.Contains(chainID) {
+		return nil
+	}
+
+	checkFn := func() (interface{}, error) {
+		m.chainsLock.Lock()
+		chain, exists := m.chains[chainID]
+		m.chainsLock.Unlock()
+		if !exists {
+			return fmt.Sprintf("chain %s isn't running", chainID), nil
+		}
+
+		// Grab the context lock before calling the chain's health check
+		ctx := chain.Ctx
+		ctx.Lock.Lock()
+		defer ctx.Lock.Unlock()
+		return chain.Handler.Engine().HealthCheck()
+	}
+	if err := m.HealthService.RegisterCheck(chainAlias, checkFn); err != nil {
+		return fmt.Errorf("couldn't add health check for chain %s: %w", chainAlias, err)
+	}
+	m.healthChecks.Add(chainID)
+	return nil
+}
+
 // Implements Manager.AddRegistrant
 func (m *manager) AddRegistrant(r Registrant) { m.registrants = append(m.registrants, r) }
 
@@ -452,7 +545,7 @@ func (m *manager) createAvalancheChain(
 
 	// Passes messages from the consensus engine to the network
 	sender := sender.Sender{}
-	err = sender.Initialize(ctx, m.Net, m.ManagerConfig.Router, m.TimeoutManager, m.ConsensusParams.Namespace, m.ConsensusParams.Metrics)
+	err = sender.Initialize(ctx, m.Net, m.ManagerConfig.Router, m.TimeoutManager, consensusParams.Namespace, consensusParams.Metrics)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't initialize sender: %w", err)
 	}
@@ -486,8 +579,13 @@ func (m *manager) createAvalancheChain(
 			Manager:    vtxManager,
 			VM:         vm,
 		},
-		Params:    consensusParams,
-		Consensus: &avcon.Topological{},
+		Params:         consensusParams,
+		Consensus:      &avcon.Topological{},
+		PollDurations:  m.pollDurations,
+		PollOutcomes:   m.pollOutcomes,
+		PollResponses:  m.pollResponses,
+		PollAggregator: m.pollAggregator,
+		QueryTimeout:   m.MaximumTimeout,
 	}); err != nil {
 		return nil, fmt.Errorf("error initializing avalanche engine: %w", err)
 	}
@@ -497,14 +595,8 @@ func (m *manager) createAvalancheChain(
 	if err != nil {
 		chainAlias = ctx.ChainID.String()
 	}
-	// Grab the context lock before calling the chain's health check
-	checkFn := func() (interface{}, error) {
-		ctx.Lock.Lock()
-		defer ctx.Lock.Unlock()
-		return engine.HealthCheck()
-	}
-	if err := m.HealthService.RegisterCheck(chainAlias, checkFn); err != nil {
-		return nil, fmt.Errorf("couldn't add health check for chain %s: %w", chainAlias, err)
+	if err := m.registerHealthCheck(ctx.ChainID, chainAlias); err != nil {
+		return nil, err
 	}
 
 	// Asynchronously passes messages from the network to the consensus engine
@@ -528,6 +620,7 @@ func (m *manager) createAvalancheChain(
 		Handler: handler,
 		VM:      vm,
 		Ctx:     ctx,
+		DB:      db,
 	}, nil
 }
 
@@ -606,8 +699,13 @@ func (m *manager) createSnowmanChain(
 			VM:           vm,
 			Bootstrapped: m.unblockChains,
 		},
-		Params:    consensusParams,
-		Consensus: &smcon.Topological{},
+		Params:         consensusParams,
+		Consensus:      &smcon.Topological{},
+		PollDurations:  m.pollDurations,
+		PollOutcomes:   m.pollOutcomes,
+		PollResponses:  m.pollResponses,
+		PollAggregator: m.pollAggregator,
+		QueryTimeout:   m.MaximumTimeout,
 	}); err != nil {
 		return nil, fmt.Errorf("error initializing snowman engine: %w", err)
 	}
@@ -632,14 +730,8 @@ func (m *manager) createSnowmanChain(
 	if err != nil {
 		chainAlias = ctx.ChainID.String()
 	}
-
-	checkFn := func() (interface{}, error) {
-		ctx.Lock.Lock()
-		defer ctx.Lock.Unlock()
-		return engine.HealthCheck()
-	}
-	if err := m.HealthService.RegisterCheck(chainAlias, checkFn); err != nil {
-		return nil, fmt.Errorf("couldn't add health check for chain %s: %w", chainAlias, err)
+	if err := m.registerHealthCheck(ctx.ChainID, chainAlias); err != nil {
+		return nil, err
 	}
 
 	return &chain{
@@ -648,6 +740,7 @@ func (m *manager) createSnowmanChain(
 		Handler: handler,
 		VM:      vm,
 		Ctx:     ctx,
+		DB:      db,
 	}, nil
 }
 
@@ -659,7 +752,7 @@ func (m *manager) SubnetID(chainID ids.ID) (ids.ID, error) {
 	if !exists {
 		return ids.ID{}, errors.New("unknown chain ID")
 	}
-	return chain.Context().SubnetID, nil
+	return chain.Ctx.SubnetID, nil
 }
 
 func (m *manager) IsBootstrapped(id ids.ID) bool {
@@ -670,7 +763,101 @@ func (m *manager) IsBootstrapped(id ids.ID) bool {
 		return false
 	}
 
-	return chain.Engine().IsBootstrapped()
+	return chain.Handler.Engine().IsBootstrapped()
+}
+
+// StopChain shuts down the chain with the given ID and stops routing messages
+// to it. The chain's API handlers are removed, the chain is removed from its
+// subnet, and once its engine has shut down, its metrics are unregistered, its
+// database is closed, and its aliases are removed, so that the chain can be
+// created again. If the engine doesn't shut down within the router's timeout,
+// an error is returned and the chain's resources are released once the engine
+// does shut down.
+func (m *manager) StopChain(chainID ids.ID) error {
+	if m.CriticalChains.Contains(chainID) {
+		return fmt.Errorf("can't stop critical chain %s", chainID)
+	}
+
+	m.chainsLock.Lock()
+	chain, exists := m.chains[chainID]
+	if !exists {
+		m.chainsLock.Unlock()
+		return fmt.Errorf("can't stop unknown chain %s", chainID)
+	}
+	delete(m.chains, chainID)
+	sb, hasSubnet := m.subnets[chain.Ctx.SubnetID]
+	m.chainsLock.Unlock()
+
+	m.Log.Info("stopping chain %s", chainID)
+
+	// API calls must not reach the VM while it is being shut down
+	for _, registrant := range m.registrants {
+		registrant.DeregisterChain(chainID)
+	}
+
+	// Shuts down the chain's engine, which shuts down its poll set and VM
+	m.ManagerConfig.Router.RemoveChain(chainID)
+
+	// A chain that is stopped while bootstrapping would otherwise prevent its
+	// subnet from ever being considered bootstrapped
+	if hasSubnet {
+		sb.removeChain(chainID)
+	}
+
+	// RemoveChain stops waiting for the engine to shut down after a timeout.
+	// The engine may still be using the chain's database and metrics until it
+	// has, so they are only released once it has shut down.
+	select {
+	case <-chain.Handler.Closed():
+		m.releaseChain(chainID, chain)
+		return nil
+	default:
+		go func() {
+			<-chain.Handler.Closed()
+			m.releaseChain(chainID, chain)
+		}()
+		return fmt.Errorf("chain %s didn't shut down in time, its resources will be released once it has", chainID)
+	}
+}
+
+// releaseChain releases the resources of the stopped [chain], which must have
+// shut down, so that the chain can be created again
+func (m *manager) releaseChain(chainID ids.ID, chain *chain) {
+	m.TimeoutManager.DeregisterChain(chainID)
+	chain.registerer.unregisterAll()
+	if err := chain.DB.Close(); err != nil {
+		m.Log.Warn("failed to close the database of chain %s due to %s", chainID, err)
+	}
+	m.RemoveAliases(chainID)
+}
+
+// ConsensusState returns the consensus state reported by the engine of the
+// chain with the given ID, along with the validators benched on the chain
+func (m *manager) ConsensusState(chainID ids.ID) (interface{}, error) {
+	m.chainsLock.Lock()
+	chain, exists := m.chains[chainID]
+	m.chainsLock.Unlock()
+	if !exists {
+		return nil, fmt.Errorf("unknown chain %s", chainID)
+	}
+
+	ctx := chain.Ctx
+	ctx.Lock.Lock()
+	defer ctx.Lock.Unlock()
+
+	engine, ok := chain.Handler.Engine().(common.Debuggable)
+	if !ok {
+		return nil, fmt.Errorf("chain %s doesn't report its consensus state", chainID)
+	}
+	state := engine.ConsensusState()
+
+	benched := m.TimeoutManager.Benched(chainID)
+	benchedStrs := make([]string, len(benched))
+	for i, vdr := range benched {
+		benchedStrs[i] = vdr.PrefixedString(constants.NodeIDPrefix)
+	}
+	state["benched"] = benchedStrs
+	return state, nil
 }
 
 // Shutdown stops all the chains
@@ -0,0 +1,279 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chains
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ava-labs/avalanchego/api"
+	"github.com/ava-labs/avalanchego/api/health"
+	"github.com/ava-labs/avalanchego/api/keystore"
+	"github.com/ava-labs/avalanchego/chains/atomic"
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/database/memdb"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow"
+	"github.com/ava-labs/avalanchego/snow/choices"
+	"github.com/ava-labs/avalanchego/snow/consensus/snowball"
+	"github.com/ava-labs/avalanchego/snow/consensus/snowman"
+	"github.com/ava-labs/avalanchego/snow/engine/common"
+	"github.com/ava-labs/avalanchego/snow/engine/snowman/block"
+	"github.com/ava-labs/avalanchego/snow/networking/benchlist"
+	"github.com/ava-labs/avalanchego/snow/networking/router"
+	"github.com/ava-labs/avalanchego/snow/networking/timeout"
+	"github.com/ava-labs/avalanchego/snow/triggers"
+	"github.com/ava-labs/avalanchego/snow/validators"
+	"github.com/ava-labs/avalanchego/utils/constants"
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"github.com/ava-labs/avalanchego/utils/timer"
+	"github.com/ava-labs/avalanchego/vms"
+
+	avcon "github.com/ava-labs/avalanchego/snow/consensus/avalanche"
+)
+
+var errStaticVM = errors.New("no static API")
+
+type testVMFactory struct {
+	t   *testing.T
+	vms []*block.TestVM
+
+	// shutdown, if non-nil, blocks the VMs from shutting down until it is
+	// closed
+	shutdown chan struct{}
+}
+
+func (f *testVMFactory) New(ctx *snow.Context) (interface{}, error) {
+	if ctx == nil {
+		return nil, errStaticVM
+	}
+
+	genesis := &snowman.TestBlock{TestDecidable: choices.TestDecidable{
+		IDV:     ids.GenerateTestID(),
+		StatusV: choices.Accepted,
+	}}
+
+	vm := &block.TestVM{}
+	vm.T = f.t
+	vm.Default(true)
+	vm.CantBootstrapping = false
+	vm.InitializeF = func(*snow.Context, database.Database, []byte, chan<- common.Message, []*common.Fx) error {
+		return nil
+	}
+	vm.LastAcceptedF = func() (ids.ID, error) { return genesis.ID(), nil }
+	vm.GetBlockF = func(ids.ID) (snowman.Block, error) { return genesis, nil }
+	vm.ShutdownF = func() error {
+		if f.shutdown != nil {
+			<-f.shutdown
+		}
+		return nil
+	}
+	f.vms = append(f.vms, vm)
+	return vm, nil
+}
+
+// testRegistrant records the chains that are deregistered
+type testRegistrant struct {
+	deregistered []ids.ID
+}
+
+func (*testRegistrant) RegisterChain(string, *snow.Context, interface{}) {}
+
+func (r *testRegistrant) DeregisterChain(chainID ids.ID) {
+	r.deregistered = append(r.deregistered, chainID)
+}
+
+// newTestManager returns a manager whose chains never finish bootstrapping,
+// along with the registry of its consensus metrics and its router. The chains'
+// VMs are created by [vmFactory], which is registered as [vmID].
+func newTestManager(t *testing.T, vmID ids.ID, vmFactory *testVMFactory) (*manager, *prometheus.Registry, *router.ChainRouter) {
+	log := logging.NoLog{}
+
+	tm := &timeout.Manager{}
+	err := tm.Initialize(&timer.AdaptiveTimeoutConfig{
+		InitialTimeout:     time.Millisecond,
+		MinimumTimeout:     time.Millisecond,
+		MaximumTimeout:     10 * time.Second,
+		TimeoutCoefficient: 1.25,
+		TimeoutHalflife:    5 * time.Minute,
+		MetricsNamespace:   "",
+		Registerer:         prometheus.NewRegistry(),
+	}, benchlist.NewNoBenchlist())
+	assert.NoError(t, err)
+	go tm.Dispatch()
+
+	chainRouter := &router.ChainRouter{}
+	err = chainRouter.Initialize(ids.ShortEmpty, log, tm, time.Hour, time.Second, ids.Set{}, nil, router.HealthConfig{}, "", prometheus.NewRegistry())
+	assert.NoError(t, err)
+
+	// The beacon never connects, so the chain never finishes bootstrapping
+	vdrs := validators.NewSet()
+	assert.NoError(t, vdrs.AddWeight(ids.GenerateTestShortID(), 1))
+	vdrManager := validators.NewManager()
+	assert.NoError(t, vdrManager.Set(constants.PrimaryNetworkID, vdrs))
+
+	vmManager := vms.NewManager(&api.Server{}, log)
+	assert.NoError(t, vmManager.RegisterVMFactory(vmID, vmFactory))
+
+	decisionEvents := &triggers.EventDispatcher{}
+	decisionEvents.Initialize(log)
+	consensusEvents := &triggers.EventDispatcher{}
+	consensusEvents.Initialize(log)
+
+	registerer := prometheus.NewRegistry()
+	whitelistedSubnets := ids.Set{}
+	whitelistedSubnets.Add(constants.PrimaryNetworkID)
+
+	m := New(&ManagerConfig{
+		MaxPendingMsgs:          1024,
+		MaxNonStakerPendingMsgs: router.DefaultMaxNonStakerPendingMsgs,
+		StakerMSGPortion:        router.DefaultStakerPortion,
+		StakerCPUPortion:        router.DefaultStakerPortion,
+		Log:                     log,
+		LogFactory:              logging.NoFactory{},
+		VMManager:               vmManager,
+		DecisionEvents:          decisionEvents,
+		ConsensusEvents:         consensusEvents,
+		DB:                      memdb.New(),
+		Router:                  chainRouter,
+		ConsensusParams: avcon.Parameters{
+			Parameters: snowball.Parameters{
+				Metrics:               registerer,
+				K:                     1,
+				Alpha:                 1,
+				BetaVirtuous:          1,
+				BetaRogue:             2,
+				ConcurrentRepolls:     1,
+				OptimalProcessing:     1,
+				MaxOutstandingItems:   1,
+				MaxItemProcessingTime: 1,
+			},
+			Parents:   2,
+			BatchSize: 1,
+		},
+		Validators:         vdrManager,
+		Keystore:           &keystore.Keystore{},
+		AtomicMemory:       &atomic.Memory{},
+		TimeoutManager:     tm,
+		HealthService:      health.NewNoOpService(),
+		WhitelistedSubnets: whitelistedSubnets,
+	}).(*manager)
+	return m, registerer, chainRouter
+}
+
+func TestStopBootstrappingChain(t *testing.T) {
+	vmID := ids.GenerateTestID()
+	vmFactory := &testVMFactory{t: t}
+	m, registerer, chainRouter := newTestManager(t, vmID, vmFactory)
+	defer chainRouter.Shutdown()
+
+	registrant := &testRegistrant{}
+	m.AddRegistrant(registrant)
+
+	chainID := ids.GenerateTestID()
+	chainParams := ChainParameters{
+		ID:       chainID,
+		SubnetID: constants.PrimaryNetworkID,
+		VMAlias:  vmID.String(),
+	}
+	m.ForceCreateChain(chainParams)
+
+	_, err := m.SubnetID(chainID)
+	assert.NoError(t, err, "the chain should have been created")
+	assert.False(t, m.IsBootstrapped(chainID), "the chain shouldn't have finished bootstrapping")
+	sb := m.subnets[constants.PrimaryNetworkID]
+	assert.False(t, sb.IsBootstrapped(), "the subnet shouldn't be bootstrapped while the chain is bootstrapping")
+
+	metrics, err := registerer.Gather()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, metrics, "the chain should have registered metrics")
+
+	sharedDurations := false
+	for _, family := range metrics {
+		if family.GetName() != "avalanche_poll_duration" {
+			continue
+		}
+		sharedDurations = true
+		for _, metric := range family.GetMetric() {
+			assert.Equal(t, chainID.String(), metric.GetLabel()[0].GetValue())
+		}
+	}
+	assert.True(t, sharedDurations, "the chain should report its poll durations to the shared histogram")
+
+	stateIntf, err := m.ConsensusState(chainID)
+	assert.NoError(t, err)
+	state, ok := stateIntf.(map[string]interface{})
+	assert.True(t, ok, "the state should be reported as a map")
+	assert.Equal(t, false, state["bootstrapped"])
+	assert.Equal(t, []string{}, state["benched"], "no validators should be benched")
+	assert.Contains(t, state, "polls")
+
+	assert.NoError(t, m.StopChain(chainID))
+	assert.Equal(t, []ids.ID{chainID}, registrant.deregistered, "the chain's API handlers should have been removed")
+
+	_, err = m.SubnetID(chainID)
+	assert.Error(t, err, "the chain should have been removed")
+	assert.True(t, sb.IsBootstrapped(), "the stopped chain should have been removed from its subnet")
+
+	// Only the number of pending polls across all chains should still be
+	// reported
+	metrics, err = registerer.Gather()
+	assert.NoError(t, err)
+	if assert.Len(t, metrics, 1, "the chain's metrics should have been unregistered") {
+		assert.Equal(t, "avalanche_polls", metrics[0].GetName())
+	}
+
+	_, err = m.Lookup(chainID.String())
+	assert.Error(t, err, "the chain's aliases should have been removed")
+
+	// The chain should be able to be created again
+	m.ForceCreateChain(chainParams)
+
+	_, err = m.SubnetID(chainID)
+	assert.NoError(t, err, "the chain should have been created again")
+	assert.Len(t, vmFactory.vms, 2)
+	assert.False(t, sb.IsBootstrapped(), "the subnet shouldn't be bootstrapped while the chain is bootstrapping")
+
+	assert.NoError(t, m.StopChain(chainID))
+}
+
+func TestStopChainReleasesResourcesAfterShutdown(t *testing.T) {
+	vmID := ids.GenerateTestID()
+	vmFactory := &testVMFactory{
+		t:        t,
+		shutdown: make(chan struct{}),
+	}
+	m, registerer, chainRouter := newTestManager(t, vmID, vmFactory)
+	defer chainRouter.Shutdown()
+
+	chainID := ids.GenerateTestID()
+	m.ForceCreateChain(ChainParameters{
+		ID:       chainID,
+		SubnetID: constants.PrimaryNetworkID,
+		VMAlias:  vmID.String(),
+	})
+
+	// The VM doesn't shut down within the router's timeout
+	assert.Error(t, m.StopChain(chainID))
+
+	_, err := m.Lookup(chainID.String())
+	assert.NoError(t, err, "the chain's aliases shouldn't be removed while it is shutting down")
+	metrics, err := registerer.Gather()
+	assert.NoError(t, err)
+	assert.Greater(t, len(metrics), 1, "the chain's metrics shouldn't be unregistered while it is shutting down")
+
+	close(vmFactory.shutdown)
+	assert.Eventually(t, func() bool {
+		_, err := m.Lookup(chainID.String())
+		return err != nil
+	}, 5*time.Second, 10*time.Millisecond, "the chain's aliases should be removed once it has shut down")
+
+	metrics, err = registerer.Gather()
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 1, "the chain's metrics should be unregistered once it has shut down")
+}
@@ -18,6 +18,9 @@ func (mm MockManager) Alias(ids.ID, string) error       { return nil }
 func (mm MockManager) Shutdown()                        {}
 func (mm MockManager) SubnetID(ids.ID) (ids.ID, error)  { return ids.ID{}, nil }
 func (mm MockManager) IsBootstrapped(ids.ID) bool       { return false }
+func (mm MockManager) StopChain(ids.ID) error           { return nil }
+
+func (mm MockManager) ConsensusState(ids.ID) (interface{}, error) { return nil, nil }
 
 func (mm MockManager) Lookup(s string) (ids.ID, error) {
 	id, err := ids.FromString(s)
@@ -0,0 +1,71 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chains
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// chainRegisterer tracks the collectors a chain registers, so they can be
+// unregistered when the chain is stopped
+type chainRegisterer struct {
+	prometheus.Registerer
+
+	lock       sync.Mutex
+	collectors []prometheus.Collector
+}
+
+func newChainRegisterer(registerer prometheus.Registerer) *chainRegisterer {
+	return &chainRegisterer{Registerer: registerer}
+}
+
+// Register implements the prometheus.Registerer interface
+func (r *chainRegisterer) Register(c prometheus.Collector) error {
+	if err := r.Registerer.Register(c); err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.collectors = append(r.collectors, c)
+	return nil
+}
+
+// MustRegister implements the prometheus.Registerer interface
+func (r *chainRegisterer) MustRegister(cs ...prometheus.Collector) {
+	for _, c := range cs {
+		if err := r.Register(c); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Unregister implements the prometheus.Registerer interface
+func (r *chainRegisterer) Unregister(c prometheus.Collector) bool {
+	r.lock.Lock()
+	for i, collector := range r.collectors {
+		if collector == c {
+			r.collectors = append(r.collectors[:i], r.collectors[i+1:]...)
+			break
+		}
+	}
+	r.lock.Unlock()
+
+	return r.Registerer.Unregister(c)
+}
+
+// unregisterAll unregisters every collector that is still registered
+func (r *chainRegisterer) unregisterAll() {
+	r.lock.Lock()
+	collectors := r.collectors
+	r.collectors = nil
+	r.lock.Unlock()
+
+	for _, c := range collectors {
+		r.Registerer.Unregister(c)
+	}
+}
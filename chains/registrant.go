@@ -4,10 +4,13 @@
 package chains
 
 import (
+	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ava-labs/avalanchego/snow"
 )
 
-// Registrant can register the existence of a chain
+// Registrant can register the existence of a chain, and is notified when the
+// chain is stopped
 type Registrant interface {
 	RegisterChain(name string, ctx *snow.Context, vm interface{})
+	DeregisterChain(chainID ids.ID)
 }
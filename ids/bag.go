@@ -144,6 +144,18 @@ func (b *Bag) Split(index uint) [2]Bag {
 	return splitVotes
 }
 
+// Scale returns the bag of ids with the same ids as this bag, except all the
+// counts are multiplied by factor.
+//
+// factor must be >= 1
+func (b *Bag) Scale(factor int) Bag {
+	newBag := Bag{}
+	for vote, count := range b.counts {
+		newBag.AddCount(vote, count*factor)
+	}
+	return newBag
+}
+
 func (b *Bag) String() string {
 	sb := strings.Builder{}
 
@@ -185,6 +185,40 @@ func TestBagSplit(t *testing.T) {
 	}
 }
 
+func TestBagScale(t *testing.T) {
+	id0 := Empty
+	id1 := ID{1}
+
+	bag := Bag{}
+	bag.AddCount(id0, 2)
+	bag.AddCount(id1, 3)
+
+	identity := bag.Scale(1)
+	if !identity.Equals(bag) {
+		t.Fatalf("Bag.Scale(1) returned %s expected %s", &identity, &bag)
+	}
+
+	scaled := bag.Scale(4)
+	if count := scaled.Count(id0); count != 8 {
+		t.Fatalf("Bag.Count returned %d expected %d", count, 8)
+	} else if count := scaled.Count(id1); count != 12 {
+		t.Fatalf("Bag.Count returned %d expected %d", count, 12)
+	} else if size := scaled.Len(); size != 20 {
+		t.Fatalf("Bag.Len returned %d expected %d", size, 20)
+	} else if mode, freq := scaled.Mode(); mode != id1 {
+		t.Fatalf("Bag.Mode[0] returned %s expected %s", mode, id1)
+	} else if freq != 12 {
+		t.Fatalf("Bag.Mode[1] returned %d expected %d", freq, 12)
+	} else if count := bag.Count(id0); count != 2 {
+		t.Fatalf("Bag.Scale modified the original bag")
+	}
+
+	empty := Bag{}
+	if scaled := empty.Scale(4); scaled.Len() != 0 {
+		t.Fatalf("Bag.Len returned %d expected %d", scaled.Len(), 0)
+	}
+}
+
 func TestBagString(t *testing.T) {
 	id0 := Empty
 
@@ -3,7 +3,11 @@
 
 package ids
 
-import "strings"
+import (
+	"strings"
+
+	"github.com/ava-labs/avalanchego/utils/hashing"
+)
 
 const (
 	minShortSetSize = 16
@@ -102,6 +106,19 @@ func (ids ShortSet) Equals(oIDs ShortSet) bool {
 	return true
 }
 
+// Hash returns an ID derived from the elements of the set. The hash doesn't
+// depend on the order the elements were added to the set.
+func (ids ShortSet) Hash() ID {
+	idList := ids.List()
+	SortShortIDs(idList)
+
+	bytes := make([]byte, 0, len(idList)*len(ShortID{}))
+	for _, id := range idList {
+		bytes = append(bytes, id[:]...)
+	}
+	return ID(hashing.ComputeHash256Array(bytes))
+}
+
 // String returns the string representation of a set
 func (ids ShortSet) String() string {
 	sb := strings.Builder{}
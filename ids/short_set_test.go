@@ -164,6 +164,31 @@ func TestShortSetEquals(t *testing.T) {
 	}
 }
 
+func TestShortSetHash(t *testing.T) {
+	set := ShortSet{}
+	set.Add(ShortID{1}, ShortID{2}, ShortID{3})
+
+	otherSet := ShortSet{}
+	otherSet.Add(ShortID{3}, ShortID{1}, ShortID{2})
+	if set.Hash() != otherSet.Hash() {
+		t.Fatal("Equal sets should have the same hash")
+	}
+
+	otherSet.Add(ShortID{4})
+	if set.Hash() == otherSet.Hash() {
+		t.Fatal("Unequal sets should have different hashes")
+	}
+
+	otherSet.Remove(ShortID{4}, ShortID{3})
+	if set.Hash() == otherSet.Hash() {
+		t.Fatal("Unequal sets should have different hashes")
+	}
+
+	if (ShortSet{}).Hash() == set.Hash() {
+		t.Fatal("Unequal sets should have different hashes")
+	}
+}
+
 func TestShortSetList(t *testing.T) {
 	set := ShortSet{}
 	otherSet := ShortSet{}
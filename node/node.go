@@ -576,6 +576,7 @@ func (n *Node) initChainManager(avaxAssetID ids.ID) error {
 		XChainID:                  xChainID,
 		CriticalChains:            criticalChains,
 		TimeoutManager:            timeoutManager,
+		MaximumTimeout:            n.Config.NetworkConfig.MaximumTimeout,
 		HealthService:             n.healthService,
 		WhitelistedSubnets:        n.Config.WhitelistedSubnets,
 		RetryBootstrap:            n.Config.RetryBootstrap,
@@ -627,11 +628,52 @@ func (n *Node) initChainManager(avaxAssetID ids.ID) error {
 		return errs.Err
 	}
 
+	if err := n.registerPluginVMs(); err != nil {
+		return err
+	}
+
 	// Notify the API server when new chains are created
 	n.chainManager.AddRegistrant(&n.APIServer)
 	return nil
 }
 
+// registerPluginVMs registers a VM for each binary in the plugin directory
+// that is named by a VM ID. Binaries with other names, such as the builtin
+// evm plugin, and VM IDs that are already registered are skipped.
+func (n *Node) registerPluginVMs() error {
+	files, err := ioutil.ReadDir(n.Config.PluginDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't read plugin directory %s: %w", n.Config.PluginDir, err)
+	}
+
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		name := file.Name()
+		vmID, err := ids.FromString(name)
+		if err != nil {
+			n.Log.Debug("skipping plugin %s since it isn't named by a VM ID", name)
+			continue
+		}
+		if _, err := n.vmManager.GetVMFactory(vmID); err == nil {
+			n.Log.Debug("skipping plugin %s since the VM is already registered", name)
+			continue
+		}
+
+		n.Log.Info("registering VM %s from the plugin directory", vmID)
+		if err := n.vmManager.RegisterVMFactory(vmID, &rpcchainvm.Factory{
+			Path: filepath.Join(n.Config.PluginDir, name),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // initSharedMemory initializes the shared memory for cross chain interation
 func (n *Node) initSharedMemory() error {
 	n.Log.Info("initializing SharedMemory")
@@ -691,7 +733,7 @@ func (n *Node) initAdminAPI() error {
 		return nil
 	}
 	n.Log.Info("initializing admin API")
-	service, err := admin.NewService(n.Log, n.chainManager, &n.APIServer)
+	service, err := admin.NewService(n.Log, n.chainManager, &n.APIServer, n.LogFactory)
 	if err != nil {
 		return err
 	}
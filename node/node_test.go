@@ -0,0 +1,92 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package node
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ava-labs/avalanchego/api"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow"
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"github.com/ava-labs/avalanchego/vms"
+	"github.com/ava-labs/avalanchego/vms/rpcchainvm"
+)
+
+type testVMFactory struct{}
+
+func (testVMFactory) New(*snow.Context) (interface{}, error) { return nil, nil }
+
+func TestRegisterPluginVMs(t *testing.T) {
+	pluginDir, err := ioutil.TempDir("", "plugins")
+	assert.NoError(t, err)
+	defer os.RemoveAll(pluginDir)
+
+	newVMID := ids.GenerateTestID()
+	registeredVMID := ids.GenerateTestID()
+	dirVMID := ids.GenerateTestID()
+
+	// The plugins are never run, so they don't need to be valid binaries
+	for _, name := range []string{newVMID.String(), registeredVMID.String(), "evm", "not a VM ID"} {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(pluginDir, name), nil, 0600))
+	}
+	assert.NoError(t, os.Mkdir(filepath.Join(pluginDir, dirVMID.String()), 0700))
+
+	log := logging.NoLog{}
+	vmManager := vms.NewManager(&api.Server{}, log)
+	registeredFactory := testVMFactory{}
+	assert.NoError(t, vmManager.RegisterVMFactory(registeredVMID, registeredFactory))
+
+	n := &Node{
+		Log:       log,
+		Config:    &Config{PluginDir: pluginDir},
+		vmManager: vmManager,
+	}
+	assert.NoError(t, n.registerPluginVMs())
+
+	factory, err := vmManager.GetVMFactory(newVMID)
+	if assert.NoError(t, err, "the plugin named by a VM ID should have been registered") {
+		assert.Equal(t, &rpcchainvm.Factory{Path: filepath.Join(pluginDir, newVMID.String())}, factory)
+	}
+
+	factory, err = vmManager.GetVMFactory(registeredVMID)
+	assert.NoError(t, err)
+	assert.Equal(t, registeredFactory, factory, "the plugin of an already registered VM shouldn't replace it")
+
+	_, err = vmManager.GetVMFactory(dirVMID)
+	assert.Error(t, err, "a directory shouldn't be registered as a plugin")
+
+	_, err = vmManager.Lookup("evm")
+	assert.Error(t, err, "a plugin that isn't named by a VM ID shouldn't be registered")
+}
+
+func TestRegisterPluginVMsMissingDir(t *testing.T) {
+	log := logging.NoLog{}
+	n := &Node{
+		Log:       log,
+		Config:    &Config{PluginDir: filepath.Join(os.TempDir(), ids.GenerateTestID().String())},
+		vmManager: vms.NewManager(&api.Server{}, log),
+	}
+	assert.NoError(t, n.registerPluginVMs(), "a missing plugin directory shouldn't be an error")
+}
+
+func TestRegisterPluginVMsUnreadableDir(t *testing.T) {
+	pluginFile, err := ioutil.TempFile("", "plugins")
+	assert.NoError(t, err)
+	defer os.Remove(pluginFile.Name())
+	assert.NoError(t, pluginFile.Close())
+
+	log := logging.NoLog{}
+	n := &Node{
+		Log:       log,
+		Config:    &Config{PluginDir: pluginFile.Name()},
+		vmManager: vms.NewManager(&api.Server{}, log),
+	}
+	assert.Error(t, n.registerPluginVMs(), "a plugin directory that isn't a directory should be an error")
+}
@@ -4,7 +4,9 @@
 package poll
 
 import (
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/ava-labs/avalanchego/ids"
 )
@@ -12,10 +14,23 @@ import (
 // Set is a collection of polls
 type Set interface {
 	fmt.Stringer
+	json.Marshaler
 
 	Add(requestID uint32, vdrs ids.ShortBag) bool
 	Vote(requestID uint32, vdr ids.ShortID, votes []ids.ID) (ids.UniqueBag, bool)
 	Len() int
+
+	// Timeout finishes the outstanding poll with [requestID] with the votes
+	// it has received so far, treating every validator that hasn't responded
+	// as dropped
+	Timeout(requestID uint32) (ids.UniqueBag, bool)
+
+	// Age returns the length of time the outstanding poll with [requestID]
+	// has existed. Returns false if there is no such poll.
+	Age(requestID uint32) (time.Duration, bool)
+
+	// Shutdown unregisters the set's metrics
+	Shutdown() error
 }
 
 // Poll is an outstanding poll
@@ -4,28 +4,113 @@
 package poll
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow/consensus/metrics"
 	"github.com/ava-labs/avalanchego/utils/logging"
 	"github.com/ava-labs/avalanchego/utils/timer"
 )
 
+var errFailedUnregister = errors.New("failed to unregister poll statistics")
+
 type poll struct {
 	Poll
 	start time.Time
+
+	// vdrs are the validators that were polled
+	vdrs ids.ShortSet
+	// responded are the validators that have responded to the poll
+	responded ids.ShortSet
+	// dropped are the validators that responded without any votes, which
+	// includes validators whose queries failed
+	dropped ids.ShortSet
+}
+
+const (
+	chainLabel   = "chain"
+	outcomeLabel = "outcome"
+
+	// Outcomes of polls
+	successfulOutcome = "successful"
+	failedOutcome     = "failed"
+
+	// Outcomes of polled validators
+	votedOutcome   = "voted"
+	droppedOutcome = "dropped"
+
+	// expiryChecksPerMaxAge is the number of times stale polls are checked for
+	// per MaxPollAge
+	expiryChecksPerMaxAge = 4
+)
+
+// SetConfig configures a set of polls
+type SetConfig struct {
+	Factory    Factory
+	Log        logging.Logger
+	Namespace  string
+	Registerer prometheus.Registerer
+
+	// Aggregator, if non-nil, is used to report statistics across multiple
+	// sets. The set is registered on creation and deregistered on shutdown.
+	Aggregator metrics.PollAggregator
+
+	// SharedDurations, if non-nil, is used to report poll durations rather
+	// than registering a histogram per set. Observations are labeled with
+	// [Chain].
+	SharedDurations *prometheus.HistogramVec
+	Chain           string
+
+	// SharedOutcomes and SharedResponses, if non-nil, are used to report the
+	// outcomes of polls and responses rather than registering counters per
+	// set. Like SharedDurations, observations are labeled with [Chain].
+	SharedOutcomes  *prometheus.CounterVec
+	SharedResponses *prometheus.CounterVec
+
+	// MaxPollAge is the duration after which an outstanding poll is expired.
+	// Expired polls are finished as if every validator that hasn't responded
+	// was dropped, and their results are discarded. If 0, polls never expire.
+	MaxPollAge time.Duration
 }
 
 type set struct {
-	log      logging.Logger
-	numPolls prometheus.Gauge
-	durPolls prometheus.Histogram
-	factory  Factory
-	polls    map[uint32]poll
+	// pending is the number of polls in [polls]. It is updated atomically so
+	// that Len can be called by the aggregator concurrently with the set's
+	// other methods.
+	pending int64
+
+	// lock guards polls, which are expired by [expirer] concurrently with the
+	// set's other methods
+	lock sync.Mutex
+
+	config             SetConfig
+	log                logging.Logger
+	numPolls           prometheus.Gauge
+	durPolls           prometheus.Observer
+	numSuccessfulPolls prometheus.Counter
+	numFailedPolls     prometheus.Counter
+	numExpiredPolls    prometheus.Counter
+	numTimedOutPolls   prometheus.Counter
+	numVotes           prometheus.Counter
+	numDrops           prometheus.Counter
+	factory            Factory
+	polls              map[uint32]poll
+
+	// expirer, if non-nil, expires stale polls every MaxPollAge /
+	// expiryChecksPerMaxAge
+	expirer *timer.Repeater
+
+	// metrics are the metrics registered by this set, which are unregistered
+	// on shutdown
+	metrics []prometheus.Collector
 }
 
 // NewSet returns a new empty set of polls
@@ -35,38 +120,129 @@ func NewSet(
 	namespace string,
 	registerer prometheus.Registerer,
 ) Set {
+	return NewSetWithConfig(SetConfig{
+		Factory:    factory,
+		Log:        log,
+		Namespace:  namespace,
+		Registerer: registerer,
+	})
+}
+
+// NewSetWithConfig returns a new empty set of polls configured by [config]
+func NewSetWithConfig(config SetConfig) Set {
+	log := config.Log
+
 	numPolls := prometheus.NewGauge(prometheus.GaugeOpts{
-		Namespace: namespace,
+		Namespace: config.Namespace,
 		Name:      "polls",
 		Help:      "Number of pending network polls",
 	})
-	if err := registerer.Register(numPolls); err != nil {
+	if err := config.Registerer.Register(numPolls); err != nil {
 		log.Error("failed to register polls statistics due to %s", err)
 	}
+	collectors := []prometheus.Collector{numPolls}
+
+	var durPolls prometheus.Observer
+	if config.SharedDurations != nil {
+		durPolls = config.SharedDurations.WithLabelValues(config.Chain)
+	} else {
+		histogram := prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: config.Namespace,
+			Name:      "poll_duration",
+			Help:      "Length of time the poll existed in milliseconds",
+			Buckets:   timer.MillisecondsBuckets,
+		})
+		if err := config.Registerer.Register(histogram); err != nil {
+			log.Error("failed to register poll_duration statistics due to %s", err)
+		}
+		durPolls = histogram
+		collectors = append(collectors, histogram)
+	}
 
-	durPolls := prometheus.NewHistogram(prometheus.HistogramOpts{
-		Namespace: namespace,
-		Name:      "poll_duration",
-		Help:      "Length of time the poll existed in milliseconds",
-		Buckets:   timer.MillisecondsBuckets,
+	var outcomes *prometheus.CounterVec
+	if config.SharedOutcomes != nil {
+		outcomes = config.SharedOutcomes.MustCurryWith(prometheus.Labels{chainLabel: config.Chain})
+	} else {
+		outcomes = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: config.Namespace,
+			Name:      "poll_outcomes",
+			Help:      "Number of polls that finished successfully or finished without any votes",
+		}, []string{outcomeLabel})
+		if err := config.Registerer.Register(outcomes); err != nil {
+			log.Error("failed to register poll_outcomes statistics due to %s", err)
+		}
+		collectors = append(collectors, outcomes)
+	}
+
+	var responses *prometheus.CounterVec
+	if config.SharedResponses != nil {
+		responses = config.SharedResponses.MustCurryWith(prometheus.Labels{chainLabel: config.Chain})
+	} else {
+		responses = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: config.Namespace,
+			Name:      "poll_responses",
+			Help:      "Number of polled validators that voted or were dropped",
+		}, []string{outcomeLabel})
+		if err := config.Registerer.Register(responses); err != nil {
+			log.Error("failed to register poll_responses statistics due to %s", err)
+		}
+		collectors = append(collectors, responses)
+	}
+
+	numExpiredPolls := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Name:      "expired_polls",
+		Help:      "Number of polls finished due to exceeding the maximum poll age",
+	})
+	if config.MaxPollAge > 0 {
+		if err := config.Registerer.Register(numExpiredPolls); err != nil {
+			log.Error("failed to register expired_polls statistics due to %s", err)
+		}
+		collectors = append(collectors, numExpiredPolls)
+	}
+
+	numTimedOutPolls := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Name:      "poll_expired",
+		Help:      "Number of polls finished by timing out with the votes received so far",
 	})
-	if err := registerer.Register(durPolls); err != nil {
-		log.Error("failed to register poll_duration statistics due to %s", err)
+	if err := config.Registerer.Register(numTimedOutPolls); err != nil {
+		log.Error("failed to register poll_expired statistics due to %s", err)
 	}
+	collectors = append(collectors, numTimedOutPolls)
 
-	return &set{
-		log:      log,
-		numPolls: numPolls,
-		durPolls: durPolls,
-		factory:  factory,
-		polls:    make(map[uint32]poll),
+	s := &set{
+		config:             config,
+		log:                log,
+		numPolls:           numPolls,
+		durPolls:           durPolls,
+		numSuccessfulPolls: outcomes.WithLabelValues(successfulOutcome),
+		numFailedPolls:     outcomes.WithLabelValues(failedOutcome),
+		numExpiredPolls:    numExpiredPolls,
+		numTimedOutPolls:   numTimedOutPolls,
+		numVotes:           responses.WithLabelValues(votedOutcome),
+		numDrops:           responses.WithLabelValues(droppedOutcome),
+		factory:            config.Factory,
+		polls:              make(map[uint32]poll),
+		metrics:            collectors,
+	}
+	if config.MaxPollAge > 0 {
+		s.expirer = timer.NewRepeater(s.expireStale, config.MaxPollAge/expiryChecksPerMaxAge)
+		go s.expirer.Dispatch()
+	}
+	if config.Aggregator != nil {
+		config.Aggregator.Register(s)
 	}
+	return s
 }
 
 // Add to the current set of polls
 // Returns true if the poll was registered correctly and the network sample
 //         should be made.
 func (s *set) Add(requestID uint32, vdrs ids.ShortBag) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
 	if _, exists := s.polls[requestID]; exists {
 		s.log.Debug("dropping poll due to duplicated requestID: %d", requestID)
 		return false
@@ -76,10 +252,16 @@ func (s *set) Add(requestID uint32, vdrs ids.ShortBag) bool {
 		requestID,
 		&vdrs)
 
+	polled := ids.ShortSet{}
+	polled.Add(vdrs.List()...)
 	s.polls[requestID] = poll{
-		Poll:  s.factory.New(vdrs), // create the new poll
-		start: time.Now(),
+		Poll:      s.factory.New(vdrs), // create the new poll
+		start:     time.Now(),
+		vdrs:      polled,
+		responded: ids.ShortSet{},
+		dropped:   ids.ShortSet{},
 	}
+	atomic.AddInt64(&s.pending, 1)
 	s.numPolls.Inc() // increase the metrics
 	return true
 }
@@ -91,6 +273,9 @@ func (s *set) Vote(
 	vdr ids.ShortID,
 	votes []ids.ID,
 ) (ids.UniqueBag, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
 	poll, exists := s.polls[requestID]
 	if !exists {
 		s.log.Verbo("dropping vote from %s to an unknown poll with requestID: %d",
@@ -104,23 +289,212 @@ func (s *set) Vote(
 		requestID,
 		votes)
 
+	if poll.vdrs.Contains(vdr) && !poll.responded.Contains(vdr) {
+		poll.responded.Add(vdr)
+		if len(votes) == 0 {
+			poll.dropped.Add(vdr)
+			s.numDrops.Inc()
+		} else {
+			s.numVotes.Inc()
+		}
+	}
+
 	poll.Vote(vdr, votes)
 	if !poll.Finished() {
 		return nil, false
 	}
+	return s.finish(requestID, poll), true
+}
+
+// expireStale finishes every poll that has been outstanding for at least
+// MaxPollAge, treating the validators that haven't responded as dropped
+func (s *set) expireStale() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for requestID, poll := range s.polls {
+		age := time.Since(poll.start)
+		if age < s.config.MaxPollAge {
+			continue
+		}
+
+		s.log.Debug("expiring poll with requestID %d after %s", requestID, age)
+		s.dropPending(poll)
+		s.finish(requestID, poll)
+		s.numExpiredPolls.Inc()
+	}
+}
+
+// Timeout finishes the outstanding poll with [requestID] with the votes it has
+// received so far, treating every validator that hasn't responded as dropped.
+// Returns the result of the poll and true if the poll was outstanding.
+func (s *set) Timeout(requestID uint32) (ids.UniqueBag, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	poll, exists := s.polls[requestID]
+	if !exists {
+		s.log.Verbo("dropping timeout of an unknown poll with requestID: %d", requestID)
+		return nil, false
+	}
+
+	s.log.Debug("timing out poll with requestID %d after %s", requestID, time.Since(poll.start))
+	s.dropPending(poll)
+	result := s.finish(requestID, poll)
+	s.numTimedOutPolls.Inc()
+	return result, true
+}
+
+// Age returns the length of time the outstanding poll with [requestID] has
+// existed
+func (s *set) Age(requestID uint32) (time.Duration, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	poll, exists := s.polls[requestID]
+	if !exists {
+		return 0, false
+	}
+	return time.Since(poll.start), true
+}
 
+// dropPending drops every validator from [poll] that hasn't responded
+// Assumes the lock is held
+func (s *set) dropPending(poll poll) {
+	for _, vdr := range poll.vdrs.List() {
+		if !poll.responded.Contains(vdr) {
+			poll.responded.Add(vdr)
+			poll.dropped.Add(vdr)
+			s.numDrops.Inc()
+			poll.Vote(vdr, nil)
+		}
+	}
+}
+
+// finish removes the finished poll and returns its result
+// Assumes the lock is held
+func (s *set) finish(requestID uint32, poll poll) ids.UniqueBag {
 	s.log.Verbo("poll with requestID %d finished as %s", requestID, poll)
 
 	delete(s.polls, requestID) // remove the poll from the current set
+	atomic.AddInt64(&s.pending, -1)
 	s.durPolls.Observe(float64(time.Since(poll.start).Milliseconds()))
 	s.numPolls.Dec() // decrease the metrics
-	return poll.Result(), true
+
+	result := poll.Result()
+	if len(result) == 0 {
+		s.numFailedPolls.Inc()
+	} else {
+		s.numSuccessfulPolls.Inc()
+	}
+	return result
 }
 
 // Len returns the number of outstanding polls
-func (s *set) Len() int { return len(s.polls) }
+func (s *set) Len() int { return int(atomic.LoadInt64(&s.pending)) }
+
+// Shutdown stops expiring stale polls and unregisters the set's metrics
+func (s *set) Shutdown() error {
+	// The expirer acquires the lock, so it must be stopped without holding the
+	// lock
+	if s.expirer != nil {
+		s.expirer.Stop()
+	}
+
+	if s.config.Aggregator != nil {
+		s.config.Aggregator.Deregister(s)
+	}
+
+	// The shared collectors are owned by the caller, so only this set's
+	// observations are removed from them
+	if s.config.SharedDurations != nil {
+		s.config.SharedDurations.DeleteLabelValues(s.config.Chain)
+	}
+	if s.config.SharedOutcomes != nil {
+		for _, outcome := range []string{successfulOutcome, failedOutcome} {
+			s.config.SharedOutcomes.DeleteLabelValues(s.config.Chain, outcome)
+		}
+	}
+	if s.config.SharedResponses != nil {
+		for _, outcome := range []string{votedOutcome, droppedOutcome} {
+			s.config.SharedResponses.DeleteLabelValues(s.config.Chain, outcome)
+		}
+	}
+
+	// Every metric is unregistered, even if some fail, so a failure doesn't
+	// leak the remaining metrics
+	failed := []string(nil)
+	for _, metric := range s.metrics {
+		if !s.config.Registerer.Unregister(metric) {
+			failed = append(failed, metricName(metric))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%w: %s", errFailedUnregister, strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// metricName returns the fully qualified name of [collector]
+func metricName(collector prometheus.Collector) string {
+	metric, ok := collector.(prometheus.Metric)
+	if !ok {
+		return fmt.Sprintf("%T", collector)
+	}
+	desc := metric.Desc().String()
+	name := ""
+	if _, err := fmt.Sscanf(desc, "Desc{fqName: %q", &name); err != nil {
+		return desc
+	}
+	return name
+}
+
+type pollJSON struct {
+	RequestID  uint32   `json:"requestID"`
+	AgeMs      int64    `json:"ageMs"`
+	Validators []string `json:"validators"`
+	Responded  []string `json:"responded"`
+	Dropped    []string `json:"dropped"`
+}
+
+type setJSON struct {
+	Pending int        `json:"pending"`
+	Polls   []pollJSON `json:"polls"`
+}
+
+// MarshalJSON returns a summary of the outstanding polls
+func (s *set) MarshalJSON() ([]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	summary := setJSON{
+		Pending: len(s.polls),
+		Polls:   make([]pollJSON, 0, len(s.polls)),
+	}
+	for requestID, poll := range s.polls {
+		summary.Polls = append(summary.Polls, pollJSON{
+			RequestID:  requestID,
+			AgeMs:      time.Since(poll.start).Milliseconds(),
+			Validators: idStrings(poll.vdrs.List()),
+			Responded:  idStrings(poll.responded.List()),
+			Dropped:    idStrings(poll.dropped.List()),
+		})
+	}
+	return json.Marshal(summary)
+}
+
+func idStrings(vdrs []ids.ShortID) []string {
+	strs := make([]string, len(vdrs))
+	for i, vdr := range vdrs {
+		strs[i] = vdr.String()
+	}
+	return strs
+}
 
 func (s *set) String() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
 	sb := strings.Builder{}
 	sb.WriteString(fmt.Sprintf("current polls: (Size = %d)", len(s.polls)))
 	for requestID, poll := range s.polls {
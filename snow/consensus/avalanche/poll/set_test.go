@@ -4,11 +4,17 @@
 package poll
 
 import (
+	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 
 	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow/consensus/metrics"
 	"github.com/ava-labs/avalanchego/utils/logging"
 	"github.com/ava-labs/avalanchego/utils/wrappers"
 )
@@ -106,3 +112,321 @@ func TestSetString(t *testing.T) {
 			str)
 	}
 }
+
+func TestSetMarshalJSON(t *testing.T) {
+	factory := NewNoEarlyTermFactory()
+	log := logging.NoLog{}
+	namespace := ""
+	registerer := prometheus.NewRegistry()
+	s := NewSet(factory, log, namespace, registerer)
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+	vdr3 := ids.ShortID{3} // k = 3
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(
+		vdr1,
+		vdr2,
+		vdr3,
+	)
+
+	if !s.Add(5, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := s.Vote(5, vdr1, []ids.ID{{1}}); finished {
+		t.Fatalf("Shouldn't have finished the poll")
+	} else if _, finished := s.Vote(5, vdr2, nil); finished {
+		t.Fatalf("Shouldn't have finished the poll")
+	}
+
+	summaryBytes, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary := setJSON{}
+	if err := json.Unmarshal(summaryBytes, &summary); err != nil {
+		t.Fatal(err)
+	}
+
+	if summary.Pending != 1 {
+		t.Fatalf("Should have reported one pending poll, reported %d", summary.Pending)
+	} else if len(summary.Polls) != 1 {
+		t.Fatalf("Should have reported one poll, reported %d", len(summary.Polls))
+	} else if p := summary.Polls[0]; p.RequestID != 5 {
+		t.Fatalf("Wrong requestID reported: %d", p.RequestID)
+	} else if len(p.Validators) != 3 {
+		t.Fatalf("Should have reported 3 validators, reported %v", p.Validators)
+	} else if len(p.Responded) != 2 {
+		t.Fatalf("Should have reported 2 responses, reported %v", p.Responded)
+	} else if len(p.Dropped) != 1 || p.Dropped[0] != vdr2.String() {
+		t.Fatalf("Should have reported %s as dropped, reported %v", vdr2, p.Dropped)
+	}
+}
+
+func TestSetShutdown(t *testing.T) {
+	factory := NewNoEarlyTermFactory()
+	log := logging.NoLog{}
+	namespace := ""
+	registerer := prometheus.NewRegistry()
+	s := NewSet(factory, log, namespace, registerer)
+
+	if err := s.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The metrics should be able to be registered again once the set has been
+	// shut down
+	s = NewSet(factory, log, namespace, registerer)
+	if err := s.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Shutdown(); err == nil {
+		t.Fatalf("Shouldn't have been able to unregister the metrics twice")
+	}
+}
+
+// failingUnregisterer fails to unregister [collector], but otherwise behaves
+// like the embedded registerer
+type failingUnregisterer struct {
+	prometheus.Registerer
+	collector prometheus.Collector
+}
+
+func (r *failingUnregisterer) Unregister(collector prometheus.Collector) bool {
+	if collector == r.collector {
+		return false
+	}
+	return r.Registerer.Unregister(collector)
+}
+
+func TestSetShutdownUnregistersAfterFailure(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	registerer := &failingUnregisterer{Registerer: registry}
+	s := NewSetWithConfig(SetConfig{
+		Factory:    NewNoEarlyTermFactory(),
+		Log:        logging.NoLog{},
+		Namespace:  "test",
+		Registerer: registerer,
+	})
+	registerer.collector = s.(*set).metrics[0]
+
+	err := s.Shutdown()
+	if !errors.Is(err, errFailedUnregister) {
+		t.Fatalf("Should have failed to unregister the metrics")
+	} else if !strings.Contains(err.Error(), "test_polls") {
+		t.Fatalf("Should have named the metric that failed to be unregistered, got: %s", err)
+	}
+
+	metrics, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(metrics) != 1 || metrics[0].GetName() != "test_polls" {
+		t.Fatalf("Should have unregistered every other metric, %d remain", len(metrics))
+	}
+}
+
+func TestSetSharedMetrics(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	durations := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "poll_duration",
+	}, []string{chainLabel})
+	outcomes := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "poll_outcomes",
+	}, []string{chainLabel, outcomeLabel})
+	responses := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "poll_responses",
+	}, []string{chainLabel, outcomeLabel})
+
+	errs := wrappers.Errs{}
+	errs.Add(
+		registerer.Register(durations),
+		registerer.Register(outcomes),
+		registerer.Register(responses),
+	)
+	if errs.Errored() {
+		t.Fatal(errs.Err)
+	}
+
+	newSet := func(chain string) Set {
+		return NewSetWithConfig(SetConfig{
+			Factory:         NewNoEarlyTermFactory(),
+			Log:             logging.NoLog{},
+			Namespace:       chain,
+			Registerer:      registerer,
+			SharedDurations: durations,
+			Chain:           chain,
+			SharedOutcomes:  outcomes,
+			SharedResponses: responses,
+		})
+	}
+	s0 := newSet("chain0")
+	s1 := newSet("chain1")
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+	vdrs0 := ids.ShortBag{}
+	vdrs0.Add(vdr1, vdr2)
+	vdrs1 := ids.ShortBag{}
+	vdrs1.Add(vdr1, vdr2)
+
+	votes := []ids.ID{{1}}
+	if !s0.Add(0, vdrs0) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := s0.Vote(0, vdr1, votes); finished {
+		t.Fatalf("Shouldn't have finished the poll yet")
+	} else if _, finished := s0.Vote(0, vdr2, nil); !finished {
+		t.Fatalf("Should have finished the poll")
+	} else if !s1.Add(0, vdrs1) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := s1.Vote(0, vdr1, nil); finished {
+		t.Fatalf("Shouldn't have finished the poll yet")
+	} else if _, finished := s1.Vote(0, vdr2, nil); !finished {
+		t.Fatalf("Should have finished the poll")
+	}
+
+	if count := testutil.ToFloat64(outcomes.WithLabelValues("chain0", successfulOutcome)); count != 1 {
+		t.Fatalf("Should have reported 1 successful poll but reported %f", count)
+	} else if count := testutil.ToFloat64(outcomes.WithLabelValues("chain1", failedOutcome)); count != 1 {
+		t.Fatalf("Should have reported 1 failed poll but reported %f", count)
+	} else if count := testutil.ToFloat64(responses.WithLabelValues("chain0", votedOutcome)); count != 1 {
+		t.Fatalf("Should have reported 1 vote but reported %f", count)
+	} else if count := testutil.ToFloat64(responses.WithLabelValues("chain1", droppedOutcome)); count != 2 {
+		t.Fatalf("Should have reported 2 drops but reported %f", count)
+	}
+
+	if err := s0.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	if count := testutil.CollectAndCount(durations); count != 1 {
+		t.Fatalf("Should have only reported the durations of 1 chain but reported %d", count)
+	}
+	if err := s1.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	if count := testutil.CollectAndCount(outcomes); count != 0 {
+		t.Fatalf("Shouldn't have reported the outcomes of any chains but reported %d", count)
+	}
+}
+
+func TestSetAggregator(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	aggregator, err := metrics.NewPollAggregator("", registerer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewSetWithConfig(SetConfig{
+		Factory:    NewNoEarlyTermFactory(),
+		Log:        logging.NoLog{},
+		Namespace:  "chain",
+		Registerer: registerer,
+		Aggregator: aggregator,
+	})
+
+	vdr1 := ids.ShortID{1} // k = 1
+	newVdrs := func() ids.ShortBag {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(vdr1)
+		return vdrs
+	}
+
+	if !s.Add(0, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if numPolls := aggregator.Len(); numPolls != 1 {
+		t.Fatalf("Should have reported 1 pending poll, reported %d", numPolls)
+	} else if _, finished := s.Vote(0, vdr1, []ids.ID{{1}}); !finished {
+		t.Fatalf("Should have finished the poll")
+	} else if numPolls := aggregator.Len(); numPolls != 0 {
+		t.Fatalf("Shouldn't have reported any pending polls, reported %d", numPolls)
+	} else if !s.Add(1, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if err := s.Shutdown(); err != nil {
+		t.Fatal(err)
+	} else if numPolls := aggregator.Len(); numPolls != 0 {
+		t.Fatalf("Shouldn't have reported the polls of a shut down set, reported %d", numPolls)
+	}
+}
+
+func TestSetExpiresStalePolls(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	s := NewSetWithConfig(SetConfig{
+		Factory:    NewNoEarlyTermFactory(),
+		Log:        logging.NoLog{},
+		Registerer: registerer,
+		MaxPollAge: 10 * time.Millisecond,
+	})
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2} // k = 2
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1, vdr2)
+
+	if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := s.Vote(0, vdr1, []ids.ID{{1}}); finished {
+		t.Fatalf("Shouldn't have finished the poll yet")
+	}
+
+	// [vdr2] never responds, so the poll is only finished by being expired
+	numPolls := s.(*set).numPolls
+	deadline := time.Now().Add(5 * time.Second)
+	for testutil.ToFloat64(numPolls) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Should have expired the stale poll")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if s.Len() != 0 {
+		t.Fatalf("Should have removed the expired poll")
+	} else if _, finished := s.Vote(0, vdr2, []ids.ID{{1}}); finished {
+		t.Fatalf("Shouldn't have finished an expired poll")
+	} else if count := testutil.ToFloat64(s.(*set).numExpiredPolls); count != 1 {
+		t.Fatalf("Should have reported 1 expired poll but reported %f", count)
+	} else if count := testutil.ToFloat64(s.(*set).numSuccessfulPolls); count != 1 {
+		t.Fatalf("Should have reported the expired poll as successful but reported %f", count)
+	} else if count := testutil.ToFloat64(s.(*set).numDrops); count != 1 {
+		t.Fatalf("Should have reported the unresponsive validator as dropped but reported %f", count)
+	} else if err := s.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetTimeout(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	s := NewSet(NewNoEarlyTermFactory(), logging.NoLog{}, "", registerer)
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2} // k = 2
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1, vdr2)
+
+	if _, timedOut := s.Timeout(0); timedOut {
+		t.Fatalf("Shouldn't have timed out an unknown poll")
+	} else if _, ok := s.Age(0); ok {
+		t.Fatalf("Shouldn't have reported the age of an unknown poll")
+	} else if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, ok := s.Age(0); !ok {
+		t.Fatalf("Should have reported the age of the poll")
+	} else if _, finished := s.Vote(0, vdr1, []ids.ID{vtxID}); finished {
+		t.Fatalf("Shouldn't have finished the poll yet")
+	} else if result, timedOut := s.Timeout(0); !timedOut {
+		t.Fatalf("Should have timed out the poll")
+	} else if list := result.List(); len(list) != 1 || list[0] != vtxID {
+		t.Fatalf("Should have finished with the votes received so far")
+	} else if s.Len() != 0 {
+		t.Fatalf("Should have removed the timed out poll")
+	} else if _, finished := s.Vote(0, vdr2, []ids.ID{vtxID}); finished {
+		t.Fatalf("Should have ignored the vote for the timed out poll")
+	} else if _, timedOut := s.Timeout(0); timedOut {
+		t.Fatalf("Shouldn't have timed out the poll twice")
+	} else if count := testutil.ToFloat64(s.(*set).numTimedOutPolls); count != 1 {
+		t.Fatalf("Should have reported 1 timed out poll but reported %f", count)
+	} else if count := testutil.ToFloat64(s.(*set).numDrops); count != 1 {
+		t.Fatalf("Should have reported the unresponsive validator as dropped but reported %f", count)
+	}
+}
@@ -0,0 +1,80 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metrics
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PendingPolls is a collection of pending polls, such as the poll set of a
+// snowman or avalanche engine
+type PendingPolls interface {
+	// Len returns the number of pending polls. It may be called concurrently
+	// with the collection's other methods.
+	Len() int
+}
+
+// PollAggregator reports the total number of pending polls across every
+// collection that has been registered with it, such as the poll sets of every
+// chain in the process
+type PollAggregator interface {
+	// Register [s] to be included in the aggregated statistics
+	Register(s PendingPolls)
+
+	// Deregister [s] from the aggregated statistics
+	Deregister(s PendingPolls)
+
+	// Len returns the total number of pending polls across all registered
+	// collections
+	Len() int
+}
+
+type pollAggregator struct {
+	lock sync.Mutex
+	sets map[PendingPolls]struct{}
+}
+
+// NewPollAggregator returns a new aggregator whose statistics are registered
+// with [registerer] under [namespace]
+func NewPollAggregator(namespace string, registerer prometheus.Registerer) (PollAggregator, error) {
+	a := &pollAggregator{sets: make(map[PendingPolls]struct{})}
+
+	numPolls := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "polls",
+		Help:      "Number of pending network polls across all chains",
+	}, func() float64 { return float64(a.Len()) })
+	if err := registerer.Register(numPolls); err != nil {
+		return nil, fmt.Errorf("failed to register polls statistics due to %w", err)
+	}
+	return a, nil
+}
+
+func (a *pollAggregator) Register(s PendingPolls) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.sets[s] = struct{}{}
+}
+
+func (a *pollAggregator) Deregister(s PendingPolls) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	delete(a.sets, s)
+}
+
+func (a *pollAggregator) Len() int {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	numPolls := 0
+	for s := range a.sets {
+		numPolls += s.Len()
+	}
+	return numPolls
+}
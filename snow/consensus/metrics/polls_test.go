@@ -0,0 +1,53 @@
+// (c) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type pendingPolls int
+
+func (p *pendingPolls) Len() int { return int(*p) }
+
+func TestPollAggregatorLen(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	aggregator, err := NewPollAggregator("", registerer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p0 := pendingPolls(2)
+	p1 := pendingPolls(3)
+	aggregator.Register(&p0)
+	aggregator.Register(&p1)
+	if numPolls := aggregator.Len(); numPolls != 5 {
+		t.Fatalf("Should have reported 5 pending polls, reported %d", numPolls)
+	}
+
+	p1 = 1
+	if numPolls := aggregator.Len(); numPolls != 3 {
+		t.Fatalf("Should have reported 3 pending polls, reported %d", numPolls)
+	}
+
+	aggregator.Deregister(&p0)
+	if numPolls := aggregator.Len(); numPolls != 1 {
+		t.Fatalf("Should have reported 1 pending poll, reported %d", numPolls)
+	}
+}
+
+func TestNewPollAggregatorErrorOnMetrics(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	if err := registerer.Register(prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "polls",
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewPollAggregator("", registerer); err == nil {
+		t.Fatalf("should have errored due to a duplicated metric")
+	}
+}
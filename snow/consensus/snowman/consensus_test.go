@@ -7,6 +7,7 @@ import (
 	"errors"
 	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 
@@ -14,6 +15,8 @@ import (
 	"github.com/ava-labs/avalanchego/snow"
 	"github.com/ava-labs/avalanchego/snow/choices"
 	"github.com/ava-labs/avalanchego/snow/consensus/snowball"
+	"github.com/ava-labs/avalanchego/snow/consensus/snowman/poll"
+	"github.com/ava-labs/avalanchego/utils/logging"
 )
 
 var (
@@ -51,6 +54,7 @@ var (
 		ErrorOnRejectSiblingTest,
 		ErrorOnTransitiveRejectionTest,
 		RandomizedConsistencyTest,
+		RandomizedConsistencyWithFaultsTest,
 	}
 )
 
@@ -1494,3 +1498,74 @@ func RandomizedConsistencyTest(t *testing.T, factory Factory) {
 		t.Fatalf("Network agreed on inconsistent values")
 	}
 }
+
+func RandomizedConsistencyWithFaultsTest(t *testing.T, factory Factory) {
+	numColors := 50
+	numNodes := 100
+	numByzantine := 10
+	params := snowball.Parameters{
+		Metrics:               prometheus.NewRegistry(),
+		K:                     20,
+		Alpha:                 15,
+		BetaVirtuous:          20,
+		BetaRogue:             30,
+		ConcurrentRepolls:     1,
+		OptimalProcessing:     1,
+		MaxOutstandingItems:   1,
+		MaxItemProcessingTime: 1,
+	}
+	seed := int64(0)
+
+	rand.Seed(seed)
+
+	n := Network{
+		polls: poll.NewSet(
+			poll.NewEarlyTermNoTraversalFactory(params.Alpha),
+			logging.NoLog{},
+			"",
+			prometheus.NewRegistry(),
+		),
+		maxLatency: 100 * time.Millisecond,
+		timeout:    time.Second,
+		dropRate:   .05,
+	}
+	n.Initialize(params, numColors)
+
+	for i := 0; i < numByzantine; i++ {
+		if err := n.AddByzantineNode(factory.New()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := numByzantine; i < numNodes; i++ {
+		if err := n.AddNode(factory.New()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for !n.Finalized() {
+		if err := n.Round(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !n.Agreement() {
+		t.Fatalf("Network agreed on inconsistent values")
+	}
+
+	t.Logf("Network finalized after %d rounds", n.rounds)
+
+	// Every node needs at least BetaVirtuous successful polls to finalize, and
+	// the faults shouldn't slow the network down by more than a few times that
+	if maxRounds := 4 * numNodes * params.BetaVirtuous; n.rounds > maxRounds {
+		t.Fatalf("Network took %d rounds to finalize, expected at most %d", n.rounds, maxRounds)
+	}
+	if n.unused == 0 {
+		t.Fatalf("Polls should have finished before the slowest responses arrived")
+	}
+	if averageLatency := n.elapsed / time.Duration(n.rounds); averageLatency >= n.timeout {
+		t.Fatalf("Polls took %s on average to finish, expected less than the timeout of %s", averageLatency, n.timeout)
+	}
+	if n.polls.Len() != 0 {
+		t.Fatalf("Every poll should have finished, but %d are outstanding", n.polls.Len())
+	}
+}
@@ -4,7 +4,10 @@
 package snowman
 
 import (
+	"errors"
 	"math/rand"
+	"sort"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 
@@ -12,13 +15,43 @@ import (
 	"github.com/ava-labs/avalanchego/snow"
 	"github.com/ava-labs/avalanchego/snow/choices"
 	"github.com/ava-labs/avalanchego/snow/consensus/snowball"
+	"github.com/ava-labs/avalanchego/snow/consensus/snowman/poll"
 	"github.com/ava-labs/avalanchego/utils/sampler"
 )
 
+var errUnfinishedPoll = errors.New("poll didn't finish after every response was delivered")
+
 type Network struct {
 	params         snowball.Parameters
 	colors         []*TestBlock
 	nodes, running []Consensus
+	nodeIDs        []ids.ShortID
+
+	// polls, if non-nil, collects the votes of each round. Otherwise, the
+	// vote of every sampled node is counted.
+	polls     poll.Set
+	requestID uint32
+
+	// maxLatency is the maximum delay of a response, and timeout is the delay
+	// after which a lost response is dropped. Responses are delivered to
+	// [polls] in the order they arrive, until the poll finishes.
+	maxLatency time.Duration
+	timeout    time.Duration
+
+	// dropRate is the probability that a sampled node's vote is lost. Only
+	// used if polls is set.
+	dropRate float64
+
+	// elapsed is the total time it took for the polls to finish, and unused
+	// is the number of responses that arrived after their poll finished
+	elapsed time.Duration
+	unused  int
+
+	// byzantine are the nodes that vote for a random color rather than their
+	// preference
+	byzantine map[Consensus]bool
+
+	rounds int
 }
 
 func (n *Network) shuffleColors() {
@@ -89,9 +122,31 @@ func (n *Network) AddNode(sm Consensus) error {
 	}
 	n.nodes = append(n.nodes, sm)
 	n.running = append(n.running, sm)
+	n.nodeIDs = append(n.nodeIDs, ids.GenerateTestShortID())
+	return nil
+}
+
+// AddByzantineNode adds a node that votes for a random color rather than its
+// preference
+func (n *Network) AddByzantineNode(sm Consensus) error {
+	if err := n.AddNode(sm); err != nil {
+		return err
+	}
+	if n.byzantine == nil {
+		n.byzantine = make(map[Consensus]bool)
+	}
+	n.byzantine[sm] = true
 	return nil
 }
 
+// vote returns the vote [node] responds to a query with
+func (n *Network) vote(node Consensus) ids.ID {
+	if n.byzantine[node] {
+		return n.colors[rand.Intn(len(n.colors))].ID() // #nosec G404
+	}
+	return node.Preference()
+}
+
 func (n *Network) Finalized() bool { return len(n.running) == 0 }
 
 func (n *Network) Round() error {
@@ -106,10 +161,19 @@ func (n *Network) Round() error {
 	_ = s.Initialize(uint64(len(n.nodes)))
 	indices, _ := s.Sample(n.params.K)
 	sampledColors := ids.Bag{}
-	for _, index := range indices {
-		peer := n.nodes[int(index)]
-		sampledColors.Add(peer.Preference())
+	if n.polls == nil {
+		for _, index := range indices {
+			peer := n.nodes[int(index)]
+			sampledColors.Add(n.vote(peer))
+		}
+	} else {
+		var err error
+		sampledColors, err = n.poll(indices)
+		if err != nil {
+			return err
+		}
 	}
+	n.rounds++
 
 	if err := running.RecordPoll(sampledColors); err != nil {
 		return err
@@ -125,13 +189,70 @@ func (n *Network) Round() error {
 	return nil
 }
 
-func (n *Network) Agreement() bool {
-	if len(n.nodes) == 0 {
-		return true
+type response struct {
+	index int
+	delay time.Duration
+	lost  bool
+}
+
+// poll queries the nodes at [indices] through [polls] and returns the result
+// of the poll
+func (n *Network) poll(indices []uint64) (ids.Bag, error) {
+	n.requestID++
+	vdrs := ids.ShortBag{}
+	responses := make([]response, len(indices))
+	for i, index := range indices {
+		vdrs.Add(n.nodeIDs[int(index)])
+
+		r := response{index: int(index)}
+		if rand.Float64() < n.dropRate { // #nosec G404
+			// A lost response is only dropped once the request times out
+			r.delay = n.timeout
+			r.lost = true
+		} else {
+			r.delay = time.Duration(rand.Int63n(int64(n.maxLatency) + 1)) // #nosec G404
+		}
+		responses[i] = r
 	}
-	pref := n.nodes[0].Preference()
+	n.polls.Add(n.requestID, vdrs)
+
+	sort.SliceStable(responses, func(i, j int) bool {
+		return responses[i].delay < responses[j].delay
+	})
+	for i, r := range responses {
+		vdr := n.nodeIDs[r.index]
+
+		var (
+			result   ids.Bag
+			finished bool
+		)
+		if r.lost {
+			result, finished = n.polls.Drop(n.requestID, vdr)
+		} else {
+			result, finished = n.polls.Vote(n.requestID, vdr, n.vote(n.nodes[r.index]))
+		}
+		if finished {
+			n.elapsed += r.delay
+			n.unused += len(responses) - i - 1
+			return result, nil
+		}
+	}
+	return ids.Bag{}, errUnfinishedPoll
+}
+
+// Agreement returns true if all the nodes that aren't byzantine prefer the
+// same color
+func (n *Network) Agreement() bool {
+	pref := ids.ID{}
+	hasPref := false
 	for _, node := range n.nodes {
-		if pref != node.Preference() {
+		if n.byzantine[node] {
+			continue
+		}
+		if !hasPref {
+			pref = node.Preference()
+			hasPref = true
+		} else if pref != node.Preference() {
 			return false
 		}
 	}
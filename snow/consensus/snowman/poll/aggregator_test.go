@@ -0,0 +1,80 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package poll
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow/consensus/metrics"
+	"github.com/ava-labs/avalanchego/utils/logging"
+)
+
+func TestAggregatorLen(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	aggregator, err := metrics.NewPollAggregator("", registerer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newSet := func(namespace string) Set {
+		return NewSetWithConfig(SetConfig{
+			Factory:    NewNoEarlyTermFactory(),
+			Log:        logging.NoLog{},
+			Namespace:  namespace,
+			Registerer: registerer,
+			Aggregator: aggregator,
+		})
+	}
+	s0 := newSet("chain0")
+	s1 := newSet("chain1")
+
+	vdr1 := ids.ShortID{1} // k = 1
+
+	newVdrs := func() ids.ShortBag {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(vdr1)
+		return vdrs
+	}
+
+	if !s0.Add(0, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if !s0.Add(1, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if !s1.Add(0, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if numPolls := aggregator.Len(); numPolls != 3 {
+		t.Fatalf("Should have reported 3 pending polls, reported %d", numPolls)
+	} else if _, finished := s0.Vote(1, vdr1, ids.ID{1}); !finished {
+		t.Fatalf("Should have finished the poll")
+	} else if numPolls := aggregator.Len(); numPolls != 2 {
+		t.Fatalf("Should have reported 2 pending polls, reported %d", numPolls)
+	}
+
+	families, err := registerer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, metric := range families {
+		if metric.GetName() != "polls" {
+			continue
+		}
+		found = true
+		if value := metric.GetMetric()[0].GetGauge().GetValue(); value != 2 {
+			t.Fatalf("Should have reported 2 pending polls, reported %f", value)
+		}
+	}
+	if !found {
+		t.Fatalf("Aggregated polls metric wasn't registered")
+	}
+
+	if err := s0.Shutdown(); err != nil {
+		t.Fatal(err)
+	} else if numPolls := aggregator.Len(); numPolls != 1 {
+		t.Fatalf("Should have reported 1 pending poll, reported %d", numPolls)
+	}
+}
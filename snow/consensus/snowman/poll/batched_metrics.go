@@ -0,0 +1,87 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package poll
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// batchedGauge accumulates changes to a gauge until it is flushed. It isn't
+// safe for concurrent use.
+type batchedGauge struct {
+	prometheus.Gauge
+	delta float64
+}
+
+func (g *batchedGauge) Inc()              { g.delta++ }
+func (g *batchedGauge) Dec()              { g.delta-- }
+func (g *batchedGauge) Add(delta float64) { g.delta += delta }
+func (g *batchedGauge) Sub(delta float64) { g.delta -= delta }
+
+// Set [value] immediately, discarding any accumulated changes
+func (g *batchedGauge) Set(value float64) {
+	g.delta = 0
+	g.Gauge.Set(value)
+}
+
+func (g *batchedGauge) flush() {
+	if g.delta != 0 {
+		g.Gauge.Add(g.delta)
+		g.delta = 0
+	}
+}
+
+// batchedObserver accumulates observations until it is flushed. It isn't safe
+// for concurrent use.
+type batchedObserver struct {
+	prometheus.Observer
+	pending []float64
+}
+
+func (o *batchedObserver) Observe(value float64) { o.pending = append(o.pending, value) }
+
+func (o *batchedObserver) flush() {
+	for _, value := range o.pending {
+		o.Observer.Observe(value)
+	}
+	o.pending = o.pending[:0]
+}
+
+// maybeFlushMetrics reports the batched metric updates to prometheus if enough
+// polls have been added or finished, or enough time has passed, since the last
+// time they were reported
+// Assumes the lock is held
+func (s *set) maybeFlushMetrics() {
+	if s.batchedPolls == nil {
+		return
+	}
+
+	s.batchedOps++
+	now := s.clock.Time()
+	interval := s.config.MetricFlushInterval
+	if s.batchedOps < s.config.MetricBatchSize && (interval <= 0 || now.Sub(s.lastFlush) < interval) {
+		return
+	}
+	s.flushMetrics(now)
+}
+
+// flushBatchedMetrics reports the batched metric updates to prometheus
+func (s *set) flushBatchedMetrics() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.flushMetrics(s.clock.Time())
+}
+
+// flushMetrics reports the batched metric updates to prometheus
+// Assumes the lock is held
+func (s *set) flushMetrics(now time.Time) {
+	s.batchedPolls.flush()
+	s.batchedDurPolls.flush()
+	s.batchedSlowDurPolls.flush()
+	s.batchedOps = 0
+	s.lastFlush = now
+}
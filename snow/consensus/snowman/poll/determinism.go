@@ -0,0 +1,80 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package poll
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+// shuffleSeed is used to deterministically reorder votes while checking a
+// factory for determinism
+const shuffleSeed = 0
+
+// VoteEvent is a single response to a poll
+type VoteEvent struct {
+	Validator ids.ShortID
+	Vote      ids.ID
+	// Dropped is true if [Validator] failed to respond, in which case [Vote]
+	// is ignored
+	Dropped bool
+}
+
+// DeterminismCheck returns an error if polls created by [factory] report
+// different results when given the same sequence of [votes].
+//
+// The sequence is replayed through two fresh polls, stopping once each poll
+// finishes, and the results must be identical. The sequence is also replayed
+// in full in both its original and a shuffled order, which must result in
+// the same tally as the order of responses shouldn't matter once every
+// validator has responded.
+func DeterminismCheck(factory Factory, vdrs ids.ShortBag, votes []VoteEvent) error {
+	firstResult, firstFinished := replay(factory, vdrs, votes, true)
+	secondResult, secondFinished := replay(factory, vdrs, votes, true)
+	if firstFinished != secondFinished {
+		return fmt.Errorf("replayed polls diverged on finishing: %v != %v",
+			firstFinished,
+			secondFinished)
+	}
+	if !firstResult.Equals(secondResult) {
+		return fmt.Errorf("replayed polls diverged on results: %s != %s",
+			&firstResult,
+			&secondResult)
+	}
+
+	shuffled := make([]VoteEvent, len(votes))
+	for i, j := range rand.New(rand.NewSource(shuffleSeed)).Perm(len(votes)) { // #nosec G404
+		shuffled[i] = votes[j]
+	}
+
+	orderedResult, _ := replay(factory, vdrs, votes, false)
+	shuffledResult, _ := replay(factory, vdrs, shuffled, false)
+	if !orderedResult.Equals(shuffledResult) {
+		return fmt.Errorf("shuffled poll diverged on results: %s != %s",
+			&orderedResult,
+			&shuffledResult)
+	}
+	return nil
+}
+
+// replay applies [votes] to a fresh poll created by [factory]. If
+// [stopOnFinish] is true, no votes are applied after the poll reports that it
+// has finished.
+func replay(factory Factory, vdrs ids.ShortBag, votes []VoteEvent, stopOnFinish bool) (ids.Bag, bool) {
+	// Polls may modify the provided bag, so each poll is given its own copy
+	poll := factory.New(copyBag(vdrs))
+	for _, vote := range votes {
+		if stopOnFinish && poll.Finished() {
+			break
+		}
+		if vote.Dropped {
+			poll.Drop(vote.Validator)
+		} else {
+			poll.Vote(vote.Validator, vote.Vote)
+		}
+	}
+	return poll.Result(), poll.Finished()
+}
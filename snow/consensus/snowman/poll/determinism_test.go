@@ -0,0 +1,110 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package poll
+
+import (
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+// nonDeterministicFactory creates polls that count votes differently based on
+// how many polls the factory has previously created
+type nonDeterministicFactory struct {
+	created int
+}
+
+func (f *nonDeterministicFactory) New(vdrs ids.ShortBag) Poll {
+	f.created++
+	return &nonDeterministicPoll{
+		Poll:  NewNoEarlyTermFactory().New(vdrs),
+		extra: f.created,
+	}
+}
+
+type nonDeterministicPoll struct {
+	Poll
+	extra int
+}
+
+func (p *nonDeterministicPoll) Result() ids.Bag {
+	result := p.Poll.Result()
+	result.AddCount(ids.ID{byte(p.extra)}, p.extra)
+	return result
+}
+
+// orderDependentFactory creates polls that only count the first vote they
+// receive
+type orderDependentFactory struct{}
+
+func (orderDependentFactory) New(vdrs ids.ShortBag) Poll {
+	return &orderDependentPoll{Poll: NewNoEarlyTermFactory().New(vdrs)}
+}
+
+type orderDependentPoll struct {
+	Poll
+	first    ids.ID
+	hasFirst bool
+}
+
+func (p *orderDependentPoll) Vote(vdr ids.ShortID, vote ids.ID) {
+	if !p.hasFirst {
+		p.first = vote
+		p.hasFirst = true
+	}
+	p.Poll.Vote(vdr, p.first)
+}
+
+func determinismTestVotes() (ids.ShortBag, []VoteEvent) {
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+	vdr3 := ids.ShortID{3}
+	vdr4 := ids.ShortID{4} // k = 4
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(
+		vdr1,
+		vdr2,
+		vdr3,
+		vdr4,
+	)
+
+	votes := []VoteEvent{
+		{Validator: vdr1, Vote: ids.ID{1}},
+		{Validator: vdr2, Vote: ids.ID{2}},
+		{Validator: vdr3, Dropped: true},
+		{Validator: vdr4, Vote: ids.ID{2}},
+	}
+	return vdrs, votes
+}
+
+func TestDeterminismCheck(t *testing.T) {
+	vdrs, votes := determinismTestVotes()
+
+	factories := []Factory{
+		NewNoEarlyTermFactory(),
+		NewEarlyTermNoTraversalFactory(2),
+	}
+	for _, factory := range factories {
+		if err := DeterminismCheck(factory, vdrs, votes); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDeterminismCheckNonDeterministicFactory(t *testing.T) {
+	vdrs, votes := determinismTestVotes()
+
+	if err := DeterminismCheck(&nonDeterministicFactory{}, vdrs, votes); err == nil {
+		t.Fatalf("Should have reported the factory as non-deterministic")
+	}
+}
+
+func TestDeterminismCheckOrderDependentFactory(t *testing.T) {
+	vdrs, votes := determinismTestVotes()
+
+	if err := DeterminismCheck(orderDependentFactory{}, vdrs, votes); err == nil {
+		t.Fatalf("Should have reported the factory as order dependent")
+	}
+}
@@ -0,0 +1,88 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package poll
+
+import (
+	"sync"
+	"time"
+)
+
+// numDropRateBuckets is the number of buckets the drop rate window is split
+// into. Responses expire from the window one bucket at a time.
+const numDropRateBuckets = 10
+
+type dropRateBucket struct {
+	// epoch is the index of the bucket period this bucket is counting
+	epoch int64
+	votes uint64
+	drops uint64
+}
+
+// dropRate tracks the fraction of responses to polls that were drops over a
+// sliding window
+type dropRate struct {
+	lock           sync.Mutex
+	bucketDuration time.Duration
+	buckets        [numDropRateBuckets]dropRateBucket
+}
+
+func newDropRate(window time.Duration) *dropRate {
+	bucketDuration := window / numDropRateBuckets
+	if bucketDuration <= 0 {
+		bucketDuration = 1
+	}
+	return &dropRate{bucketDuration: bucketDuration}
+}
+
+// Vote records a vote that was received at [now]
+func (r *dropRate) Vote(now time.Time) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.bucket(now).votes++
+}
+
+// Drop records a drop that occurred at [now]
+func (r *dropRate) Drop(now time.Time) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.bucket(now).drops++
+}
+
+// Rate returns drops / (votes + drops) over the window ending at [now]. If no
+// responses were recorded in the window, 0 is returned.
+func (r *dropRate) Rate(now time.Time) float64 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	epoch := r.epoch(now)
+	votes, drops := uint64(0), uint64(0)
+	for _, bucket := range r.buckets {
+		if age := epoch - bucket.epoch; age >= 0 && age < numDropRateBuckets {
+			votes += bucket.votes
+			drops += bucket.drops
+		}
+	}
+	if total := votes + drops; total > 0 {
+		return float64(drops) / float64(total)
+	}
+	return 0
+}
+
+// bucket returns the bucket that counts responses at [now], resetting it if
+// it was last used in a previous window
+// Assumes the lock is held
+func (r *dropRate) bucket(now time.Time) *dropRateBucket {
+	epoch := r.epoch(now)
+	bucket := &r.buckets[epoch%numDropRateBuckets]
+	if bucket.epoch != epoch {
+		*bucket = dropRateBucket{epoch: epoch}
+	}
+	return bucket
+}
+
+func (r *dropRate) epoch(now time.Time) int64 {
+	return now.UnixNano() / int64(r.bucketDuration)
+}
@@ -0,0 +1,47 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package poll
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDropRate(t *testing.T) {
+	r := newDropRate(10 * time.Second)
+
+	now := time.Now()
+	if rate := r.Rate(now); rate != 0 {
+		t.Fatalf("Should have reported a drop rate of 0 without any responses, reported %f", rate)
+	}
+
+	r.Vote(now)
+	r.Vote(now)
+	r.Vote(now)
+	r.Drop(now)
+	if rate := r.Rate(now); rate != .25 {
+		t.Fatalf("Should have reported a drop rate of 0.25, reported %f", rate)
+	}
+
+	now = now.Add(5 * time.Second)
+	r.Drop(now)
+	r.Drop(now)
+	r.Drop(now)
+	r.Drop(now)
+	if rate := r.Rate(now); rate != .625 {
+		t.Fatalf("Should have reported a drop rate of 0.625, reported %f", rate)
+	}
+
+	// The first responses should have expired from the window
+	now = now.Add(6 * time.Second)
+	if rate := r.Rate(now); rate != 1 {
+		t.Fatalf("Should have reported a drop rate of 1, reported %f", rate)
+	}
+
+	// Every response should have expired from the window
+	now = now.Add(10 * time.Second)
+	if rate := r.Rate(now); rate != 0 {
+		t.Fatalf("Should have reported a drop rate of 0, reported %f", rate)
+	}
+}
@@ -10,7 +10,8 @@ import (
 )
 
 type earlyTermNoTraversalFactory struct {
-	alpha int
+	alpha      int
+	tieBreaker TieBreaker
 }
 
 // NewEarlyTermNoTraversalFactory returns a factory that returns polls with
@@ -19,20 +20,36 @@ func NewEarlyTermNoTraversalFactory(alpha int) Factory {
 	return &earlyTermNoTraversalFactory{alpha: alpha}
 }
 
+// NewEarlyTermNoTraversalFactoryWithTieBreaker returns a factory that returns
+// polls with early termination, without doing DAG traversals, whose results
+// break ties with [tieBreaker]
+func NewEarlyTermNoTraversalFactoryWithTieBreaker(alpha int, tieBreaker TieBreaker) Factory {
+	return &earlyTermNoTraversalFactory{
+		alpha:      alpha,
+		tieBreaker: tieBreaker,
+	}
+}
+
 func (f *earlyTermNoTraversalFactory) New(vdrs ids.ShortBag) Poll {
 	return &earlyTermNoTraversalPoll{
-		polled: vdrs,
-		alpha:  f.alpha,
+		polled:     vdrs,
+		alpha:      f.alpha,
+		tieBreaker: f.tieBreaker,
 	}
 }
 
+func (f *earlyTermNoTraversalFactory) String() string {
+	return fmt.Sprintf("EarlyTermNoTraversal(Alpha = %d)", f.alpha)
+}
+
 // earlyTermNoTraversalPoll finishes when any remaining validators can't change
 // the result of the poll. However, does not terminate tightly with this bound.
 // It terminates as quickly as it can without performing any DAG traversals.
 type earlyTermNoTraversalPoll struct {
-	votes  ids.Bag
-	polled ids.ShortBag
-	alpha  int
+	votes      ids.Bag
+	polled     ids.ShortBag
+	alpha      int
+	tieBreaker TieBreaker
 }
 
 // Vote registers a response for this poll
@@ -61,7 +78,9 @@ func (p *earlyTermNoTraversalPoll) Finished() bool {
 }
 
 // Result returns the result of this poll
-func (p *earlyTermNoTraversalPoll) Result() ids.Bag { return p.votes }
+func (p *earlyTermNoTraversalPoll) Result() ids.Bag {
+	return breakTies(p.tieBreaker, p.votes)
+}
 
 func (p *earlyTermNoTraversalPoll) PrefixedString(prefix string) string {
 	return fmt.Sprintf("waiting on %s", p.polled.PrefixedString(prefix))
@@ -0,0 +1,116 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package poll
+
+import (
+	"fmt"
+	"time"
+)
+
+// EffectiveConfig is a serializable summary of the settings a set is using.
+// Components are reported by name, and defaults that the set filled in are
+// reported rather than the values it was configured with.
+type EffectiveConfig struct {
+	Factory       string `json:"factory"`
+	ShadowFactory string `json:"shadowFactory,omitempty"`
+
+	Namespace string `json:"namespace"`
+	Subsystem string `json:"subsystem,omitempty"`
+	Chain     string `json:"chain,omitempty"`
+	RedactIDs bool   `json:"redactIDs"`
+
+	// Aggregated, SharedDurations, SharedOutcomes, and SharedResponses report
+	// whether the set is reporting to an aggregator or shared collectors
+	Aggregated      bool `json:"aggregated"`
+	SharedDurations bool `json:"sharedDurations"`
+	SharedOutcomes  bool `json:"sharedOutcomes"`
+	SharedResponses bool `json:"sharedResponses"`
+
+	// Validators reports whether the set samples from a live validator set.
+	// Sampler and UnknownValidators are only reported if it does.
+	Validators        bool   `json:"validators"`
+	Sampler           string `json:"sampler,omitempty"`
+	UnknownValidators string `json:"unknownValidators,omitempty"`
+
+	MetricBatchSize     int           `json:"metricBatchSize"`
+	MetricFlushInterval time.Duration `json:"metricFlushInterval"`
+	MaxObservedDuration time.Duration `json:"maxObservedDuration"`
+	MaxPollAge          time.Duration `json:"maxPollAge"`
+	MaxOutstanding      int           `json:"maxOutstanding"`
+	RetainAfterFinish   time.Duration `json:"retainAfterFinish"`
+	ContainerResults    int           `json:"containerResults"`
+	MaxRequeues         int           `json:"maxRequeues"`
+	AllowForceFinalize  bool          `json:"allowForceFinalize"`
+	Alpha               int           `json:"alpha"`
+	AlphaWeight         uint64        `json:"alphaWeight"`
+	RecentResults       int           `json:"recentResults"`
+	IncludeWeights      bool          `json:"includeWeights"`
+	DropRateWindow      time.Duration `json:"dropRateWindow"`
+	LatencyHalflife     time.Duration `json:"latencyHalflife"`
+	SlowPollThreshold   time.Duration `json:"slowPollThreshold"`
+	OrderedFinish       bool          `json:"orderedFinish"`
+
+	// SlowDurations, ResponseMetrics, Outcomes, OldestPollAge, and
+	// ValidatorSeconds report which optional metrics are enabled
+	SlowDurations    bool `json:"slowDurations"`
+	ResponseMetrics  bool `json:"responseMetrics"`
+	Outcomes         bool `json:"outcomes"`
+	OldestPollAge    bool `json:"oldestPollAge"`
+	ValidatorSeconds bool `json:"validatorSeconds"`
+}
+
+// Config returns a summary of the settings the set is using
+func (s *set) Config() EffectiveConfig {
+	config := EffectiveConfig{
+		Factory:             name(s.factory),
+		Namespace:           s.config.Namespace,
+		Subsystem:           s.config.Subsystem,
+		Chain:               s.config.Chain,
+		RedactIDs:           s.config.RedactIDs,
+		Aggregated:          s.config.Aggregator != nil,
+		SharedDurations:     s.config.SharedDurations != nil,
+		SharedOutcomes:      s.config.SharedOutcomes != nil,
+		SharedResponses:     s.config.SharedResponses != nil,
+		Validators:          s.config.Validators != nil,
+		MetricBatchSize:     s.config.MetricBatchSize,
+		MetricFlushInterval: s.config.MetricFlushInterval,
+		MaxObservedDuration: s.config.MaxObservedDuration,
+		MaxPollAge:          s.config.MaxPollAge,
+		MaxOutstanding:      s.config.MaxOutstanding,
+		RetainAfterFinish:   s.config.RetainAfterFinish,
+		ContainerResults:    s.config.ContainerResults,
+		MaxRequeues:         s.config.MaxRequeues,
+		AllowForceFinalize:  s.config.AllowForceFinalize,
+		Alpha:               s.config.Alpha,
+		AlphaWeight:         s.config.AlphaWeight,
+		RecentResults:       s.config.RecentResults,
+		IncludeWeights:      s.config.IncludeWeights,
+		DropRateWindow:      s.config.DropRateWindow,
+		LatencyHalflife:     s.config.LatencyHalflife,
+		SlowPollThreshold:   s.config.SlowPollThreshold,
+		OrderedFinish:       s.config.OrderedFinish,
+		SlowDurations:       s.config.SlowDurations,
+		ResponseMetrics:     s.config.ResponseMetrics,
+		Outcomes:            s.config.Outcomes,
+		OldestPollAge:       s.config.OldestPollAge,
+		ValidatorSeconds:    s.config.ValidatorSeconds,
+	}
+	if s.config.ShadowFactory != nil {
+		config.ShadowFactory = name(s.config.ShadowFactory)
+	}
+	if s.config.Validators != nil {
+		config.Sampler = name(s.sampler)
+		config.UnknownValidators = s.config.UnknownValidators.String()
+	}
+	return config
+}
+
+// name returns the name of a component of a set. Components that don't name
+// themselves are named by their type.
+func name(component interface{}) string {
+	if stringer, ok := component.(fmt.Stringer); ok {
+		return stringer.String()
+	}
+	return fmt.Sprintf("%T", component)
+}
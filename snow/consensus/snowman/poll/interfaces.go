@@ -4,19 +4,163 @@
 package poll
 
 import (
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow/validators"
 )
 
 // Set is a collection of polls
 type Set interface {
 	fmt.Stringer
+	json.Marshaler
 
 	Add(requestID uint32, vdrs ids.ShortBag) bool
+
+	// AddForContainer behaves like Add, but records that the poll is of
+	// [containerID]. See CancelForContainer.
+	AddForContainer(requestID uint32, containerID ids.ID, vdrs ids.ShortBag) bool
+
+	// AddWithMandatory behaves like Add, but the poll won't finish until every
+	// validator in [mandatory] has responded. If a mandatory validator is
+	// dropped, the poll only finishes once it times out or expires. Every
+	// mandatory validator must be polled.
+	AddWithMandatory(requestID uint32, vdrs ids.ShortBag, mandatory ids.ShortSet) bool
+
+	// AddFromValidators samples the validators to poll from the set's current
+	// validators and returns the sampled validators
+	AddFromValidators(requestID uint32, sampleSize int) (ids.ShortBag, bool)
 	Vote(requestID uint32, vdr ids.ShortID, vote ids.ID) (ids.Bag, bool)
 	Drop(requestID uint32, vdr ids.ShortID) (ids.Bag, bool)
+
+	// DropWithRetry behaves like Drop once [vdr] has failed to respond more
+	// than [maxRetries] times. Before then, the failure is ignored and the
+	// final return value is true to signal that the query should be re-sent
+	// to [vdr].
+	DropWithRetry(requestID uint32, vdr ids.ShortID, maxRetries int) (ids.Bag, bool, bool)
 	Len() int
+
+	// TransferTo moves all outstanding polls, including their start times and
+	// the votes they have received, to [dst]. Transfers to a set that has
+	// been shut down, or that would exceed its MaxOutstanding, fail. If an
+	// error is returned, no polls are moved.
+	TransferTo(dst Set) error
+
+	// PollID returns an ID derived from the requestID and polled validators of
+	// the outstanding poll with [requestID]. Returns false if there is no such
+	// poll.
+	PollID(requestID uint32) (ids.ID, bool)
+
+	// Age returns the length of time the outstanding poll with [requestID]
+	// has existed. Returns false if there is no such poll.
+	Age(requestID uint32) (time.Duration, bool)
+
+	// GetPendingVoters returns the validators that the outstanding poll with
+	// [requestID] is still waiting on. Returns false if there is no such poll.
+	GetPendingVoters(requestID uint32) (ids.ShortSet, bool)
+
+	// Result returns the result of the finished poll with [requestID], if the
+	// set is configured to retain finished polls and the poll finished
+	// recently enough
+	Result(requestID uint32) (ids.Bag, bool)
+
+	// WeightToWin returns the additional weight that must vote for [id] in the
+	// outstanding poll with [requestID] for [id] to be decided. If [id] can
+	// no longer be decided, ImpossibleWeight is returned.
+	WeightToWin(requestID uint32, id ids.ID) (uint64, bool)
+
+	// CombineResults returns the combined results of the polls with
+	// [requestIDs], such as the polls of a query that was split across
+	// disjoint validators. Returns false until every poll has finished. Like
+	// Result, finished polls are only available if they are retained.
+	CombineResults(requestIDs ...uint32) (ids.Bag, bool)
+
+	// SizeDistribution returns the number of outstanding polls that poll each
+	// number of validators
+	SizeDistribution() map[int]int
+
+	// RecentResults returns the most recently finished poll results, newest
+	// first
+	RecentResults() []PollResult
+
+	// LastResultFor returns the result of the most recently finished poll that
+	// was added with AddForContainer for [containerID]. Returns false if no
+	// such result is known.
+	LastResultFor(containerID ids.ID) (ids.Bag, bool)
+
+	// Requeue a finished poll's [result] so that it is returned by a later
+	// call to Requeued. Returns false if the result of the poll with
+	// [result]'s requestID has already been requeued the maximum number of
+	// times, as counted by the set.
+	Requeue(result PollResult) bool
+
+	// Requeued returns, and removes, the results that have been requeued
+	Requeued() []PollResult
+
+	// Cancel removes the outstanding poll with [requestID] without finishing
+	// it. Returns true if there was such a poll.
+	Cancel(requestID uint32) bool
+
+	// CancelForContainer removes every outstanding poll that was added with
+	// AddForContainer for [containerID] and returns their requestIDs
+	CancelForContainer(containerID ids.ID) []uint32
+
+	// Timeout finishes the outstanding poll with [requestID] with the votes
+	// it has received so far. This allows stale polls to be finished when
+	// validators never respond and are never dropped.
+	Timeout(requestID uint32) (ids.Bag, bool)
+
+	// ExpireStale finishes every outstanding poll that is older than the
+	// set's MaxPollAge and returns their results, in order of their
+	// requestIDs. Stale polls are also expired periodically by the set, so
+	// this only needs to be called to expire them immediately.
+	ExpireStale() []PollResult
+
+	// FinalizeAndReissue finishes the poll with [oldRequestID], treating every
+	// validator that hasn't responded as dropped, and adds a poll of the same
+	// validators, and mandatory validators, with [newRequestID]. The old
+	// poll's result is returned. If the new poll can't be added, the old poll
+	// is left outstanding and false is returned.
+	FinalizeAndReissue(oldRequestID, newRequestID uint32) (ids.Bag, bool)
+
+	// ForceFinalize finishes the outstanding poll with [requestID] with
+	// [result], regardless of the responses it has received. It is only
+	// enabled if the set was configured to allow it. Returns true if the poll
+	// was finalized.
+	ForceFinalize(requestID uint32, result ids.Bag) bool
+
+	// VoteMany registers [vdr]'s votes in each of the polls in [votes], keyed
+	// by their requestIDs, and returns the results of the polls that finished
+	VoteMany(vdr ids.ShortID, votes map[uint32]ids.ID) map[uint32]ids.Bag
+
+	// DropAll drops [vdr] from every outstanding poll and returns the results
+	// of the polls that finished as a result, in order of their requestIDs
+	DropAll(vdr ids.ShortID) []PollResult
+
+	// PruneDisconnected removes every outstanding poll that doesn't poll any
+	// validator in [connected] and returns their requestIDs. Validators that
+	// aren't in [connected] are dropped from the remaining polls, and the
+	// results of the polls that finished as a result are returned. The
+	// latencies of validators that aren't in [connected] are forgotten.
+	PruneDisconnected(connected ids.ShortSet) (cancelled []uint32, finished []PollResult)
+
+	// ValidatorLatencies returns the moving average of the time each validator
+	// has taken to vote in the polls it was polled in, if the set tracks it
+	ValidatorLatencies() map[ids.ShortID]time.Duration
+
+	// ChurningBlocks returns the IDs that have received votes, without
+	// reaching alpha votes, in at least [threshold] consecutive polls. This
+	// may indicate a liveness fault.
+	ChurningBlocks(threshold int) []ids.ID
+
+	// Config returns a serializable summary of the settings the set is using
+	Config() EffectiveConfig
+
+	// Shutdown unregisters the set's metrics and removes it from its
+	// aggregator, if any
+	Shutdown() error
 }
 
 // Poll is an outstanding poll
@@ -34,3 +178,22 @@ type Poll interface {
 type Factory interface {
 	New(vdrs ids.ShortBag) Poll
 }
+
+// WeightedFactory creates polls that account for the weights of the polled
+// validators. If the set has Validators configured, polls are created with
+// NewWeighted rather than New.
+type WeightedFactory interface {
+	Factory
+
+	// NewWeighted returns a poll of [vdrs] whose weights are looked up in
+	// [weights]
+	NewWeighted(vdrs ids.ShortBag, weights validators.Set) Poll
+}
+
+// TieBreaker chooses the winner between IDs that received the same number of
+// votes in a poll
+type TieBreaker interface {
+	// Break returns the preferred ID of [candidates], which all have the
+	// highest count in [bag]
+	Break(candidates []ids.ID, bag ids.Bag) ids.ID
+}
@@ -0,0 +1,120 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package poll
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+func TestInternedEarlyTermNoTraversalMatchesDefault(t *testing.T) {
+	alpha := 11
+	k := 20
+
+	// #nosec G404
+	source := rand.New(rand.NewSource(0))
+	for i := 0; i < 100; i++ {
+		vdrs := ids.ShortBag{}
+		for j := 0; j < k; j++ {
+			vdrs.Add(ids.ShortID{byte(source.Intn(k))})
+		}
+
+		var votes []VoteEvent
+		for _, vdr := range vdrs.List() {
+			votes = append(votes, VoteEvent{
+				Validator: vdr,
+				Vote:      ids.ID{byte(source.Intn(3))},
+				Dropped:   source.Intn(4) == 0,
+			})
+		}
+
+		expected, expectedFinished := replay(NewEarlyTermNoTraversalFactory(alpha), vdrs, votes, true)
+		result, finished := replay(NewInternedEarlyTermNoTraversalFactory(alpha), vdrs, votes, true)
+		if finished != expectedFinished {
+			t.Fatalf("Interned poll finished %v, default poll finished %v", finished, expectedFinished)
+		} else if !result.Equals(expected) {
+			t.Fatalf("Interned poll returned %s, default poll returned %s", &result, &expected)
+		} else if mode, _ := result.Mode(); expected.Len() > 0 {
+			if expectedMode, _ := expected.Mode(); mode != expectedMode {
+				t.Fatalf("Interned poll returned mode %s, default poll returned mode %s", mode, expectedMode)
+			}
+		}
+	}
+}
+
+func TestInternedEarlyTermNoTraversalFinishesAtAlpha(t *testing.T) {
+	alpha := 2
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+	vdr3 := ids.ShortID{3} // k = 3
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(
+		vdr1,
+		vdr2,
+		vdr3,
+	)
+
+	factory := NewInternedEarlyTermNoTraversalFactory(alpha)
+	poll := factory.New(vdrs)
+
+	poll.Vote(vdr1, vtxID)
+	if poll.Finished() {
+		t.Fatalf("Poll finished after less than alpha votes")
+	}
+	poll.Vote(vdr1, vtxID)
+	if poll.Finished() {
+		t.Fatalf("Poll finished after a duplicated vote")
+	}
+	poll.Vote(vdr2, vtxID)
+	if !poll.Finished() {
+		t.Fatalf("Poll did not terminate after receiving alpha votes")
+	}
+
+	result := poll.Result()
+	if list := result.List(); len(list) != 1 {
+		t.Fatalf("Wrong number of vertices returned")
+	} else if retVtxID := list[0]; retVtxID != vtxID {
+		t.Fatalf("Wrong vertex returned")
+	} else if result.Count(vtxID) != 2 {
+		t.Fatalf("Wrong number of votes returned")
+	}
+}
+
+// benchmarkPollVotes measures the cost of tracking the votes of a poll whose
+// votes are split between two IDs
+func benchmarkPollVotes(b *testing.B, factory Factory) {
+	k := 20
+
+	vdrList := make([]ids.ShortID, k)
+	for i := range vdrList {
+		vdrList[i] = ids.ShortID{byte(i)}
+	}
+	vtxIDs := []ids.ID{{1}, {2}}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(vdrList...)
+
+		poll := factory.New(vdrs)
+		for j, vdr := range vdrList {
+			poll.Vote(vdr, vtxIDs[j%len(vtxIDs)])
+		}
+	}
+}
+
+func BenchmarkEarlyTermNoTraversalVotes(b *testing.B) {
+	benchmarkPollVotes(b, NewEarlyTermNoTraversalFactory(20))
+}
+
+func BenchmarkInternedEarlyTermNoTraversalVotes(b *testing.B) {
+	benchmarkPollVotes(b, NewInternedEarlyTermNoTraversalFactory(20))
+}
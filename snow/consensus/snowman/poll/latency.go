@@ -0,0 +1,80 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package poll
+
+import (
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+
+	safemath "github.com/ava-labs/avalanchego/utils/math"
+)
+
+// observeLatency records the time [vdr] took to vote in [poll]
+func (s *set) observeLatency(poll *poll, vdr ids.ShortID) {
+	if s.config.LatencyHalflife <= 0 {
+		return
+	}
+
+	now := s.clock.Time()
+	latency := float64(now.Sub(poll.start))
+
+	// A vote may arrive after its validator was removed from the validator
+	// set, in which case its latency would never be pruned
+	if s.config.Validators != nil && !s.config.Validators.Contains(vdr) {
+		return
+	}
+
+	s.latencyLock.Lock()
+	defer s.latencyLock.Unlock()
+
+	if averager, exists := s.latencies[vdr]; exists {
+		averager.Observe(latency, now)
+	} else {
+		s.latencies[vdr] = safemath.NewAverager(latency, s.config.LatencyHalflife, now)
+	}
+}
+
+// ValidatorLatencies returns the average time each validator has taken to
+// vote in the polls it was polled in. Validators that have never voted aren't
+// included. Only tracked if LatencyHalflife is positive.
+func (s *set) ValidatorLatencies() map[ids.ShortID]time.Duration {
+	s.latencyLock.Lock()
+	defer s.latencyLock.Unlock()
+
+	latencies := make(map[ids.ShortID]time.Duration, len(s.latencies))
+	for vdr, averager := range s.latencies {
+		latencies[vdr] = time.Duration(averager.Read())
+	}
+	return latencies
+}
+
+// pruneLatencies forgets the latencies of the validators that aren't in
+// [connected]
+func (s *set) pruneLatencies(connected ids.ShortSet) {
+	s.latencyLock.Lock()
+	defer s.latencyLock.Unlock()
+
+	for vdr := range s.latencies {
+		if !connected.Contains(vdr) {
+			delete(s.latencies, vdr)
+		}
+	}
+}
+
+// OnValidatorAdded implements the validators.SetCallbackListener interface.
+func (s *set) OnValidatorAdded(ids.ShortID, uint64) {}
+
+// OnValidatorRemoved implements the validators.SetCallbackListener interface.
+// The latency of the removed validator is forgotten.
+func (s *set) OnValidatorRemoved(vdr ids.ShortID, _ uint64) {
+	s.latencyLock.Lock()
+	defer s.latencyLock.Unlock()
+
+	delete(s.latencies, vdr)
+}
+
+// OnValidatorWeightChanged implements the validators.SetCallbackListener
+// interface.
+func (s *set) OnValidatorWeightChanged(ids.ShortID, uint64, uint64) {}
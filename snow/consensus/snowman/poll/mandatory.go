@@ -0,0 +1,46 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package poll
+
+import (
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+// mandatoryPoll wraps a poll so that it doesn't finish until every mandatory
+// validator has responded. A mandatory validator that is dropped keeps
+// blocking the poll, so that it can't finish without the mandatory vote. Such
+// a poll is only finished by timing it out or expiring it.
+type mandatoryPoll struct {
+	Poll
+
+	// pending are the mandatory validators that haven't yet responded
+	pending ids.ShortSet
+}
+
+func newMandatoryPoll(poll Poll, mandatory ids.ShortSet) Poll {
+	pending := ids.ShortSet{}
+	pending.Union(mandatory)
+	return &mandatoryPoll{
+		Poll:    poll,
+		pending: pending,
+	}
+}
+
+// Vote registers a response for this poll
+func (p *mandatoryPoll) Vote(vdr ids.ShortID, vote ids.ID) {
+	p.pending.Remove(vdr)
+	p.Poll.Vote(vdr, vote)
+}
+
+// Finished returns true when the wrapped poll is finished and every mandatory
+// validator has responded
+func (p *mandatoryPoll) Finished() bool { return p.pending.Len() == 0 && p.Poll.Finished() }
+
+func (p *mandatoryPoll) PrefixedString(prefix string) string {
+	return fmt.Sprintf("%s\n%swaiting on mandatory %s", p.Poll.PrefixedString(prefix), prefix, p.pending)
+}
+
+func (p *mandatoryPoll) String() string { return p.PrefixedString("") }
@@ -0,0 +1,166 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package poll
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/logging"
+)
+
+func TestAddWithMandatoryWaitsForMandatory(t *testing.T) {
+	s := NewSet(NewEarlyTermNoTraversalFactory(2), logging.NoLog{}, "", prometheus.NewRegistry())
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+	vdr3 := ids.ShortID{3} // k = 3
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1, vdr2, vdr3)
+
+	mandatory := ids.ShortSet{}
+	mandatory.Add(vdr3)
+
+	if !s.AddWithMandatory(0, vdrs, mandatory) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := s.Vote(0, vdr1, vtxID); finished {
+		t.Fatalf("Shouldn't have finished the poll")
+	} else if _, finished := s.Vote(0, vdr2, vtxID); finished {
+		t.Fatalf("Shouldn't have finished the poll before the mandatory validator responded")
+	}
+
+	result, finished := s.Vote(0, vdr3, vtxID)
+	if !finished {
+		t.Fatalf("Should have finished the poll once the mandatory validator responded")
+	} else if count := result.Count(vtxID); count != 3 {
+		t.Fatalf("Should have reported 3 votes, reported %d", count)
+	}
+}
+
+func TestAddWithMandatoryAllResponded(t *testing.T) {
+	s := NewSet(NewEarlyTermNoTraversalFactory(2), logging.NoLog{}, "", prometheus.NewRegistry())
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+	vdr3 := ids.ShortID{3} // k = 3
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1, vdr2, vdr3)
+
+	mandatory := ids.ShortSet{}
+	mandatory.Add(vdr1, vdr2)
+
+	if !s.AddWithMandatory(0, vdrs, mandatory) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := s.Vote(0, vdr1, vtxID); finished {
+		t.Fatalf("Shouldn't have finished the poll")
+	} else if _, finished := s.Vote(0, vdr2, vtxID); !finished {
+		t.Fatalf("Should have finished the poll once alpha was reached and the mandatory validators responded")
+	}
+}
+
+func TestAddWithMandatoryDropped(t *testing.T) {
+	s := NewSet(NewEarlyTermNoTraversalFactory(2), logging.NoLog{}, "", prometheus.NewRegistry())
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+	vdr3 := ids.ShortID{3} // k = 3
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1, vdr2, vdr3)
+
+	mandatory := ids.ShortSet{}
+	mandatory.Add(vdr3)
+
+	if !s.AddWithMandatory(0, vdrs, mandatory) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := s.Vote(0, vdr1, vtxID); finished {
+		t.Fatalf("Shouldn't have finished the poll")
+	} else if _, finished := s.Vote(0, vdr2, vtxID); finished {
+		t.Fatalf("Shouldn't have finished the poll before the mandatory validator responded")
+	} else if _, finished := s.Drop(0, vdr3); finished {
+		t.Fatalf("Shouldn't have finished the poll without the mandatory validator's vote")
+	} else if s.Len() != 1 {
+		t.Fatalf("Should have kept the poll blocked on the mandatory validator")
+	}
+
+	// Timing out the poll finishes it with the votes it has received
+	result, finished := s.Timeout(0)
+	if !finished {
+		t.Fatalf("Should have finished the poll once it timed out")
+	} else if count := result.Count(vtxID); count != 2 {
+		t.Fatalf("Should have reported 2 votes, reported %d", count)
+	}
+}
+
+func TestAddWithMandatoryDroppedExpires(t *testing.T) {
+	s := NewSetWithConfig(SetConfig{
+		Factory:    NewEarlyTermNoTraversalFactory(2),
+		Log:        logging.NoLog{},
+		Registerer: prometheus.NewRegistry(),
+		MaxPollAge: time.Minute,
+	})
+	defer s.Shutdown()
+
+	now := time.Now()
+	s.(*set).clock.Set(now)
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2} // k = 2
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1, vdr2)
+
+	mandatory := ids.ShortSet{}
+	mandatory.Add(vdr2)
+
+	if !s.AddWithMandatory(0, vdrs, mandatory) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := s.Vote(0, vdr1, vtxID); finished {
+		t.Fatalf("Shouldn't have finished the poll")
+	} else if _, finished := s.Drop(0, vdr2); finished {
+		t.Fatalf("Shouldn't have finished the poll without the mandatory validator's vote")
+	}
+
+	s.(*set).clock.Set(now.Add(time.Minute))
+	expired := s.ExpireStale()
+	if len(expired) != 1 {
+		t.Fatalf("Should have expired the blocked poll")
+	} else if count := expired[0].Result.Count(vtxID); count != 1 {
+		t.Fatalf("Should have reported 1 vote, reported %d", count)
+	} else if s.Len() != 0 {
+		t.Fatalf("Should have removed the expired poll")
+	}
+}
+
+func TestAddWithMandatoryNotPolled(t *testing.T) {
+	s := NewSet(NewEarlyTermNoTraversalFactory(2), logging.NoLog{}, "", prometheus.NewRegistry())
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2} // k = 1
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1)
+
+	mandatory := ids.ShortSet{}
+	mandatory.Add(vdr2)
+
+	if s.AddWithMandatory(0, vdrs, mandatory) {
+		t.Fatalf("Shouldn't have been able to add a poll that doesn't poll a mandatory validator")
+	} else if s.Len() != 0 {
+		t.Fatalf("Shouldn't have added the poll")
+	}
+}
@@ -0,0 +1,340 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package poll
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/timer"
+)
+
+// NewSharedDurations returns a poll duration histogram, registered with
+// [registerer], that can be shared by sets of many chains
+func NewSharedDurations(namespace string, registerer prometheus.Registerer) (*prometheus.HistogramVec, error) {
+	durPolls := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "poll_duration",
+		Help:      "Length of time the poll existed in milliseconds",
+		Buckets:   timer.MillisecondsBuckets,
+	}, []string{chainLabel})
+	if err := registerer.Register(durPolls); err != nil {
+		return nil, fmt.Errorf("failed to register poll_duration statistics due to %w", err)
+	}
+	return durPolls, nil
+}
+
+// NewSharedOutcomes returns a poll outcome counter, registered with
+// [registerer], that can be shared by sets of many chains
+func NewSharedOutcomes(namespace string, registerer prometheus.Registerer) (*prometheus.CounterVec, error) {
+	outcomes := newOutcomes(namespace, "", chainLabel)
+	if err := registerer.Register(outcomes); err != nil {
+		return nil, fmt.Errorf("failed to register poll_outcomes statistics due to %w", err)
+	}
+	return outcomes, nil
+}
+
+// NewSharedResponses returns a poll response counter, registered with
+// [registerer], that can be shared by sets of many chains
+func NewSharedResponses(namespace string, registerer prometheus.Registerer) (*prometheus.CounterVec, error) {
+	responses := newResponses(namespace, "", chainLabel)
+	if err := registerer.Register(responses); err != nil {
+		return nil, fmt.Errorf("failed to register poll_responses statistics due to %w", err)
+	}
+	return responses, nil
+}
+
+func newOutcomes(namespace, subsystem string, labels ...string) *prometheus.CounterVec {
+	return prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "poll_outcomes",
+		Help:      "Number of polls that finished successfully, finished without any votes, or were cancelled",
+	}, append(labels, outcomeLabel))
+}
+
+func newResponses(namespace, subsystem string, labels ...string) *prometheus.CounterVec {
+	return prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "poll_responses",
+		Help:      "Number of polled validators that voted or were dropped",
+	}, append(labels, outcomeLabel))
+}
+
+// initMetrics creates the set's metrics. Metrics of optional features are
+// only registered if the feature is enabled, but are always created so they
+// can be updated unconditionally.
+func (s *set) initMetrics() {
+	config := s.config
+
+	s.numPolls = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: config.Namespace,
+		Subsystem: config.Subsystem,
+		Name:      "polls",
+		Help:      "Number of pending network polls",
+	})
+	s.register("polls", s.numPolls)
+
+	if config.SharedDurations != nil {
+		s.durPolls = config.SharedDurations.WithLabelValues(config.Chain)
+	} else {
+		durPolls := prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: config.Namespace,
+			Subsystem: config.Subsystem,
+			Name:      "poll_duration",
+			Help:      "Length of time the poll existed in milliseconds",
+			Buckets:   timer.MillisecondsBuckets,
+		})
+		s.register("poll_duration", durPolls)
+		s.durPolls = durPolls
+	}
+
+	s.numEmptyPolls = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: config.Subsystem,
+		Name:      "empty_polls",
+		Help:      "Number of polls rejected due to not polling any validators",
+	})
+	s.register("empty_polls", s.numEmptyPolls)
+
+	s.numCancelledPolls = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: config.Subsystem,
+		Name:      "cancelled_polls",
+		Help:      "Number of polls cancelled before finishing",
+	})
+	s.register("cancelled_polls", s.numCancelledPolls)
+
+	s.numTimedOutPolls = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: config.Subsystem,
+		Name:      "poll_expired",
+		Help:      "Number of polls finished by timing out with the votes received so far",
+	})
+	s.register("poll_expired", s.numTimedOutPolls)
+
+	s.validatorSeconds = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: config.Subsystem,
+		Name:      "poll_validator_seconds",
+		Help:      "Cumulative number of validators polled by outstanding polls over time, in validator seconds",
+	})
+	if config.ValidatorSeconds {
+		s.register("poll_validator_seconds", s.validatorSeconds)
+	}
+
+	slowDurPolls := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: config.Namespace,
+		Subsystem: config.Subsystem,
+		Name:      "slow_poll_duration",
+		Help:      "Length of time the poll existed in seconds, for polls that existed for at least a second",
+		Buckets:   timer.SecondsBuckets,
+	})
+	if config.SlowDurations {
+		s.register("slow_poll_duration", slowDurPolls)
+	}
+	s.slowDurPolls = slowDurPolls
+
+	firstResponsePolls := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: config.Namespace,
+		Subsystem: config.Subsystem,
+		Name:      "poll_first_response",
+		Help:      "Length of time from the creation of a poll until its first response in milliseconds",
+		Buckets:   timer.MillisecondsBuckets,
+	})
+	votesPolls := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: config.Namespace,
+		Subsystem: config.Subsystem,
+		Name:      "poll_votes",
+		Help:      "Number of validators that voted in a finished poll",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 10),
+	})
+	if config.ResponseMetrics {
+		s.register("poll_first_response", firstResponsePolls)
+		s.register("poll_votes", votesPolls)
+	}
+	s.firstResponsePolls = firstResponsePolls
+	s.votesPolls = votesPolls
+
+	var outcomes *prometheus.CounterVec
+	if config.SharedOutcomes != nil {
+		outcomes = config.SharedOutcomes.MustCurryWith(prometheus.Labels{chainLabel: config.Chain})
+	} else {
+		outcomes = newOutcomes(config.Namespace, config.Subsystem)
+		if config.Outcomes {
+			s.register("poll_outcomes", outcomes)
+		}
+	}
+	s.numSuccessfulPolls = outcomes.WithLabelValues(successfulOutcome)
+	s.numFailedPolls = outcomes.WithLabelValues(failedOutcome)
+	s.numCancelledOutcomes = outcomes.WithLabelValues(cancelledOutcome)
+
+	var responses *prometheus.CounterVec
+	if config.SharedResponses != nil {
+		responses = config.SharedResponses.MustCurryWith(prometheus.Labels{chainLabel: config.Chain})
+	} else {
+		responses = newResponses(config.Namespace, config.Subsystem)
+		if config.Outcomes {
+			s.register("poll_responses", responses)
+		}
+	}
+	s.numVotes = responses.WithLabelValues(votedOutcome)
+	s.numDrops = responses.WithLabelValues(droppedOutcome)
+
+	s.numShadowDivergences = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: config.Subsystem,
+		Name:      "shadow_poll_divergences",
+		Help:      "Number of shadow polls whose results diverged from the polls they were shadowing",
+	})
+	if config.ShadowFactory != nil {
+		s.register("shadow_poll_divergences", s.numShadowDivergences)
+	}
+
+	s.numClampedDurations = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: config.Subsystem,
+		Name:      "clamped_poll_durations",
+		Help:      "Number of poll durations that exceeded the maximum reported duration",
+	})
+	if config.MaxObservedDuration > 0 {
+		s.register("clamped_poll_durations", s.numClampedDurations)
+	}
+
+	s.numRejectedRequeues = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: config.Subsystem,
+		Name:      "rejected_poll_requeues",
+		Help:      "Number of poll results that weren't requeued due to exceeding the maximum number of requeues",
+	})
+	if config.MaxRequeues > 0 {
+		s.register("rejected_poll_requeues", s.numRejectedRequeues)
+	}
+
+	s.numRejectedPolls = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: config.Subsystem,
+		Name:      "rejected_polls",
+		Help:      "Number of polls dropped due to exceeding the maximum number of outstanding polls",
+	})
+	if config.MaxOutstanding > 0 {
+		s.register("rejected_polls", s.numRejectedPolls)
+	}
+
+	s.numExpiredPolls = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: config.Subsystem,
+		Name:      "expired_polls",
+		Help:      "Number of polls finished due to exceeding the maximum poll age",
+	})
+	if config.MaxPollAge > 0 {
+		s.register("expired_polls", s.numExpiredPolls)
+	}
+
+	s.numUnknownValidators = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: config.Subsystem,
+		Name:      "unknown_poll_validators",
+		Help:      "Number of polled validators that weren't in the current validator set",
+	})
+	if config.Validators != nil && config.UnknownValidators != AllowUnknownValidators {
+		s.register("unknown_poll_validators", s.numUnknownValidators)
+	}
+
+	if s.dropRate != nil {
+		s.register("poll_drop_rate", prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: config.Namespace,
+			Subsystem: config.Subsystem,
+			Name:      "poll_drop_rate",
+			Help:      "Fraction of recent responses to polls that were drops",
+		}, func() float64 { return s.dropRate.Rate(s.clock.Time()) }))
+	}
+
+	if config.OldestPollAge {
+		s.register("oldest_poll_age_ms", prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: config.Namespace,
+			Subsystem: config.Subsystem,
+			Name:      "oldest_poll_age_ms",
+			Help:      "Length of time the oldest outstanding poll has existed in milliseconds",
+		}, func() float64 { return float64(s.oldestPollAge().Milliseconds()) }))
+	}
+}
+
+// register registers [collector], which reports the [name] statistics, and
+// tracks it to be unregistered on shutdown. A failure to register is logged
+// rather than returned, so the set remains usable.
+func (s *set) register(name string, collector prometheus.Collector) {
+	if err := s.config.Registerer.Register(collector); err != nil {
+		s.log.Error("failed to register %s statistics due to %s", name, err)
+	}
+	s.metrics = append(s.metrics, collector)
+}
+
+// metricName returns the fully qualified name of [collector]
+func metricName(collector prometheus.Collector) string {
+	metric, ok := collector.(prometheus.Metric)
+	if !ok {
+		return fmt.Sprintf("%T", collector)
+	}
+	desc := metric.Desc().String()
+	name := ""
+	if _, err := fmt.Sscanf(desc, "Desc{fqName: %q", &name); err != nil {
+		return desc
+	}
+	return name
+}
+
+// oldestPollAge returns the length of time the oldest outstanding poll has
+// existed, or 0 if there are no outstanding polls
+func (s *set) oldestPollAge() time.Duration {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if len(s.queue) == 0 {
+		return 0
+	}
+	return s.clock.Time().Sub(s.queue[0].start)
+}
+
+// trackValidatorSeconds accumulates the validator seconds of the outstanding
+// polls since the last change, and then changes the number of validators in
+// outstanding polls by [delta]
+// Assumes the lock is held
+func (s *set) trackValidatorSeconds(delta int) {
+	now := s.clock.Time()
+	if s.numPolledValidators > 0 {
+		elapsed := now.Sub(s.lastValidatorSecondsUpdate).Seconds()
+		s.validatorSeconds.Add(float64(s.numPolledValidators) * elapsed)
+	}
+	s.lastValidatorSecondsUpdate = now
+	s.numPolledValidators += delta
+}
+
+// observeFirstResponse reports the time until the first response to [poll],
+// if [vdr] is the first of the polled validators to respond
+// Assumes the lock is held exclusively, or the lock is held and the lock
+// stripe of [poll] is held
+func (s *set) observeFirstResponse(poll *poll, vdr ids.ShortID) {
+	if !poll.hasResponse && poll.pending(vdr) {
+		poll.hasResponse = true
+		s.firstResponsePolls.Observe(float64(s.clock.Time().Sub(poll.start).Milliseconds()))
+	}
+}
+
+// observeDuration reports the [duration] of a poll
+// Assumes the lock is held
+func (s *set) observeDuration(duration time.Duration) {
+	if maxDuration := s.config.MaxObservedDuration; maxDuration > 0 && duration > maxDuration {
+		duration = maxDuration
+		s.numClampedDurations.Inc()
+	}
+	s.durPolls.Observe(float64(duration.Milliseconds()))
+	if duration >= slowPollDuration {
+		s.slowDurPolls.Observe(duration.Seconds())
+	}
+}
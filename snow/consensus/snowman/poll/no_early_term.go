@@ -9,21 +9,35 @@ import (
 	"github.com/ava-labs/avalanchego/ids"
 )
 
-type noEarlyTermFactory struct{}
+type noEarlyTermFactory struct {
+	tieBreaker TieBreaker
+}
 
 // NewNoEarlyTermFactory returns a factory that returns polls with no early
 // termination
 func NewNoEarlyTermFactory() Factory { return noEarlyTermFactory{} }
 
-func (noEarlyTermFactory) New(vdrs ids.ShortBag) Poll {
-	return &noEarlyTermPoll{polled: vdrs}
+// NewNoEarlyTermFactoryWithTieBreaker returns a factory that returns polls
+// with no early termination, whose results break ties with [tieBreaker]
+func NewNoEarlyTermFactoryWithTieBreaker(tieBreaker TieBreaker) Factory {
+	return noEarlyTermFactory{tieBreaker: tieBreaker}
+}
+
+func (f noEarlyTermFactory) New(vdrs ids.ShortBag) Poll {
+	return &noEarlyTermPoll{
+		polled:     vdrs,
+		tieBreaker: f.tieBreaker,
+	}
 }
 
+func (noEarlyTermFactory) String() string { return "NoEarlyTerm" }
+
 // noEarlyTermPoll finishes when all polled validators either respond to the
 // query or a timeout occurs
 type noEarlyTermPoll struct {
-	votes  ids.Bag
-	polled ids.ShortBag
+	votes      ids.Bag
+	polled     ids.ShortBag
+	tieBreaker TieBreaker
 }
 
 // Vote registers a response for this poll
@@ -43,7 +57,7 @@ func (p *noEarlyTermPoll) Drop(vdr ids.ShortID) { p.polled.Remove(vdr) }
 func (p *noEarlyTermPoll) Finished() bool { return p.polled.Len() == 0 }
 
 // Result returns the result of this poll
-func (p *noEarlyTermPoll) Result() ids.Bag { return p.votes }
+func (p *noEarlyTermPoll) Result() ids.Bag { return breakTies(p.tieBreaker, p.votes) }
 
 func (p *noEarlyTermPoll) PrefixedString(prefix string) string {
 	return fmt.Sprintf("waiting on %s", p.polled.PrefixedString(prefix))
@@ -0,0 +1,29 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package poll
+
+import (
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/hashing"
+	"github.com/ava-labs/avalanchego/utils/wrappers"
+)
+
+// pollID deterministically derives an ID from [requestID] and the validators
+// that were polled. Polls with the same requestID and validators, regardless
+// of the order the validators were added to the bag, will have the same ID.
+func pollID(requestID uint32, vdrs ids.ShortBag) ids.ID {
+	vdrList := vdrs.List()
+	ids.SortShortIDs(vdrList)
+
+	p := wrappers.Packer{Bytes: make(
+		[]byte,
+		wrappers.IntLen+len(vdrList)*(hashing.AddrLen+wrappers.IntLen),
+	)}
+	p.PackInt(requestID)
+	for _, vdr := range vdrList {
+		p.PackFixedBytes(vdr[:])
+		p.PackInt(uint32(vdrs.Count(vdr)))
+	}
+	return ids.ID(hashing.ComputeHash256Array(p.Bytes))
+}
@@ -0,0 +1,50 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package poll
+
+import (
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+func TestPollIDDeterministic(t *testing.T) {
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+
+	vdrs1 := ids.ShortBag{}
+	vdrs1.Add(vdr1, vdr2, vdr2)
+
+	vdrs2 := ids.ShortBag{}
+	vdrs2.Add(vdr2, vdr1, vdr2)
+
+	if id1, id2 := pollID(0, vdrs1), pollID(0, vdrs2); id1 != id2 {
+		t.Fatalf("Identical polls should have the same ID, got %s and %s", id1, id2)
+	}
+}
+
+func TestPollIDDiffers(t *testing.T) {
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1, vdr2)
+
+	moreVdrs := ids.ShortBag{}
+	moreVdrs.Add(vdr1, vdr2, vdr2)
+
+	otherVdrs := ids.ShortBag{}
+	otherVdrs.Add(vdr1)
+
+	id := pollID(0, vdrs)
+	if otherID := pollID(1, vdrs); id == otherID {
+		t.Fatalf("Polls with different requestIDs should have different IDs")
+	}
+	if otherID := pollID(0, moreVdrs); id == otherID {
+		t.Fatalf("Polls with different validator counts should have different IDs")
+	}
+	if otherID := pollID(0, otherVdrs); id == otherID {
+		t.Fatalf("Polls with different validators should have different IDs")
+	}
+}
@@ -0,0 +1,54 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package poll
+
+import (
+	"container/heap"
+)
+
+// A pollQueue implements heap.Interface and holds the outstanding polls,
+// ordered by the time they were started
+type pollQueue []*poll
+
+func (pq pollQueue) Len() int           { return len(pq) }
+func (pq pollQueue) Less(i, j int) bool { return pq[i].start.Before(pq[j].start) }
+func (pq pollQueue) Swap(i, j int) {
+	pq[i], pq[j] = pq[j], pq[i]
+	pq[i].index = i
+	pq[j].index = j
+}
+
+// Push adds an item to this priority queue. x must have type *poll
+func (pq *pollQueue) Push(x interface{}) {
+	item := x.(*poll)
+	item.index = len(*pq)
+	*pq = append(*pq, item)
+}
+
+// Pop returns the next item in this queue
+func (pq *pollQueue) Pop() interface{} {
+	n := len(*pq)
+	item := (*pq)[n-1]
+	(*pq)[n-1] = nil // make sure the item is freed from memory
+	*pq = (*pq)[:n-1]
+	return item
+}
+
+// enqueue adds [poll] to the set's queue, if the set reports the age of its
+// oldest poll
+// Assumes the lock is held exclusively
+func (s *set) enqueue(poll *poll) {
+	if s.config.OldestPollAge {
+		heap.Push(&s.queue, poll)
+	}
+}
+
+// dequeue removes [poll] from the set's queue, if the set reports the age of
+// its oldest poll
+// Assumes the lock is held exclusively
+func (s *set) dequeue(poll *poll) {
+	if s.config.OldestPollAge {
+		heap.Remove(&s.queue, poll.index)
+	}
+}
@@ -0,0 +1,51 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package polltest
+
+import (
+	"errors"
+	"sync/atomic"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow/validators"
+)
+
+var errNoTargets = errors.New("none of the adversarial sampler's targets are validators")
+
+// AdversarialSampler is a poll.Sampler for fault injection testing that only
+// samples its targets, such as the slowest or least reliable validators
+type AdversarialSampler struct {
+	// sampled is the number of times Sample was called. It is updated
+	// atomically because the set may call Sample concurrently.
+	sampled int64
+
+	// Targets are the validators to sample, in order of preference. Targets
+	// that aren't in the sampled validator set are skipped.
+	Targets []ids.ShortID
+}
+
+// Sampled returns the number of times Sample was called
+func (s *AdversarialSampler) Sampled() int { return int(atomic.LoadInt64(&s.sampled)) }
+
+// Sample returns the first [size] targets in [vdrs]. If there are fewer than
+// [size] such targets, they are repeated.
+func (s *AdversarialSampler) Sample(vdrs validators.Set, size int) ([]ids.ShortID, error) {
+	atomic.AddInt64(&s.sampled, 1)
+
+	targets := []ids.ShortID(nil)
+	for _, vdr := range s.Targets {
+		if vdrs.Contains(vdr) {
+			targets = append(targets, vdr)
+		}
+	}
+	if len(targets) == 0 {
+		return nil, errNoTargets
+	}
+
+	sampled := make([]ids.ShortID, size)
+	for i := range sampled {
+		sampled[i] = targets[i%len(targets)]
+	}
+	return sampled, nil
+}
@@ -0,0 +1,180 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package poll
+
+import (
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+// PollResult describes a finished poll
+type PollResult struct {
+	RequestID uint32
+	Start     time.Time
+	Duration  time.Duration
+	Result    ids.Bag
+
+	// Requeues is the number of times this result has been requeued
+	Requeues int
+}
+
+// completedPoll is a finished poll whose result is retained until [expiry]
+type completedPoll struct {
+	requestID uint32
+	result    ids.Bag
+	expiry    time.Time
+}
+
+// resultBuffer is a fixed size ring buffer of the most recent poll results
+type resultBuffer struct {
+	results []PollResult
+	// next is the index the next result will be written to
+	next int
+	// full is true once every index has been written to
+	full bool
+}
+
+func newResultBuffer(size int) *resultBuffer {
+	return &resultBuffer{results: make([]PollResult, size)}
+}
+
+// Add [result], evicting the oldest result if the buffer is full
+func (b *resultBuffer) Add(result PollResult) {
+	if len(b.results) == 0 {
+		return
+	}
+
+	b.results[b.next] = result
+	b.next++
+	if b.next == len(b.results) {
+		b.next = 0
+		b.full = true
+	}
+}
+
+// List returns the results currently in the buffer, newest first
+func (b *resultBuffer) List() []PollResult {
+	size := b.next
+	if b.full {
+		size = len(b.results)
+	}
+
+	results := make([]PollResult, size)
+	for i := range results {
+		index := b.next - 1 - i
+		if index < 0 {
+			index += len(b.results)
+		}
+		results[i] = b.results[index]
+	}
+	return results
+}
+
+// LastResultFor returns the result of the most recently finished poll of
+// [containerID], if it is still cached
+func (s *set) LastResultFor(containerID ids.ID) (ids.Bag, bool) {
+	if s.containerResults == nil {
+		return ids.Bag{}, false
+	}
+	result, ok := s.containerResults.Get(containerID)
+	if !ok {
+		return ids.Bag{}, false
+	}
+	return result.(ids.Bag), true
+}
+
+// Result returns the result of the poll with [requestID] if it finished within
+// the last RetainAfterFinish
+func (s *set) Result(requestID uint32) (ids.Bag, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.sweepCompleted()
+	completed, ok := s.completed[requestID]
+	return completed.result, ok
+}
+
+// CombineResults returns the combined results of the polls with [requestIDs].
+// Returns false unless every poll finished within the last RetainAfterFinish.
+func (s *set) CombineResults(requestIDs ...uint32) (ids.Bag, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.sweepCompleted()
+	combined := ids.Bag{}
+	for _, requestID := range requestIDs {
+		completed, ok := s.completed[requestID]
+		if !ok {
+			return ids.Bag{}, false
+		}
+		for _, id := range completed.result.List() {
+			combined.AddCount(id, completed.result.Count(id))
+		}
+	}
+	return combined, true
+}
+
+// sweepCompleted removes the results of polls that finished more than
+// RetainAfterFinish ago
+// Assumes the lock is held
+func (s *set) sweepCompleted() {
+	now := s.clock.Time()
+	for len(s.completedOrder) > 0 {
+		completed := s.completedOrder[0]
+		if now.Before(completed.expiry) {
+			return
+		}
+		s.completedOrder = s.completedOrder[1:]
+
+		// If the requestID was reused, the retained result is from a more
+		// recent poll
+		if retained := s.completed[completed.requestID]; retained.expiry.Equal(completed.expiry) {
+			delete(s.completed, completed.requestID)
+		}
+	}
+}
+
+// RecentResults returns the most recently finished poll results, newest first
+func (s *set) RecentResults() []PollResult {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.recentResults.List()
+}
+
+// Requeue [result] to be returned by a later call to Requeued. Returns false if
+// the result of the poll with [result]'s requestID has already been requeued
+// the maximum number of times. The number of requeues is tracked by the set,
+// regardless of the value of [result.Requeues].
+func (s *set) Requeue(result PollResult) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	requeues := s.requeues[result.RequestID]
+	if requeues >= s.config.MaxRequeues {
+		s.log.Debug("dropping result of poll with requestID %d after %d requeues",
+			result.RequestID,
+			requeues)
+		s.numRejectedRequeues.Inc()
+		return false
+	}
+
+	requeues++
+	s.requeues[result.RequestID] = requeues
+	result.Requeues = requeues
+	s.requeued = append(s.requeued, result)
+	return true
+}
+
+// Requeued returns, and removes, the results that have been requeued since the
+// last call to Requeued
+func (s *set) Requeued() []PollResult {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	requeued := s.requeued
+	s.requeued = nil
+	return requeued
+}
@@ -0,0 +1,45 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package poll
+
+import (
+	"testing"
+)
+
+func TestResultBuffer(t *testing.T) {
+	b := newResultBuffer(3)
+	if results := b.List(); len(results) != 0 {
+		t.Fatalf("Shouldn't have reported any results, reported %d", len(results))
+	}
+
+	for requestID := uint32(0); requestID < 2; requestID++ {
+		b.Add(PollResult{RequestID: requestID})
+	}
+	if results := b.List(); len(results) != 2 {
+		t.Fatalf("Should have reported 2 results, reported %d", len(results))
+	} else if results[0].RequestID != 1 || results[1].RequestID != 0 {
+		t.Fatalf("Results should have been reported newest first: %v", results)
+	}
+
+	for requestID := uint32(2); requestID < 5; requestID++ {
+		b.Add(PollResult{RequestID: requestID})
+	}
+	results := b.List()
+	if len(results) != 3 {
+		t.Fatalf("Should have reported 3 results, reported %d", len(results))
+	}
+	for i, result := range results {
+		if expected := uint32(4 - i); result.RequestID != expected {
+			t.Fatalf("Expected result %d to have requestID %d, had %d", i, expected, result.RequestID)
+		}
+	}
+}
+
+func TestResultBufferDisabled(t *testing.T) {
+	b := newResultBuffer(0)
+	b.Add(PollResult{})
+	if results := b.List(); len(results) != 0 {
+		t.Fatalf("Shouldn't have reported any results, reported %d", len(results))
+	}
+}
@@ -0,0 +1,36 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package poll
+
+import (
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow/validators"
+)
+
+// Sampler selects the validators to poll from a validator set
+type Sampler interface {
+	// Sample [size] validators, potentially with duplicates, from [vdrs]
+	Sample(vdrs validators.Set, size int) ([]ids.ShortID, error)
+}
+
+type validatorSampler struct{}
+
+// NewValidatorSampler returns a sampler that samples validators with
+// probability proportional to their weight, using the validator set's sampler
+func NewValidatorSampler() Sampler { return validatorSampler{} }
+
+func (validatorSampler) String() string { return "ValidatorSampler" }
+
+func (validatorSampler) Sample(vdrs validators.Set, size int) ([]ids.ShortID, error) {
+	sampled, err := vdrs.Sample(size)
+	if err != nil {
+		return nil, err
+	}
+
+	vdrIDs := make([]ids.ShortID, len(sampled))
+	for i, vdr := range sampled {
+		vdrIDs[i] = vdr.ID()
+	}
+	return vdrIDs, nil
+}
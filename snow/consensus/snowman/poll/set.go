@@ -4,28 +4,480 @@
 package poll
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 
+	"github.com/ava-labs/avalanchego/cache"
 	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow/consensus/metrics"
+	"github.com/ava-labs/avalanchego/snow/validators"
 	"github.com/ava-labs/avalanchego/utils/logging"
 	"github.com/ava-labs/avalanchego/utils/timer"
+
+	safemath "github.com/ava-labs/avalanchego/utils/math"
+)
+
+const (
+	// redactedIDLen is the number of characters of an ID that are reported
+	// when IDs are redacted
+	redactedIDLen = 8
+
+	chainLabel   = "chain"
+	outcomeLabel = "outcome"
+
+	// Outcomes of polls, reported by the poll_outcomes metric
+	successfulOutcome = "successful"
+	failedOutcome     = "failed"
+	cancelledOutcome  = "cancelled"
+
+	// Outcomes of responses, reported by the poll_responses metric
+	votedOutcome   = "voted"
+	droppedOutcome = "dropped"
+
+	// numLockStripes is the number of locks responses to polls are striped
+	// across
+	numLockStripes = 64
+
+	// slowPollDuration is the duration after which a poll's duration is also
+	// reported in seconds, in addition to milliseconds
+	slowPollDuration = time.Second
+
+	// numSlowPollResponders is the number of the last validators to respond
+	// that are logged when a poll is slow to finish
+	numSlowPollResponders = 3
+
+	// expiryChecksPerMaxAge is the number of times stale polls are checked for
+	// per MaxPollAge, so a poll is expired at most MaxPollAge /
+	// expiryChecksPerMaxAge after it becomes stale
+	expiryChecksPerMaxAge = 4
+
+	// ImpossibleWeight is returned by WeightToWin if an ID can no longer
+	// receive enough weight to be decided
+	ImpossibleWeight = math.MaxUint64
+)
+
+var (
+	errFailedUnregister   = errors.New("failed to unregister poll statistics")
+	errUnsupportedSet     = errors.New("unsupported poll set implementation")
+	errTransferToSelf     = errors.New("can't transfer polls to the same set")
+	errTransferToShutdown = errors.New("can't transfer polls to a set that has been shut down")
+	errTransferOverLimit  = errors.New("transfer would exceed the maximum number of outstanding polls")
+	errDuplicateRequestID = errors.New("duplicated requestID")
 )
 
+// nextSetID is the ID of the most recently created set
+var nextSetID uint64
+
+// UnknownValidatorPolicy defines how a set handles polls of validators that
+// aren't in the set's current validator set
+type UnknownValidatorPolicy uint32
+
+// List of possible policies for handling unknown validators
+const (
+	// AllowUnknownValidators doesn't check the polled validators
+	AllowUnknownValidators UnknownValidatorPolicy = iota
+	// FilterUnknownValidators removes unknown validators from the poll
+	FilterUnknownValidators
+	// RejectUnknownValidators rejects polls that contain unknown validators
+	RejectUnknownValidators
+)
+
+func (p UnknownValidatorPolicy) String() string {
+	switch p {
+	case AllowUnknownValidators:
+		return "Allow"
+	case FilterUnknownValidators:
+		return "Filter"
+	case RejectUnknownValidators:
+		return "Reject"
+	default:
+		return fmt.Sprintf("Unknown(%d)", uint32(p))
+	}
+}
+
+// SetConfig configures a set of polls
+type SetConfig struct {
+	Factory    Factory
+	Log        logging.Logger
+	Namespace  string
+	Registerer prometheus.Registerer
+
+	// Subsystem, if non-empty, is included in the names of the set's metrics
+	// between the namespace and the name of the metric
+	Subsystem string
+
+	// RedactIDs causes the JSON representation of the set to only report
+	// truncated validator IDs
+	RedactIDs bool
+
+	// Aggregator, if non-nil, is used to report statistics across multiple
+	// sets. The set is registered on creation and deregistered on shutdown.
+	Aggregator metrics.PollAggregator
+
+	// SharedDurations, if non-nil, is used to report poll durations rather
+	// than registering a histogram per set. Observations are labeled with
+	// [Chain]. See NewSharedDurations.
+	SharedDurations *prometheus.HistogramVec
+	Chain           string
+
+	// SharedOutcomes and SharedResponses, if non-nil, are used to report the
+	// outcomes of polls and responses rather than registering counters per
+	// set. Like SharedDurations, observations are labeled with [Chain]. See
+	// NewSharedOutcomes and NewSharedResponses.
+	SharedOutcomes  *prometheus.CounterVec
+	SharedResponses *prometheus.CounterVec
+
+	// Validators, if non-nil, is the live validator set that is sampled by
+	// AddFromValidators
+	Validators validators.Set
+
+	// Sampler, if non-nil, selects the validators polled by
+	// AddFromValidators. Defaults to sampling by weight.
+	Sampler Sampler
+
+	// UnknownValidators defines how polls containing validators that aren't
+	// in [Validators] are handled. Ignored if [Validators] is nil.
+	UnknownValidators UnknownValidatorPolicy
+
+	// ShadowFactory, if non-nil, creates a shadow poll for every poll. Shadow
+	// polls are given the same responses, and their results are compared
+	// against the results of the polls created by [Factory]. Divergences are
+	// logged and counted but otherwise don't impact the set.
+	ShadowFactory Factory
+
+	// MaxObservedDuration, if positive, is the maximum poll duration that
+	// will be reported. Longer durations are reported as this value and are
+	// counted separately.
+	MaxObservedDuration time.Duration
+
+	// Alpha, if positive, enables tracking IDs that repeatedly fail to reach
+	// alpha votes. See ChurningBlocks.
+	Alpha int
+
+	// AlphaWeight is the weight of votes, according to Validators, an ID must
+	// receive to be decided. See WeightToWin.
+	AlphaWeight uint64
+
+	// RecentResults is the number of the most recently finished poll results
+	// to keep. If 0, no results are kept. See RecentResults.
+	RecentResults int
+
+	// IncludeWeights reports the current weight of each polled validator in
+	// the set's JSON summary. Requires Validators to be set.
+	IncludeWeights bool
+
+	// DropRateWindow is the duration over which the poll_drop_rate metric is
+	// calculated. If 0, the drop rate isn't tracked.
+	DropRateWindow time.Duration
+
+	// LatencyHalflife is the halflife of the moving averages of each
+	// validator's response latency reported by ValidatorLatencies. If 0, the
+	// latencies aren't tracked. The latencies of validators that disconnect,
+	// or that are removed from Validators, are forgotten.
+	LatencyHalflife time.Duration
+
+	// SlowDurations additionally reports the durations of polls that took at
+	// least a second in the slow_poll_duration histogram, which has coarser
+	// buckets than poll_duration
+	SlowDurations bool
+
+	// ResponseMetrics reports the time until the first response to each poll
+	// in poll_first_response, and the number of validators that voted in each
+	// finished poll in poll_votes
+	ResponseMetrics bool
+
+	// Outcomes reports the outcomes of polls and responses in poll_outcomes
+	// and poll_responses. The outcomes are always reported to SharedOutcomes
+	// and SharedResponses if they are set.
+	Outcomes bool
+
+	// OldestPollAge reports the age of the oldest outstanding poll in
+	// oldest_poll_age_ms
+	OldestPollAge bool
+
+	// ValidatorSeconds reports the cumulative number of validators polled by
+	// outstanding polls over time in poll_validator_seconds
+	ValidatorSeconds bool
+
+	// SlowPollThreshold is the duration after which finishing a poll will be
+	// logged as a warning. If 0, slow polls aren't logged.
+	SlowPollThreshold time.Duration
+
+	// MetricBatchSize, if non-zero, causes changes to the number of pending
+	// polls and the durations of finished polls to be reported to prometheus
+	// in batches. Changes are reported once MetricBatchSize polls have been
+	// added or finished, every MetricFlushInterval if it is non-zero, and when
+	// the set is shut down.
+	MetricBatchSize     int
+	MetricFlushInterval time.Duration
+
+	// MaxPollAge is the duration after which an outstanding poll is expired.
+	// Expired polls are finished as if every validator that hasn't responded
+	// was dropped. The set periodically expires stale polls itself, and
+	// ExpireStale may be called to expire them immediately. If 0, polls never
+	// expire.
+	MaxPollAge time.Duration
+
+	// MaxOutstanding is the maximum number of outstanding polls. Polls added
+	// while the set is at capacity are dropped. If 0, the number of polls
+	// isn't limited.
+	MaxOutstanding int
+
+	// RetainAfterFinish is the duration the results of finished polls remain
+	// available from Result. If 0, results aren't retained.
+	RetainAfterFinish time.Duration
+
+	// ContainerResults is the number of containers whose most recent poll
+	// result is kept. If 0, no results are kept. See LastResultFor.
+	ContainerResults int
+
+	// MaxRequeues is the number of times a finished poll's result may be
+	// requeued for reprocessing. If 0, results can't be requeued. See Requeue.
+	MaxRequeues int
+
+	// AllowForceFinalize enables ForceFinalize. It should only be enabled to
+	// allow operators to intervene in stuck polls.
+	AllowForceFinalize bool
+
+	// OnCreate, if non-nil, is called with the polled validators after a poll
+	// has been added to the set. It is called without holding the set's lock,
+	// so it may call back into the set.
+	OnCreate func(requestID uint32, vdrs ids.ShortBag)
+
+	// OnFinish, if non-nil, is called with the result of every poll that
+	// finishes. Like OnCreate, it is called without holding the set's lock.
+	OnFinish func(result PollResult)
+
+	// OrderedFinish, if true, reports results that finish while OnFinish is
+	// being called in order of their requestIDs, rather than in the order
+	// their polls finished.
+	OrderedFinish bool
+}
+
 type poll struct {
 	Poll
+	id    ids.ID
 	start time.Time
+	index int // Index in the set's queue
+
+	// containerID is the container being polled, if hasContainer is true
+	containerID  ids.ID
+	hasContainer bool
+
+	// mandatory are the validators that must respond before the poll can
+	// finish, if the poll was added with AddWithMandatory
+	mandatory ids.ShortSet
+
+	// vdrs, responded, and dropped are tracked for reporting purposes only
+	vdrs      ids.ShortBag
+	responded ids.ShortSet
+	dropped   ids.ShortSet
+
+	// responders are the validators that have responded, in the order they
+	// responded
+	responders []ids.ShortID
+
+	// votes are the IDs the validators that have responded voted for. They are
+	// only tracked if the set has Validators and AlphaWeight configured, as
+	// they are only needed by WeightToWin.
+	votes map[ids.ShortID]ids.ID
+
+	// retries tracks the number of times each validator has failed to
+	// respond without being dropped from the poll
+	retries map[ids.ShortID]int
+
+	// finished is set once the poll has finished, by the response that
+	// caused it to finish. A finished poll is about to be removed by that
+	// response, so it must otherwise be treated as if it was already removed.
+	finished bool
+
+	// hasResponse is set once a polled validator has voted or been dropped
+	hasResponse bool
+
+	// shadow, if non-nil, is given the same responses as this poll. Its
+	// result is compared against this poll's result, but is otherwise unused.
+	shadow Poll
+}
+
+// size returns the number of validators that were polled, including
+// duplicates
+func (p *poll) size() int { return p.vdrs.Len() }
+
+// pending returns true if [vdr] was polled and hasn't responded or been
+// dropped yet
+func (p *poll) pending(vdr ids.ShortID) bool {
+	return p.vdrs.Count(vdr) > 0 && !p.responded.Contains(vdr) && !p.dropped.Contains(vdr)
 }
 
 type set struct {
-	log      logging.Logger
+	// id uniquely identifies the set, and defines the order in which sets are
+	// locked when polls are transferred between them
+	id uint64
+
+	// lock guards the polls map. Responses to polls are processed while
+	// holding the read lock and the lock stripe of the poll.
+	lock    sync.RWMutex
+	stripes [numLockStripes]sync.Mutex
+
+	config  SetConfig
+	log     logging.Logger
+	factory Factory
+	polls   map[uint32]*poll
+	sampler Sampler
+	clock   timer.Clock
+
+	// queue holds the outstanding polls ordered by their start times, so the
+	// age of the oldest poll can be reported without scanning every poll. It
+	// is only maintained if OldestPollAge is set.
+	queue pollQueue
+
+	// missStreaks tracks, for each ID voted for in the last finished poll,
+	// the number of consecutive polls it has been voted for in without
+	// reaching alpha votes
+	missStreaks map[ids.ID]int
+
+	// batchedPolls, batchedDurPolls, and batchedSlowDurPolls are non-nil if
+	// metric updates are batched. They are flushed after batchedOps polls
+	// have been added or finished since lastFlush, every MetricFlushInterval
+	// by flusher, and on shutdown.
+	batchedPolls        *batchedGauge
+	batchedDurPolls     *batchedObserver
+	batchedSlowDurPolls *batchedObserver
+	batchedOps          int
+	lastFlush           time.Time
+	flusher             *timer.Repeater
+
+	// expirer, if non-nil, expires stale polls every MaxPollAge /
+	// expiryChecksPerMaxAge
+	expirer *timer.Repeater
+
+	// recentResults are the most recently finished poll results
+	recentResults *resultBuffer
+
+	// completed maps the requestIDs of polls that finished less than
+	// RetainAfterFinish ago to their results. completedOrder contains the
+	// same polls, ordered by when they finished.
+	completed      map[uint32]completedPoll
+	completedOrder []completedPoll
+
+	// containerResults, if non-nil, maps containerIDs to the result of the
+	// most recently finished poll of the container
+	containerResults *cache.LRU
+
+	// dropRate, if non-nil, tracks the fraction of recent responses that were
+	// drops
+	dropRate *dropRate
+
+	// latencyLock guards latencies, which are updated while responses are
+	// being processed concurrently
+	latencyLock sync.Mutex
+
+	// latencies tracks the average time each validator has taken to vote
+	// since the start of the polls it was polled in
+	latencies map[ids.ShortID]safemath.Averager
+
+	// numPolledValidators is the number of validators polled by the
+	// outstanding polls, as of lastValidatorSecondsUpdate
+	numPolledValidators        int
+	lastValidatorSecondsUpdate time.Time
+
+	// requeued are the results that have been requeued for reprocessing, in
+	// the order they were requeued
+	requeued []PollResult
+
+	// requeues is the number of times the result of the poll with each
+	// requestID has been requeued. Entries are removed once a new poll is
+	// added with the same requestID.
+	requeues map[uint32]int
+
+	// shutdown is true once no new polls should be accepted
+	shutdown bool
+	// callbacks tracks the callbacks that are currently executing
+	callbacks sync.WaitGroup
+
+	// notifyLock is held while reporting finished results when OrderedFinish
+	// is set. finished are the results waiting to be reported, guarded by
+	// finishedLock.
+	notifyLock   sync.Mutex
+	finishedLock sync.Mutex
+	finished     []PollResult
+
 	numPolls prometheus.Gauge
-	durPolls prometheus.Histogram
-	factory  Factory
-	polls    map[uint32]poll
+	durPolls prometheus.Observer
+
+	// firstResponsePolls tracks the time from the creation of a poll until the
+	// first response to the poll
+	firstResponsePolls prometheus.Observer
+
+	// votesPolls tracks the number of validators that voted in each finished
+	// poll
+	votesPolls prometheus.Observer
+
+	// slowDurPolls additionally tracks the durations of polls that took at
+	// least slowPollDuration, with coarser buckets than durPolls
+	slowDurPolls prometheus.Observer
+
+	// numEmptyPolls tracks the number of polls that were rejected because no
+	// validators were provided
+	numEmptyPolls prometheus.Counter
+
+	// numCancelledPolls tracks the number of polls that were removed before
+	// finishing
+	numCancelledPolls prometheus.Counter
+
+	// numExpiredPolls tracks the number of polls that were finished due to
+	// exceeding the maximum poll age
+	numExpiredPolls prometheus.Counter
+
+	// numTimedOutPolls tracks the number of polls that were finished by
+	// Timeout
+	numTimedOutPolls prometheus.Counter
+
+	// numShadowDivergences tracks the number of shadow polls that reported
+	// different results than the polls they were shadowing
+	numShadowDivergences prometheus.Counter
+
+	// numUnknownValidators tracks the number of polled validators that
+	// weren't in the current validator set
+	numUnknownValidators prometheus.Counter
+
+	// numRejectedRequeues tracks the number of results that weren't requeued
+	// due to having already been requeued MaxRequeues times
+	numRejectedRequeues prometheus.Counter
+
+	// numRejectedPolls tracks the number of polls that were dropped due to
+	// the set having MaxOutstanding polls
+	numRejectedPolls prometheus.Counter
+
+	// validatorSeconds tracks the cumulative number of validators polled by
+	// outstanding polls over time
+	validatorSeconds prometheus.Counter
+
+	// numClampedDurations tracks the number of poll durations that exceeded
+	// the maximum observed duration
+	numClampedDurations prometheus.Counter
+
+	// numSuccessfulPolls, numFailedPolls, and numCancelledOutcomes track the
+	// outcomes of polls. A poll fails if it finishes without any votes.
+	numSuccessfulPolls   prometheus.Counter
+	numFailedPolls       prometheus.Counter
+	numCancelledOutcomes prometheus.Counter
+
+	// numVotes and numDrops track the number of polled validators that voted
+	// or were dropped
+	numVotes prometheus.Counter
+	numDrops prometheus.Counter
+
+	// metrics are the collectors registered by this set
+	metrics []prometheus.Collector
 }
 
 // NewSet returns a new empty set of polls
@@ -35,55 +487,300 @@ func NewSet(
 	namespace string,
 	registerer prometheus.Registerer,
 ) Set {
-	numPolls := prometheus.NewGauge(prometheus.GaugeOpts{
-		Namespace: namespace,
-		Name:      "polls",
-		Help:      "Number of pending network polls",
+	return NewSetWithConfig(SetConfig{
+		Factory:    factory,
+		Log:        log,
+		Namespace:  namespace,
+		Registerer: registerer,
 	})
-	if err := registerer.Register(numPolls); err != nil {
-		log.Error("failed to register polls statistics due to %s", err)
-	}
+}
 
-	durPolls := prometheus.NewHistogram(prometheus.HistogramOpts{
-		Namespace: namespace,
-		Name:      "poll_duration",
-		Help:      "Length of time the poll existed in milliseconds",
-		Buckets:   timer.MillisecondsBuckets,
+// NewSetWithShadow returns a new empty set of polls whose results are driven by
+// polls created by [primary], and compared against polls created by [shadow]
+func NewSetWithShadow(
+	primary Factory,
+	shadow Factory,
+	log logging.Logger,
+	namespace string,
+	registerer prometheus.Registerer,
+) Set {
+	return NewSetWithConfig(SetConfig{
+		Factory:       primary,
+		ShadowFactory: shadow,
+		Log:           log,
+		Namespace:     namespace,
+		Registerer:    registerer,
 	})
-	if err := registerer.Register(durPolls); err != nil {
-		log.Error("failed to register poll_duration statistics due to %s", err)
+}
+
+// NewSetWithConfig returns a new empty set of polls configured by [config]
+func NewSetWithConfig(config SetConfig) Set {
+	s := &set{
+		id:      atomic.AddUint64(&nextSetID, 1),
+		config:  config,
+		log:     config.Log,
+		factory: config.Factory,
+		polls:   make(map[uint32]*poll),
+		sampler: config.Sampler,
+
+		recentResults: newResultBuffer(config.RecentResults),
+		completed:     make(map[uint32]completedPoll),
+		requeues:      make(map[uint32]int),
+		latencies:     make(map[ids.ShortID]safemath.Averager),
+	}
+	if s.sampler == nil {
+		s.sampler = NewValidatorSampler()
+	}
+	if config.DropRateWindow > 0 {
+		s.dropRate = newDropRate(config.DropRateWindow)
 	}
+	s.initMetrics()
+	if config.MetricBatchSize > 0 {
+		s.batchedPolls = &batchedGauge{Gauge: s.numPolls}
+		s.batchedDurPolls = &batchedObserver{Observer: s.durPolls}
+		s.batchedSlowDurPolls = &batchedObserver{Observer: s.slowDurPolls}
+		s.numPolls = s.batchedPolls
+		s.durPolls = s.batchedDurPolls
+		s.slowDurPolls = s.batchedSlowDurPolls
+		s.lastFlush = s.clock.Time()
 
-	return &set{
-		log:      log,
-		numPolls: numPolls,
-		durPolls: durPolls,
-		factory:  factory,
-		polls:    make(map[uint32]poll),
+		// Without a periodic flush, updates to a set that stops adding and
+		// finishing polls would never be reported
+		if config.MetricFlushInterval > 0 {
+			s.flusher = timer.NewRepeater(s.flushBatchedMetrics, config.MetricFlushInterval)
+			go s.flusher.Dispatch()
+		}
 	}
+	if config.MaxPollAge > 0 {
+		// Polls whose responses are lost would otherwise remain outstanding
+		// until the owner of the set expired them
+		s.expirer = timer.NewRepeater(func() { s.ExpireStale() }, config.MaxPollAge/expiryChecksPerMaxAge)
+		go s.expirer.Dispatch()
+	}
+	if config.ContainerResults > 0 {
+		s.containerResults = &cache.LRU{Size: config.ContainerResults}
+	}
+	if config.Validators != nil && config.LatencyHalflife > 0 {
+		config.Validators.RegisterCallbackListener(s)
+	}
+	if config.Aggregator != nil {
+		config.Aggregator.Register(s)
+	}
+	return s
 }
 
 // Add to the current set of polls
 // Returns true if the poll was registered correctly and the network sample
 //         should be made.
 func (s *set) Add(requestID uint32, vdrs ids.ShortBag) bool {
+	return s.addWith(requestID, vdrs, nil)
+}
+
+// AddForContainer adds a poll of [containerID] to the current set of polls.
+// Returns true if the poll was registered correctly and the network sample
+// should be made.
+func (s *set) AddForContainer(requestID uint32, containerID ids.ID, vdrs ids.ShortBag) bool {
+	return s.addWith(requestID, vdrs, func(poll *poll) {
+		poll.containerID = containerID
+		poll.hasContainer = true
+	})
+}
+
+// AddWithMandatory adds a poll to the current set of polls that won't finish
+// until every validator in [mandatory] has responded. If a mandatory validator
+// is dropped, the poll is only finished by Timeout or ExpireStale.
+// Returns true if the poll was registered correctly and the network sample
+// should be made.
+func (s *set) AddWithMandatory(requestID uint32, vdrs ids.ShortBag, mandatory ids.ShortSet) bool {
+	for _, vdr := range mandatory.List() {
+		if vdrs.Count(vdr) == 0 {
+			s.log.Debug("dropping poll with requestID %d due to not polling mandatory validator %s",
+				requestID,
+				vdr)
+			return false
+		}
+	}
+	return s.addWith(requestID, vdrs, func(poll *poll) {
+		poll.Poll = newMandatoryPoll(poll.Poll, mandatory)
+		poll.mandatory = mandatory
+	})
+}
+
+// addWith adds a poll to the current set of polls. If the poll is added and
+// [init] is non-nil, [init] is called with the poll while the lock is held.
+func (s *set) addWith(requestID uint32, vdrs ids.ShortBag, init func(*poll)) bool {
+	var created ids.ShortBag
+	if s.config.OnCreate != nil {
+		created = copyBag(vdrs) // the poll may modify the provided bag
+	}
+
+	s.lock.Lock()
+	added := s.add(requestID, vdrs)
+	if added && init != nil {
+		init(s.polls[requestID])
+	}
+	notify := added && s.config.OnCreate != nil
+	if notify {
+		s.callbacks.Add(1)
+	}
+	s.lock.Unlock()
+
+	if notify {
+		defer s.callbacks.Done()
+		s.config.OnCreate(requestID, created)
+	}
+	return added
+}
+
+// ExpireStale finishes every poll that has been outstanding for at least
+// MaxPollAge, treating the validators that haven't responded as dropped, and
+// returns their results in order of their requestIDs
+func (s *set) ExpireStale() []PollResult {
+	maxAge := s.config.MaxPollAge
+	if maxAge <= 0 {
+		return nil
+	}
+
+	s.lock.Lock()
+	now := s.clock.Time()
+	expired := []PollResult(nil)
+	for requestID, poll := range s.polls {
+		if poll.finished || now.Sub(poll.start) < maxAge {
+			continue
+		}
+
+		s.log.Debug("expiring poll with requestID %d after %s", requestID, now.Sub(poll.start))
+		s.dropPending(poll)
+		poll.finished = true
+		result := s.finish(requestID, poll, poll.Result())
+		s.compareShadow(requestID, poll, result.Result)
+		s.numExpiredPolls.Inc()
+		expired = append(expired, result)
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i].RequestID < expired[j].RequestID })
+
+	notify := s.config.OnFinish != nil && !s.shutdown
+	if notify {
+		s.callbacks.Add(len(expired))
+	}
+	s.lock.Unlock()
+
+	if notify {
+		for _, result := range expired {
+			s.notifyFinish(result)
+		}
+	}
+	return expired
+}
+
+// AddFromValidators samples [sampleSize] validators from the current validator
+// set and adds a poll of them to the current set of polls.
+// Returns the sampled validators and true if the poll was registered correctly
+//         and the network sample should be made.
+func (s *set) AddFromValidators(requestID uint32, sampleSize int) (ids.ShortBag, bool) {
+	if s.config.Validators == nil {
+		s.log.Error("dropping poll with requestID %d due to not having a validator set", requestID)
+		return ids.ShortBag{}, false
+	}
+
+	sampled, err := s.sampler.Sample(s.config.Validators, sampleSize)
+	if err != nil {
+		s.log.Error("dropping poll with requestID %d due to an insufficient number of validators", requestID)
+		return ids.ShortBag{}, false
+	}
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(sampled...)
+	polled := copyBag(vdrs) // the poll may modify the provided bag
+	return polled, s.Add(requestID, vdrs)
+}
+
+// add assumes the lock is held
+func (s *set) add(requestID uint32, vdrs ids.ShortBag) bool {
+	return s.addWithLimit(requestID, vdrs, s.config.MaxOutstanding)
+}
+
+// addWithLimit adds a poll unless there are already [maxOutstanding] polls. If
+// [maxOutstanding] is 0, the number of polls isn't limited.
+// Assumes the lock is held
+func (s *set) addWithLimit(requestID uint32, vdrs ids.ShortBag, maxOutstanding int) bool {
+	if s.shutdown {
+		s.log.Debug("dropping poll with requestID %d due to the set being shutdown", requestID)
+		return false
+	}
+	if s.config.Validators != nil && s.config.UnknownValidators != AllowUnknownValidators {
+		known := ids.ShortBag{}
+		numUnknown := 0
+		for _, vdr := range vdrs.List() {
+			if s.config.Validators.Contains(vdr) {
+				known.AddCount(vdr, vdrs.Count(vdr))
+			} else {
+				numUnknown++
+			}
+		}
+		if numUnknown > 0 {
+			s.numUnknownValidators.Add(float64(numUnknown))
+			if s.config.UnknownValidators == RejectUnknownValidators {
+				s.log.Debug("dropping poll with requestID %d due to polling %d unknown validators",
+					requestID,
+					numUnknown)
+				return false
+			}
+			s.log.Debug("filtering %d unknown validators from poll with requestID %d",
+				numUnknown,
+				requestID)
+			vdrs = known
+		}
+	}
+	if vdrs.Len() == 0 {
+		// A poll of no validators would finish immediately without any votes
+		s.log.Warn("dropping poll with requestID %d due to not polling any validators", requestID)
+		s.numEmptyPolls.Inc()
+		return false
+	}
 	if _, exists := s.polls[requestID]; exists {
 		s.log.Debug("dropping poll due to duplicated requestID: %d", requestID)
 		return false
 	}
+	if maxOutstanding > 0 && len(s.polls) >= maxOutstanding {
+		s.log.Debug("dropping poll with requestID %d due to already having %d outstanding polls",
+			requestID,
+			len(s.polls))
+		s.numRejectedPolls.Inc()
+		return false
+	}
 
 	s.log.Verbo("creating poll with requestID %d and validators %s",
 		requestID,
 		&vdrs)
 
-	s.polls[requestID] = poll{
-		Poll:  s.factory.New(vdrs), // create the new poll
-		start: time.Now(),
+	p := &poll{
+		id:    pollID(requestID, vdrs),
+		start: s.clock.Time(),
+		vdrs:  copyBag(vdrs), // the poll may modify the provided bag
 	}
+	if s.config.ShadowFactory != nil {
+		p.shadow = s.newPoll(s.config.ShadowFactory, copyBag(vdrs))
+	}
+	p.Poll = s.newPoll(s.factory, vdrs) // create the new poll
+	s.polls[requestID] = p
+	s.enqueue(p)
+	delete(s.requeues, requestID)
 	s.numPolls.Inc() // increase the metrics
+	s.maybeFlushMetrics()
+	s.trackValidatorSeconds(p.size())
 	return true
 }
 
+// newPoll returns a poll of [vdrs] created by [factory], which is given the
+// weights of the validators if it supports them
+func (s *set) newPoll(factory Factory, vdrs ids.ShortBag) Poll {
+	if weighted, ok := factory.(WeightedFactory); ok && s.config.Validators != nil {
+		return weighted.NewWeighted(vdrs, s.config.Validators)
+	}
+	return factory.New(vdrs)
+}
+
 // Vote registers the connections response to a query for [id]. If there was no
 // query, or the response has already be registered, nothing is performed.
 func (s *set) Vote(
@@ -91,67 +788,928 @@ func (s *set) Vote(
 	vdr ids.ShortID,
 	vote ids.ID,
 ) (ids.Bag, bool) {
-	poll, exists := s.polls[requestID]
-	if !exists {
-		s.log.Verbo("dropping vote from %s to an unknown poll with requestID: %d",
+	return s.respond(requestID, vdr, func(poll *poll) {
+		s.log.Verbo("processing vote from %s in the poll with requestID: %d with the vote %s",
 			vdr,
-			requestID)
-		return ids.Bag{}, false
-	}
+			requestID,
+			vote)
 
-	s.log.Verbo("processing vote from %s in the poll with requestID: %d with the vote %s",
-		vdr,
-		requestID,
-		vote)
+		s.vote(poll, vdr, vote)
+	})
+}
 
+// vote registers [vdr]'s [vote] in [poll]
+// Assumes the lock is held exclusively, or the lock is held and the lock
+// stripe of [poll] is held
+func (s *set) vote(poll *poll, vdr ids.ShortID, vote ids.ID) {
+	if poll.pending(vdr) {
+		poll.responded.Add(vdr)
+		poll.responders = append(poll.responders, vdr)
+		if s.config.Validators != nil && s.config.AlphaWeight > 0 {
+			if poll.votes == nil {
+				poll.votes = make(map[ids.ShortID]ids.ID)
+			}
+			poll.votes[vdr] = vote
+		}
+		s.numVotes.Inc()
+		if s.dropRate != nil {
+			s.dropRate.Vote(s.clock.Time())
+		}
+		s.observeLatency(poll, vdr)
+	}
 	poll.Vote(vdr, vote)
-	if !poll.Finished() {
-		return ids.Bag{}, false
+	if poll.shadow != nil {
+		poll.shadow.Vote(vdr, vote)
 	}
+}
 
-	s.log.Verbo("poll with requestID %d finished as %s", requestID, poll)
+// VoteMany registers [vdr]'s responses to multiple polls, where [votes] maps
+// the requestIDs of the polls to [vdr]'s vote in each poll. The results of
+// the polls that finished are returned, keyed by their requestIDs.
+func (s *set) VoteMany(vdr ids.ShortID, votes map[uint32]ids.ID) map[uint32]ids.Bag {
+	s.lock.Lock()
+	results := make(map[uint32]ids.Bag)
+	finished := []PollResult(nil)
+	for requestID, vote := range votes {
+		poll, exists := s.polls[requestID]
+		if !exists {
+			s.log.Verbo("dropping vote from %s to an unknown poll with requestID: %d",
+				vdr,
+				requestID)
+			continue
+		}
+		if poll.finished {
+			continue
+		}
 
-	delete(s.polls, requestID) // remove the poll from the current set
-	s.durPolls.Observe(float64(time.Since(poll.start).Milliseconds()))
-	s.numPolls.Dec() // decrease the metrics
-	return poll.Result(), true
+		s.log.Verbo("processing vote from %s in the poll with requestID: %d with the vote %s",
+			vdr,
+			requestID,
+			vote)
+
+		s.observeFirstResponse(poll, vdr)
+		s.vote(poll, vdr, vote)
+		if poll.finished = poll.Finished(); poll.finished {
+			result := s.finish(requestID, poll, poll.Result())
+			s.compareShadow(requestID, poll, result.Result)
+			results[requestID] = result.Result
+			finished = append(finished, result)
+		}
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].RequestID < finished[j].RequestID })
+
+	notify := s.config.OnFinish != nil && !s.shutdown
+	if notify {
+		s.callbacks.Add(len(finished))
+	}
+	s.lock.Unlock()
+
+	if notify {
+		for _, result := range finished {
+			s.notifyFinish(result)
+		}
+	}
+	return results
 }
 
 // Drop registers the connections response to a query for [id]. If there was no
 // query, or the response has already be registered, nothing is performed.
 func (s *set) Drop(requestID uint32, vdr ids.ShortID) (ids.Bag, bool) {
+	return s.respond(requestID, vdr, func(poll *poll) {
+		s.log.Verbo("processing dropped vote from %s in the poll with requestID: %d",
+			vdr,
+			requestID)
+
+		s.drop(poll, vdr)
+	})
+}
+
+// drop [vdr] from [poll]
+// Assumes the lock is held exclusively, or the lock is held and the lock
+// stripe of [poll] is held
+func (s *set) drop(poll *poll, vdr ids.ShortID) {
+	if poll.pending(vdr) {
+		poll.dropped.Add(vdr)
+		s.numDrops.Inc()
+		if s.dropRate != nil {
+			s.dropRate.Drop(s.clock.Time())
+		}
+	}
+	poll.Drop(vdr)
+	if poll.shadow != nil {
+		poll.shadow.Drop(vdr)
+	}
+}
+
+// dropPending drops every validator from [poll] that hasn't responded
+// Assumes the lock is held exclusively
+func (s *set) dropPending(poll *poll) {
+	for _, vdr := range poll.vdrs.List() {
+		if poll.pending(vdr) {
+			s.drop(poll, vdr)
+		}
+	}
+}
+
+// DropWithRetry registers that [vdr] failed to respond to the query. Unless
+// [vdr] has already failed to respond more than [maxRetries] times, the
+// failure isn't registered with the poll and true is returned to signal that
+// the query should be re-sent to [vdr].
+func (s *set) DropWithRetry(requestID uint32, vdr ids.ShortID, maxRetries int) (ids.Bag, bool, bool) {
+	s.lock.RLock()
+	poll, exists := s.polls[requestID]
+	retry := false
+	if exists {
+		stripe := s.stripe(requestID)
+		stripe.Lock()
+		if !poll.finished && poll.pending(vdr) && poll.retries[vdr] < maxRetries {
+			if poll.retries == nil {
+				poll.retries = make(map[ids.ShortID]int)
+			}
+			poll.retries[vdr]++
+			retry = true
+		}
+		stripe.Unlock()
+	}
+	s.lock.RUnlock()
+
+	if !exists {
+		s.log.Verbo("dropping vote from %s to an unknown poll with requestID: %d",
+			vdr,
+			requestID)
+		return ids.Bag{}, false, false
+	}
+	if retry {
+		s.log.Verbo("retrying dropped vote from %s in the poll with requestID: %d",
+			vdr,
+			requestID)
+		return ids.Bag{}, false, true
+	}
+
+	result, finished := s.Drop(requestID, vdr)
+	return result, finished, false
+}
+
+// stripe returns the lock that guards the poll with [requestID]
+func (s *set) stripe(requestID uint32) *sync.Mutex {
+	return &s.stripes[requestID%numLockStripes]
+}
+
+// respond applies the response of [vdr] to the poll with [requestID] using
+// [apply]. If the poll finishes due to the response, the poll is removed and
+// its result is returned.
+//
+// Responses to different polls are applied concurrently. The set's lock is
+// only held exclusively when a poll must be removed.
+func (s *set) respond(requestID uint32, vdr ids.ShortID, apply func(*poll)) (ids.Bag, bool) {
+	s.lock.RLock()
 	poll, exists := s.polls[requestID]
 	if !exists {
+		s.lock.RUnlock()
+
 		s.log.Verbo("dropping vote from %s to an unknown poll with requestID: %d",
 			vdr,
 			requestID)
 		return ids.Bag{}, false
 	}
 
-	s.log.Verbo("processing dropped vote from %s in the poll with requestID: %d",
-		vdr,
-		requestID)
+	stripe := s.stripe(requestID)
+	stripe.Lock()
+	alreadyFinished := poll.finished
+	if !alreadyFinished {
+		s.observeFirstResponse(poll, vdr)
+		apply(poll)
+		poll.finished = poll.Finished()
+	}
+	finished := !alreadyFinished && poll.finished
+	stripe.Unlock()
+	s.lock.RUnlock()
 
-	poll.Drop(vdr)
-	if !poll.Finished() {
+	if !finished {
+		return ids.Bag{}, false
+	}
+
+	s.lock.Lock()
+	// The poll may have been cleared while the lock wasn't held
+	if current, exists := s.polls[requestID]; !exists || current != poll {
+		s.lock.Unlock()
+		return ids.Bag{}, false
+	}
+	result := s.finish(requestID, poll, poll.Result())
+	s.compareShadow(requestID, poll, result.Result)
+	notify := s.config.OnFinish != nil && !s.shutdown
+	if notify {
+		s.callbacks.Add(1)
+	}
+	s.lock.Unlock()
+
+	if notify {
+		s.notifyFinish(result)
+	}
+	return result.Result, true
+}
+
+// notifyFinish calls OnFinish with [result]. If OrderedFinish is set, results
+// that finish while OnFinish is being called are buffered and then reported
+// in order of their requestIDs.
+func (s *set) notifyFinish(result PollResult) {
+	if !s.config.OrderedFinish {
+		defer s.callbacks.Done()
+		s.config.OnFinish(result)
+		return
+	}
+
+	s.finishedLock.Lock()
+	s.finished = append(s.finished, result)
+	s.finishedLock.Unlock()
+
+	// Whichever response holds the notify lock reports every buffered result,
+	// so [result] may be reported by a different response
+	s.notifyLock.Lock()
+	defer s.notifyLock.Unlock()
+
+	for {
+		s.finishedLock.Lock()
+		batch := s.finished
+		s.finished = nil
+		s.finishedLock.Unlock()
+
+		if len(batch) == 0 {
+			return
+		}
+
+		sort.Slice(batch, func(i, j int) bool { return batch[i].RequestID < batch[j].RequestID })
+		for _, result := range batch {
+			s.config.OnFinish(result)
+			s.callbacks.Done()
+		}
+	}
+}
+
+// ForceFinalize finishes the poll with [requestID] with [result], regardless of
+// the responses it has received. Returns false if ForceFinalize isn't enabled
+// or there is no such outstanding poll.
+func (s *set) ForceFinalize(requestID uint32, result ids.Bag) bool {
+	if !s.config.AllowForceFinalize {
+		s.log.Warn("not force finalizing poll with requestID %d due to force finalization being disabled", requestID)
+		return false
+	}
+
+	s.lock.Lock()
+	poll, exists := s.polls[requestID]
+	if !exists || poll.finished {
+		s.lock.Unlock()
+		s.log.Debug("not force finalizing unknown poll with requestID %d", requestID)
+		return false
+	}
+	s.log.Info("force finalizing poll with requestID %d as %s", requestID, &result)
+	poll.finished = true
+	pollResult := s.finish(requestID, poll, result)
+	notify := s.config.OnFinish != nil && !s.shutdown
+	if notify {
+		s.callbacks.Add(1)
+	}
+	s.lock.Unlock()
+
+	if notify {
+		s.notifyFinish(pollResult)
+	}
+	return true
+}
+
+// Timeout finishes the outstanding poll with [requestID] with the votes it has
+// received so far, treating every validator that hasn't responded as dropped.
+// Returns the result of the poll and true if the poll was outstanding.
+func (s *set) Timeout(requestID uint32) (ids.Bag, bool) {
+	s.lock.Lock()
+	poll, exists := s.polls[requestID]
+	if !exists || poll.finished {
+		s.lock.Unlock()
+		s.log.Verbo("dropping timeout of an unknown poll with requestID: %d", requestID)
+		return ids.Bag{}, false
+	}
+
+	s.log.Debug("timing out poll with requestID %d after %s", requestID, s.clock.Time().Sub(poll.start))
+	s.dropPending(poll)
+	poll.finished = true
+	result := s.finish(requestID, poll, poll.Result())
+	s.compareShadow(requestID, poll, result.Result)
+	s.numTimedOutPolls.Inc()
+
+	notify := s.config.OnFinish != nil && !s.shutdown
+	if notify {
+		s.callbacks.Add(1)
+	}
+	s.lock.Unlock()
+
+	if notify {
+		s.notifyFinish(result)
+	}
+	return result.Result, true
+}
+
+// FinalizeAndReissue finishes the poll with [oldRequestID], dropping every
+// validator that hasn't responded, and adds a new poll of the same validators
+// with [newRequestID]. If the old poll had mandatory validators, so does the
+// new poll. Returns the result of the old poll and true if the new poll was
+// added. If the new poll can't be added, the old poll is left outstanding.
+func (s *set) FinalizeAndReissue(oldRequestID, newRequestID uint32) (ids.Bag, bool) {
+	s.lock.Lock()
+	poll, exists := s.polls[oldRequestID]
+	if !exists || poll.finished {
+		s.lock.Unlock()
+		s.log.Debug("not reissuing unknown poll with requestID %d", oldRequestID)
+		return ids.Bag{}, false
+	}
+
+	vdrs := copyBag(poll.vdrs)
+	var created ids.ShortBag
+	if s.config.OnCreate != nil {
+		created = copyBag(vdrs) // the poll may modify the provided bag
+	}
+
+	// The new poll is added before the old poll is removed, so that the old
+	// poll remains outstanding if the new poll can't be added. The old poll
+	// is about to be removed, so it doesn't count against MaxOutstanding.
+	maxOutstanding := s.config.MaxOutstanding
+	if maxOutstanding > 0 {
+		maxOutstanding++
+	}
+	if !s.addWithLimit(newRequestID, vdrs, maxOutstanding) {
+		s.lock.Unlock()
+		s.log.Debug("not reissuing poll with requestID %d as requestID %d", oldRequestID, newRequestID)
 		return ids.Bag{}, false
 	}
+	reissued := s.polls[newRequestID]
+	if poll.hasContainer {
+		reissued.containerID = poll.containerID
+		reissued.hasContainer = true
+	}
+	if poll.mandatory.Len() > 0 {
+		reissued.Poll = newMandatoryPoll(reissued.Poll, poll.mandatory)
+		reissued.mandatory = poll.mandatory
+	}
+
+	s.dropPending(poll)
+	poll.finished = true
+	result := s.finish(oldRequestID, poll, poll.Result())
+	s.compareShadow(oldRequestID, poll, result.Result)
+
+	notifyFinish := s.config.OnFinish != nil
+	notifyCreate := s.config.OnCreate != nil
+	if notifyFinish {
+		s.callbacks.Add(1)
+	}
+	if notifyCreate {
+		s.callbacks.Add(1)
+	}
+	s.lock.Unlock()
 
+	if notifyFinish {
+		s.notifyFinish(result)
+	}
+	if notifyCreate {
+		defer s.callbacks.Done()
+		s.config.OnCreate(newRequestID, created)
+	}
+	return result.Result, true
+}
+
+// finish removes the finished poll and reports [result] as its result
+// Assumes the lock is held
+func (s *set) finish(requestID uint32, poll *poll, result ids.Bag) PollResult {
 	s.log.Verbo("poll with requestID %d finished as %s", requestID, poll)
 
 	delete(s.polls, requestID) // remove the poll from the current set
-	s.durPolls.Observe(float64(time.Since(poll.start).Milliseconds()))
+	s.dequeue(poll)
+	duration := s.clock.Time().Sub(poll.start)
+	s.observeDuration(duration)
+	s.votesPolls.Observe(float64(poll.responded.Len()))
+	if result.Len() > 0 {
+		s.numSuccessfulPolls.Inc()
+	} else {
+		s.numFailedPolls.Inc()
+	}
 	s.numPolls.Dec() // decrease the metrics
-	return poll.Result(), true
+	s.maybeFlushMetrics()
+	s.trackValidatorSeconds(-poll.size())
+
+	if threshold := s.config.SlowPollThreshold; threshold > 0 && duration >= threshold {
+		lastResponders := poll.responders
+		if len(lastResponders) > numSlowPollResponders {
+			lastResponders = lastResponders[len(lastResponders)-numSlowPollResponders:]
+		}
+		s.log.Warn("poll with requestID %d took %s to finish. Last responders: %v",
+			requestID,
+			duration,
+			lastResponders)
+	}
+
+	pollResult := PollResult{
+		RequestID: requestID,
+		Start:     poll.start,
+		Duration:  duration,
+		Result:    result,
+	}
+	s.recentResults.Add(pollResult)
+	if s.config.RetainAfterFinish > 0 {
+		s.sweepCompleted()
+		completed := completedPoll{
+			requestID: requestID,
+			result:    result,
+			expiry:    s.clock.Time().Add(s.config.RetainAfterFinish),
+		}
+		s.completed[requestID] = completed
+		s.completedOrder = append(s.completedOrder, completed)
+	}
+	if s.containerResults != nil && poll.hasContainer {
+		s.containerResults.Put(poll.containerID, result)
+	}
+	s.trackMissStreaks(result)
+	return pollResult
+}
+
+// compareShadow reports if the shadow of [poll], if any, didn't finish with
+// [result]
+// Assumes the lock is held
+func (s *set) compareShadow(requestID uint32, poll *poll, result ids.Bag) {
+	if poll.shadow == nil {
+		return
+	}
+	shadowFinished := poll.shadow.Finished()
+	shadowResult := poll.shadow.Result()
+	if !shadowFinished || !shadowResult.Equals(result) {
+		s.log.Warn("shadow poll with requestID %d diverged. Finished: %v. Result: %s. Expected: %s",
+			requestID,
+			shadowFinished,
+			&shadowResult,
+			&result)
+		s.numShadowDivergences.Inc()
+	}
+}
+
+// trackMissStreaks updates the number of consecutive polls each ID has
+// received votes in without reaching alpha votes
+// Assumes the lock is held
+func (s *set) trackMissStreaks(result ids.Bag) {
+	alpha := s.config.Alpha
+	if alpha <= 0 {
+		return
+	}
+
+	missStreaks := make(map[ids.ID]int)
+	for _, vote := range result.List() {
+		if result.Count(vote) < alpha {
+			missStreaks[vote] = s.missStreaks[vote] + 1
+		}
+	}
+	s.missStreaks = missStreaks
+}
+
+// PollID returns the deterministic ID of the poll with [requestID], if it is
+// outstanding
+func (s *set) PollID(requestID uint32) (ids.ID, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	poll, exists := s.polls[requestID]
+	if !exists {
+		return ids.ID{}, false
+	}
+	return poll.id, true
+}
+
+// Age returns the length of time the outstanding poll with [requestID] has
+// existed
+func (s *set) Age(requestID uint32) (time.Duration, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	poll, exists := s.polls[requestID]
+	if !exists {
+		return 0, false
+	}
+	return s.clock.Time().Sub(poll.start), true
+}
+
+// GetPendingVoters returns the validators that were polled by the
+// outstanding poll with [requestID] that haven't responded or been dropped
+func (s *set) GetPendingVoters(requestID uint32) (ids.ShortSet, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	poll, exists := s.polls[requestID]
+	if !exists {
+		return nil, false
+	}
+
+	stripe := s.stripe(requestID)
+	stripe.Lock()
+	defer stripe.Unlock()
+
+	pending := ids.ShortSet{}
+	for _, vdr := range poll.vdrs.List() {
+		if poll.pending(vdr) {
+			pending.Add(vdr)
+		}
+	}
+	return pending, true
+}
+
+// WeightToWin returns the additional weight that must vote for [id] in the
+// poll with [requestID] for [id] to receive AlphaWeight. If the validators
+// that haven't responded don't have enough weight, ImpossibleWeight is
+// returned. Returns false if there is no such poll, or the set doesn't have
+// Validators and AlphaWeight configured.
+func (s *set) WeightToWin(requestID uint32, id ids.ID) (uint64, bool) {
+	if s.config.Validators == nil || s.config.AlphaWeight == 0 {
+		return 0, false
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	poll, exists := s.polls[requestID]
+	if !exists {
+		return 0, false
+	}
+
+	stripe := s.stripe(requestID)
+	stripe.Lock()
+	defer stripe.Unlock()
+
+	votedWeight, remainingWeight := uint64(0), uint64(0)
+	for _, vdr := range poll.vdrs.List() {
+		weight, _ := s.config.Validators.GetWeight(vdr)
+		switch {
+		case poll.pending(vdr):
+			remainingWeight += weight
+		case poll.responded.Contains(vdr) && poll.votes[vdr] == id:
+			votedWeight += weight
+		}
+	}
+
+	if votedWeight >= s.config.AlphaWeight {
+		return 0, true
+	}
+	needed := s.config.AlphaWeight - votedWeight
+	if needed > remainingWeight {
+		return ImpossibleWeight, true
+	}
+	return needed, true
+}
+
+// SizeDistribution returns the number of outstanding polls of each size
+func (s *set) SizeDistribution() map[int]int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	distribution := make(map[int]int)
+	for _, poll := range s.polls {
+		distribution[poll.size()]++
+	}
+	return distribution
+}
+
+// ChurningBlocks returns the IDs that have received votes, without reaching
+// alpha votes, in at least [threshold] consecutive polls
+func (s *set) ChurningBlocks(threshold int) []ids.ID {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	churning := []ids.ID(nil)
+	for blkID, streak := range s.missStreaks {
+		if streak >= threshold {
+			churning = append(churning, blkID)
+		}
+	}
+	ids.SortIDs(churning)
+	return churning
+}
+
+// copyBag returns a bag with the same counts as [vdrs] that doesn't share any
+// state with [vdrs]
+func copyBag(vdrs ids.ShortBag) ids.ShortBag {
+	cpy := ids.ShortBag{}
+	for _, vdr := range vdrs.List() {
+		cpy.AddCount(vdr, vdrs.Count(vdr))
+	}
+	return cpy
 }
 
 // Len returns the number of outstanding polls
-func (s *set) Len() int { return len(s.polls) }
+func (s *set) Len() int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return len(s.polls)
+}
+
+// TransferTo moves all outstanding polls to [dst]. The polls continue to be
+// driven by the factory that created them. The transfer fails if [dst] has
+// been shut down, or if it would exceed [dst]'s MaxOutstanding.
+//
+// Both sets are locked during the transfer. They are always locked in the
+// order they were created, so concurrent transfers between the same sets in
+// opposite directions can't deadlock.
+func (s *set) TransferTo(dst Set) error {
+	d, ok := dst.(*set)
+	if !ok {
+		return errUnsupportedSet
+	}
+	if d == s {
+		return errTransferToSelf
+	}
+
+	first, second := s, d
+	if d.id < s.id {
+		first, second = d, s
+	}
+	first.lock.Lock()
+	defer first.lock.Unlock()
+	second.lock.Lock()
+	defer second.lock.Unlock()
+
+	if d.shutdown {
+		return errTransferToShutdown
+	}
+
+	numTransferred := 0
+	for requestID, poll := range s.polls {
+		if _, exists := d.polls[requestID]; exists {
+			return fmt.Errorf("%w: %d", errDuplicateRequestID, requestID)
+		}
+		if !poll.finished {
+			numTransferred++
+		}
+	}
+	if maxOutstanding := d.config.MaxOutstanding; maxOutstanding > 0 && len(d.polls)+numTransferred > maxOutstanding {
+		return fmt.Errorf("%w: %d polls would be outstanding, the maximum is %d",
+			errTransferOverLimit,
+			len(d.polls)+numTransferred,
+			maxOutstanding)
+	}
+
+	remaining := make(map[uint32]*poll)
+	for requestID, poll := range s.polls {
+		// Finished polls must remain in this set to be removed
+		if poll.finished {
+			remaining[requestID] = poll
+			continue
+		}
+		s.dequeue(poll)
+		d.polls[requestID] = poll
+		d.enqueue(poll)
+		s.trackValidatorSeconds(-poll.size())
+		d.trackValidatorSeconds(poll.size())
+	}
+	numPolls := float64(len(s.polls) - len(remaining))
+	s.numPolls.Sub(numPolls)
+	d.numPolls.Add(numPolls)
+
+	s.log.Debug("transferred %d polls", len(s.polls)-len(remaining))
+	s.polls = remaining
+	return nil
+}
+
+// Cancel removes the outstanding poll with [requestID] without finishing it.
+// Returns true if the poll was outstanding.
+func (s *set) Cancel(requestID uint32) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	poll, exists := s.polls[requestID]
+	if !exists || poll.finished {
+		s.log.Verbo("dropping cancellation of an unknown poll with requestID: %d", requestID)
+		return false
+	}
+
+	s.log.Debug("cancelling poll with requestID %d", requestID)
+	s.cancel(requestID, poll)
+	return true
+}
+
+// CancelForContainer removes every outstanding poll of [containerID] and
+// returns their requestIDs in increasing order
+func (s *set) CancelForContainer(containerID ids.ID) []uint32 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	cancelled := []uint32(nil)
+	for requestID, poll := range s.polls {
+		if poll.finished || !poll.hasContainer || poll.containerID != containerID {
+			continue
+		}
+		s.cancel(requestID, poll)
+		cancelled = append(cancelled, requestID)
+	}
+	sort.Slice(cancelled, func(i, j int) bool { return cancelled[i] < cancelled[j] })
+
+	s.log.Debug("cancelled %d polls of %s", len(cancelled), containerID)
+	return cancelled
+}
+
+// PruneDisconnected removes every outstanding poll that doesn't poll any
+// validator in [connected] and returns their requestIDs in increasing order.
+// Validators not in [connected] are dropped from the remaining polls, and the
+// results of the polls that finish as a result are returned in order of their
+// requestIDs. The latencies of validators not in [connected] are forgotten.
+func (s *set) PruneDisconnected(connected ids.ShortSet) ([]uint32, []PollResult) {
+	s.lock.Lock()
+	cancelled := []uint32(nil)
+	finished := []PollResult(nil)
+	for requestID, poll := range s.polls {
+		if poll.finished {
+			continue
+		}
+
+		disconnected := []ids.ShortID(nil)
+		anyConnected := false
+		for _, vdr := range poll.vdrs.List() {
+			if connected.Contains(vdr) {
+				anyConnected = true
+			} else if poll.pending(vdr) {
+				disconnected = append(disconnected, vdr)
+			}
+		}
+		if !anyConnected {
+			s.cancel(requestID, poll)
+			cancelled = append(cancelled, requestID)
+			continue
+		}
+		if len(disconnected) == 0 {
+			continue
+		}
+
+		for _, vdr := range disconnected {
+			s.drop(poll, vdr)
+		}
+		if poll.finished = poll.Finished(); poll.finished {
+			result := s.finish(requestID, poll, poll.Result())
+			s.compareShadow(requestID, poll, result.Result)
+			finished = append(finished, result)
+		}
+	}
+	sort.Slice(cancelled, func(i, j int) bool { return cancelled[i] < cancelled[j] })
+	sort.Slice(finished, func(i, j int) bool { return finished[i].RequestID < finished[j].RequestID })
+	s.pruneLatencies(connected)
+
+	notify := s.config.OnFinish != nil && !s.shutdown
+	if notify {
+		s.callbacks.Add(len(finished))
+	}
+	s.lock.Unlock()
+
+	s.log.Debug("pruned %d disconnected polls", len(cancelled))
+	if notify {
+		for _, result := range finished {
+			s.notifyFinish(result)
+		}
+	}
+	return cancelled, finished
+}
+
+// DropAll registers that [vdr] failed to respond to every outstanding poll
+// that is waiting on it
+func (s *set) DropAll(vdr ids.ShortID) []PollResult {
+	s.lock.Lock()
+	finished := []PollResult(nil)
+	for requestID, poll := range s.polls {
+		if poll.finished || !poll.pending(vdr) {
+			continue
+		}
+
+		s.observeFirstResponse(poll, vdr)
+		s.drop(poll, vdr)
+		if poll.finished = poll.Finished(); poll.finished {
+			result := s.finish(requestID, poll, poll.Result())
+			s.compareShadow(requestID, poll, result.Result)
+			finished = append(finished, result)
+		}
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].RequestID < finished[j].RequestID })
+
+	notify := s.config.OnFinish != nil && !s.shutdown
+	if notify {
+		s.callbacks.Add(len(finished))
+	}
+	s.lock.Unlock()
+
+	s.log.Verbo("dropped %s from every outstanding poll, finishing %d polls", vdr, len(finished))
+	if notify {
+		for _, result := range finished {
+			s.notifyFinish(result)
+		}
+	}
+	return finished
+}
+
+// cancel removes [poll] without finishing it
+// Assumes the lock is held
+func (s *set) cancel(requestID uint32, poll *poll) {
+	delete(s.polls, requestID)
+	s.dequeue(poll)
+	s.numPolls.Dec()
+	s.numCancelledPolls.Inc()
+	s.numCancelledOutcomes.Inc()
+	s.trackValidatorSeconds(-poll.size())
+}
+
+// Shutdown unregisters the set's metrics and removes it from its aggregator.
+// Shutdown must not be called from a callback of the set.
+//
+// Shutdown is performed in the following order:
+// 1) No new polls are accepted, so no new callbacks will be started.
+// 2) Callbacks that are currently executing are waited on, and the periodic
+//    metric flushes and expiry of stale polls are stopped.
+// 3) All outstanding polls are cleared without being finished, and any
+//    batched metric updates are flushed.
+// 4) The set is removed from its aggregator and its metrics are unregistered.
+//
+// This ensures that no callback observes the set after its metrics have been
+// unregistered.
+func (s *set) Shutdown() error {
+	s.lock.Lock()
+	s.shutdown = true
+	s.lock.Unlock()
+
+	s.callbacks.Wait()
+
+	// The flusher and the expirer acquire the lock, so they must be stopped
+	// without holding the lock
+	if s.flusher != nil {
+		s.flusher.Stop()
+	}
+	if s.expirer != nil {
+		s.expirer.Stop()
+	}
+
+	s.lock.Lock()
+	s.log.Debug("clearing %d polls due to shutdown", len(s.polls))
+	s.trackValidatorSeconds(-s.numPolledValidators)
+	s.polls = make(map[uint32]*poll)
+	s.queue = nil
+	s.numPolls.Set(0)
+	if s.batchedPolls != nil {
+		s.flushMetrics(s.clock.Time())
+	}
+	s.lock.Unlock()
+
+	// The aggregator calls into the set, so it must be notified without
+	// holding the set's lock
+	if s.config.Aggregator != nil {
+		s.config.Aggregator.Deregister(s)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// The shared histogram is owned by the caller, so only this set's
+	// observations are removed from it
+	if s.config.SharedDurations != nil {
+		s.config.SharedDurations.DeleteLabelValues(s.config.Chain)
+	}
+	if s.config.SharedOutcomes != nil {
+		for _, outcome := range []string{successfulOutcome, failedOutcome, cancelledOutcome} {
+			s.config.SharedOutcomes.DeleteLabelValues(s.config.Chain, outcome)
+		}
+	}
+	if s.config.SharedResponses != nil {
+		for _, outcome := range []string{votedOutcome, droppedOutcome} {
+			s.config.SharedResponses.DeleteLabelValues(s.config.Chain, outcome)
+		}
+	}
+
+	// Every metric is unregistered, even if some fail, so a failure doesn't
+	// leak the remaining metrics
+	failed := []string(nil)
+	for _, metric := range s.metrics {
+		if !s.config.Registerer.Unregister(metric) {
+			failed = append(failed, metricName(metric))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%w: %s", errFailedUnregister, strings.Join(failed, ", "))
+	}
+	return nil
+}
 
 func (s *set) String() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
 	sb := strings.Builder{}
 	sb.WriteString(fmt.Sprintf("current polls: (Size = %d)", len(s.polls)))
-	for requestID, poll := range s.polls {
+
+	// Report the polls in order of their requestIDs so the output is stable
+	requestIDs := make([]uint32, 0, len(s.polls))
+	for requestID := range s.polls {
+		requestIDs = append(requestIDs, requestID)
+	}
+	sort.Slice(requestIDs, func(i, j int) bool { return requestIDs[i] < requestIDs[j] })
+
+	for _, requestID := range requestIDs {
+		poll := s.polls[requestID]
 		sb.WriteString(fmt.Sprintf("\n    %d: %s", requestID, poll.PrefixedString("    ")))
 	}
 	return sb.String()
@@ -0,0 +1,184 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package poll
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/logging"
+)
+
+// maxBenchmarkPolls is the number of outstanding polls after which a
+// benchmark replaces its set, to bound its memory usage
+const maxBenchmarkPolls = 1024
+
+// benchmarkSizes are the number of validators polled by each benchmark
+var benchmarkSizes = []int{21, 1000}
+
+// newBenchmarkSet returns a set whose polls finish once 3/4 of [numVdrs]
+// validators have voted for the same ID
+func newBenchmarkSet(numVdrs int) Set {
+	return NewSet(
+		NewEarlyTermNoTraversalFactory(numVdrs*3/4),
+		logging.NoLog{},
+		"",
+		prometheus.NewRegistry(),
+	)
+}
+
+func newBenchmarkVdrs(numVdrs int) []ids.ShortID {
+	vdrs := make([]ids.ShortID, numVdrs)
+	for i := range vdrs {
+		vdrs[i] = ids.ShortID{byte(i), byte(i >> 8)}
+	}
+	return vdrs
+}
+
+func BenchmarkSetAdd(b *testing.B) {
+	for _, numVdrs := range benchmarkSizes {
+		b.Run(fmt.Sprintf("%d validators", numVdrs), func(b *testing.B) {
+			vdrList := newBenchmarkVdrs(numVdrs)
+			s := newBenchmarkSet(numVdrs)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				if s.Len() >= maxBenchmarkPolls {
+					s = newBenchmarkSet(numVdrs)
+				}
+				vdrs := ids.ShortBag{}
+				vdrs.Add(vdrList...)
+				b.StartTimer()
+
+				s.Add(uint32(i), vdrs)
+			}
+		})
+	}
+}
+
+func BenchmarkSetVote(b *testing.B) {
+	for _, numVdrs := range benchmarkSizes {
+		b.Run(fmt.Sprintf("%d validators", numVdrs), func(b *testing.B) {
+			vdrList := newBenchmarkVdrs(numVdrs)
+			s := newBenchmarkSet(numVdrs)
+			vtxID := ids.ID{1}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				vdrs := ids.ShortBag{}
+				vdrs.Add(vdrList...)
+				s.Add(uint32(i), vdrs)
+				b.StartTimer()
+
+				// Voting finishes the poll once alpha votes are received
+				for _, vdr := range vdrList {
+					s.Vote(uint32(i), vdr, vtxID)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSetDrop(b *testing.B) {
+	for _, numVdrs := range benchmarkSizes {
+		b.Run(fmt.Sprintf("%d validators", numVdrs), func(b *testing.B) {
+			vdrList := newBenchmarkVdrs(numVdrs)
+			s := newBenchmarkSet(numVdrs)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				vdrs := ids.ShortBag{}
+				vdrs.Add(vdrList...)
+				s.Add(uint32(i), vdrs)
+				b.StartTimer()
+
+				// Dropping finishes the poll once alpha votes are impossible
+				for _, vdr := range vdrList {
+					s.Drop(uint32(i), vdr)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSetVoteBatchedMetrics(b *testing.B) {
+	for _, numVdrs := range benchmarkSizes {
+		b.Run(fmt.Sprintf("%d validators", numVdrs), func(b *testing.B) {
+			vdrList := newBenchmarkVdrs(numVdrs)
+			s := NewSetWithConfig(SetConfig{
+				Factory:         NewEarlyTermNoTraversalFactory(numVdrs * 3 / 4),
+				Log:             logging.NoLog{},
+				Registerer:      prometheus.NewRegistry(),
+				MetricBatchSize: 64,
+			})
+			vtxID := ids.ID{1}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				vdrs := ids.ShortBag{}
+				vdrs.Add(vdrList...)
+				s.Add(uint32(i), vdrs)
+				b.StartTimer()
+
+				for _, vdr := range vdrList {
+					s.Vote(uint32(i), vdr, vtxID)
+				}
+			}
+		})
+	}
+}
+
+// benchmarkSetVotesMemory measures the memory used by a set to track a poll
+// whose votes are split between two IDs, including the set's own tracking of
+// the responses, when the poll is created by [factory]
+func benchmarkSetVotesMemory(b *testing.B, factory func(alpha int) Factory) {
+	for _, numVdrs := range benchmarkSizes {
+		b.Run(fmt.Sprintf("%d validators", numVdrs), func(b *testing.B) {
+			vdrList := newBenchmarkVdrs(numVdrs)
+			vtxIDs := []ids.ID{{1}, {2}}
+			newSet := func() Set {
+				// Votes are split evenly, so no poll finishes early
+				return NewSet(factory(numVdrs), logging.NoLog{}, "", prometheus.NewRegistry())
+			}
+			s := newSet()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				if s.Len() >= maxBenchmarkPolls {
+					s = newSet()
+				}
+				vdrs := ids.ShortBag{}
+				vdrs.Add(vdrList...)
+				b.StartTimer()
+
+				requestID := uint32(i)
+				s.Add(requestID, vdrs)
+				for j, vdr := range vdrList[:len(vdrList)-1] {
+					s.Vote(requestID, vdr, vtxIDs[j%len(vtxIDs)])
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSetVotesMemoryEarlyTermNoTraversal(b *testing.B) {
+	benchmarkSetVotesMemory(b, NewEarlyTermNoTraversalFactory)
+}
+
+func BenchmarkSetVotesMemoryInternedEarlyTermNoTraversal(b *testing.B) {
+	benchmarkSetVotesMemory(b, NewInternedEarlyTermNoTraversalFactory)
+}
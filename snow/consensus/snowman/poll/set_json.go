@@ -0,0 +1,89 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package poll
+
+import (
+	"encoding/json"
+
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+type pollJSON struct {
+	RequestID  uint32   `json:"requestID"`
+	AgeMs      int64    `json:"ageMs"`
+	Validators []string `json:"validators"`
+	Responded  []string `json:"responded"`
+	Dropped    []string `json:"dropped"`
+
+	// Weights, TotalWeight, and RespondedWeight are only reported if
+	// IncludeWeights is set
+	Weights         []weightJSON `json:"weights,omitempty"`
+	TotalWeight     uint64       `json:"totalWeight,omitempty"`
+	RespondedWeight uint64       `json:"respondedWeight,omitempty"`
+}
+
+type weightJSON struct {
+	Validator string `json:"validator"`
+	Weight    uint64 `json:"weight"`
+	Responded bool   `json:"responded"`
+}
+
+type setJSON struct {
+	Pending int        `json:"pending"`
+	Polls   []pollJSON `json:"polls"`
+}
+
+// MarshalJSON returns a summary of the outstanding polls
+func (s *set) MarshalJSON() ([]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.clock.Time()
+	summary := setJSON{
+		Pending: len(s.polls),
+		Polls:   make([]pollJSON, 0, len(s.polls)),
+	}
+	for requestID, poll := range s.polls {
+		p := pollJSON{
+			RequestID:  requestID,
+			AgeMs:      now.Sub(poll.start).Milliseconds(),
+			Validators: s.idStrings(poll.vdrs.List()),
+			Responded:  s.idStrings(poll.responded.List()),
+			Dropped:    s.idStrings(poll.dropped.List()),
+		}
+		if s.config.IncludeWeights && s.config.Validators != nil {
+			vdrs := poll.vdrs.List()
+			vdrStrs := s.idStrings(vdrs)
+			p.Weights = make([]weightJSON, len(vdrs))
+			for i, vdr := range vdrs {
+				weight, _ := s.config.Validators.GetWeight(vdr)
+				responded := poll.responded.Contains(vdr)
+				p.Weights[i] = weightJSON{
+					Validator: vdrStrs[i],
+					Weight:    weight,
+					Responded: responded,
+				}
+				p.TotalWeight += weight
+				if responded {
+					p.RespondedWeight += weight
+				}
+			}
+		}
+		summary.Polls = append(summary.Polls, p)
+	}
+	return json.Marshal(summary)
+}
+
+// idStrings formats [vdrs] for reporting, respecting the redaction config
+func (s *set) idStrings(vdrs []ids.ShortID) []string {
+	strs := make([]string, len(vdrs))
+	for i, vdr := range vdrs {
+		str := vdr.String()
+		if s.config.RedactIDs && len(str) > redactedIDLen {
+			str = str[:redactedIDLen]
+		}
+		strs[i] = str
+	}
+	return strs
+}
@@ -4,11 +4,20 @@
 package poll
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow/consensus/snowman/poll/polltest"
+	"github.com/ava-labs/avalanchego/snow/validators"
 	"github.com/ava-labs/avalanchego/utils/logging"
 	"github.com/ava-labs/avalanchego/utils/wrappers"
 )
@@ -38,6 +47,45 @@ func TestNewSetErrorOnMetrics(t *testing.T) {
 	}
 }
 
+func TestNewSetRegistersEnabledMetrics(t *testing.T) {
+	gatherNames := func(registerer *prometheus.Registry) []string {
+		metrics, err := registerer.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := make([]string, len(metrics))
+		for i, metric := range metrics {
+			names[i] = metric.GetName()
+		}
+		return names
+	}
+
+	registerer := prometheus.NewRegistry()
+	NewSet(NewNoEarlyTermFactory(), logging.NoLog{}, "", registerer)
+
+	expected := "[cancelled_polls empty_polls poll_duration poll_expired polls]"
+	if names := fmt.Sprint(gatherNames(registerer)); names != expected {
+		t.Fatalf("Should have only registered %s by default, registered %s", expected, names)
+	}
+
+	registerer = prometheus.NewRegistry()
+	NewSetWithConfig(SetConfig{
+		Factory:          NewNoEarlyTermFactory(),
+		Log:              logging.NoLog{},
+		Registerer:       registerer,
+		SlowDurations:    true,
+		ResponseMetrics:  true,
+		Outcomes:         true,
+		OldestPollAge:    true,
+		ValidatorSeconds: true,
+	})
+
+	expected = "[cancelled_polls empty_polls oldest_poll_age_ms poll_duration poll_expired poll_first_response poll_outcomes poll_responses poll_validator_seconds poll_votes polls slow_poll_duration]"
+	if names := fmt.Sprint(gatherNames(registerer)); names != expected {
+		t.Fatalf("Should have registered %s, registered %s", expected, names)
+	}
+}
+
 func TestCreateAndFinishSuccessfulPoll(t *testing.T) {
 	factory := NewNoEarlyTermFactory()
 	log := logging.NoLog{}
@@ -145,3 +193,3525 @@ func TestSetString(t *testing.T) {
 			str)
 	}
 }
+
+func TestSetStringSorted(t *testing.T) {
+	factory := NewNoEarlyTermFactory()
+	log := logging.NoLog{}
+	namespace := ""
+	registerer := prometheus.NewRegistry()
+	s := NewSet(factory, log, namespace, registerer)
+
+	vdr1 := ids.ShortID{1} // k = 1
+
+	newVdrs := func() ids.ShortBag {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(vdr1)
+		return vdrs
+	}
+
+	for _, requestID := range []uint32{5, 1, 3, 0, 4, 2} {
+		if !s.Add(requestID, newVdrs()) {
+			t.Fatalf("Should have been able to add a new poll")
+		}
+	}
+
+	pollStr := ": waiting on Bag: (Size = 1)\n" +
+		"        ID[6HgC8KRBEhXYbF4riJyJFLSHt37UNuRt]: Count = 1"
+	expected := "current polls: (Size = 6)"
+	for requestID := 0; requestID < 6; requestID++ {
+		expected += fmt.Sprintf("\n    %d%s", requestID, pollStr)
+	}
+	for i := 0; i < 10; i++ {
+		if str := s.String(); expected != str {
+			t.Fatalf("Set return wrong string, Expected:\n%s\nReturned:\n%s",
+				expected,
+				str)
+		}
+	}
+}
+
+func TestSetMarshalJSON(t *testing.T) {
+	factory := NewNoEarlyTermFactory()
+	log := logging.NoLog{}
+	namespace := ""
+	registerer := prometheus.NewRegistry()
+	s := NewSet(factory, log, namespace, registerer)
+
+	now := time.Now()
+	s.(*set).clock.Set(now)
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+	vdr3 := ids.ShortID{3} // k = 3
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(
+		vdr1,
+		vdr2,
+		vdr3,
+	)
+
+	if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := s.Vote(0, vdr1, vtxID); finished {
+		t.Fatalf("Shouldn't have been able to finish an ongoing poll")
+	} else if _, finished := s.Drop(0, vdr2); finished {
+		t.Fatalf("Shouldn't have been able to finish an ongoing poll")
+	}
+
+	s.(*set).clock.Set(now.Add(5 * time.Millisecond))
+
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary := setJSON{}
+	if err := json.Unmarshal(b, &summary); err != nil {
+		t.Fatal(err)
+	}
+
+	if summary.Pending != 1 {
+		t.Fatalf("Should have reported 1 pending poll, reported %d", summary.Pending)
+	} else if len(summary.Polls) != 1 {
+		t.Fatalf("Should have reported 1 poll, reported %d", len(summary.Polls))
+	}
+
+	p := summary.Polls[0]
+	if p.RequestID != 0 {
+		t.Fatalf("Wrong requestID reported: %d", p.RequestID)
+	} else if p.AgeMs != 5 {
+		t.Fatalf("Wrong age reported: %d", p.AgeMs)
+	} else if len(p.Validators) != 3 {
+		t.Fatalf("Wrong number of validators reported: %d", len(p.Validators))
+	} else if len(p.Responded) != 1 || p.Responded[0] != vdr1.String() {
+		t.Fatalf("Wrong responded validators reported: %v", p.Responded)
+	} else if len(p.Dropped) != 1 || p.Dropped[0] != vdr2.String() {
+		t.Fatalf("Wrong dropped validators reported: %v", p.Dropped)
+	}
+}
+
+func TestSetMarshalJSONWeights(t *testing.T) {
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+	vdr3 := ids.ShortID{3} // k = 3
+
+	vdrSet := validators.NewTestSet(map[ids.ShortID]uint64{
+		vdr1: 1,
+		vdr2: 10,
+		vdr3: 100,
+	})
+
+	s := NewSetWithConfig(SetConfig{
+		Factory:        NewNoEarlyTermFactory(),
+		Log:            logging.NoLog{},
+		Registerer:     prometheus.NewRegistry(),
+		Validators:     vdrSet,
+		IncludeWeights: true,
+	})
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1, vdr2, vdr3)
+
+	if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := s.Vote(0, vdr1, ids.ID{1}); finished {
+		t.Fatalf("Shouldn't have been able to finish an ongoing poll")
+	} else if _, finished := s.Vote(0, vdr3, ids.ID{1}); finished {
+		t.Fatalf("Shouldn't have been able to finish an ongoing poll")
+	}
+
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary := setJSON{}
+	if err := json.Unmarshal(b, &summary); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(summary.Polls) != 1 {
+		t.Fatalf("Should have reported 1 poll, reported %d", len(summary.Polls))
+	}
+
+	p := summary.Polls[0]
+	if len(p.Weights) != 3 {
+		t.Fatalf("Should have reported 3 weights, reported %d", len(p.Weights))
+	} else if p.TotalWeight != 111 {
+		t.Fatalf("Should have reported a total weight of 111, reported %d", p.TotalWeight)
+	} else if p.RespondedWeight != 101 {
+		t.Fatalf("Should have reported a responded weight of 101, reported %d", p.RespondedWeight)
+	}
+
+	expectedWeights := map[string]uint64{
+		vdr1.String(): 1,
+		vdr2.String(): 10,
+		vdr3.String(): 100,
+	}
+	totalWeight := uint64(0)
+	for _, weight := range p.Weights {
+		if expected := expectedWeights[weight.Validator]; weight.Weight != expected {
+			t.Fatalf("Expected %s to have weight %d, reported %d", weight.Validator, expected, weight.Weight)
+		} else if responded := weight.Validator != vdr2.String(); weight.Responded != responded {
+			t.Fatalf("Wrong response status reported for %s", weight.Validator)
+		}
+		totalWeight += weight.Weight
+	}
+	if totalWeight != p.TotalWeight {
+		t.Fatalf("Reported weights should sum to the total weight")
+	}
+}
+
+func TestSetMarshalJSONRedacted(t *testing.T) {
+	s := NewSetWithConfig(SetConfig{
+		Factory:    NewNoEarlyTermFactory(),
+		Log:        logging.NoLog{},
+		Registerer: prometheus.NewRegistry(),
+		RedactIDs:  true,
+	})
+
+	vdr1 := ids.ShortID{1} // k = 1
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1)
+
+	if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	}
+
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary := setJSON{}
+	if err := json.Unmarshal(b, &summary); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(summary.Polls) != 1 {
+		t.Fatalf("Should have reported 1 poll, reported %d", len(summary.Polls))
+	} else if vdrs := summary.Polls[0].Validators; len(vdrs) != 1 {
+		t.Fatalf("Wrong number of validators reported: %d", len(vdrs))
+	} else if vdrs[0] == vdr1.String() {
+		t.Fatalf("Validator ID should have been redacted")
+	} else if vdrs[0] != vdr1.String()[:redactedIDLen] {
+		t.Fatalf("Wrong redacted validator ID reported: %s", vdrs[0])
+	}
+}
+
+func TestSetSharedDurations(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	durations, err := NewSharedDurations("", registerer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newSet := func(chain string) Set {
+		return NewSetWithConfig(SetConfig{
+			Factory:         NewNoEarlyTermFactory(),
+			Log:             logging.NoLog{},
+			Namespace:       chain,
+			Registerer:      registerer,
+			SharedDurations: durations,
+			Chain:           chain,
+		})
+	}
+	s0 := newSet("chain0")
+	s1 := newSet("chain1")
+
+	vdr1 := ids.ShortID{1} // k = 1
+
+	newVdrs := func() ids.ShortBag {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(vdr1)
+		return vdrs
+	}
+
+	if !s0.Add(0, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if !s0.Add(1, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if !s1.Add(0, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := s0.Vote(0, vdr1, ids.ID{1}); !finished {
+		t.Fatalf("Should have finished the poll")
+	} else if _, finished := s0.Vote(1, vdr1, ids.ID{1}); !finished {
+		t.Fatalf("Should have finished the poll")
+	} else if _, finished := s1.Vote(0, vdr1, ids.ID{1}); !finished {
+		t.Fatalf("Should have finished the poll")
+	}
+
+	metrics, err := registerer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make(map[string]uint64)
+	for _, metric := range metrics {
+		if metric.GetName() != "poll_duration" {
+			continue
+		}
+		for _, m := range metric.GetMetric() {
+			for _, label := range m.GetLabel() {
+				if label.GetName() == chainLabel {
+					counts[label.GetValue()] = m.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+	}
+	if len(counts) != 2 {
+		t.Fatalf("Should have reported durations for 2 chains, reported %d", len(counts))
+	} else if counts["chain0"] != 2 {
+		t.Fatalf("Should have reported 2 durations for chain0, reported %d", counts["chain0"])
+	} else if counts["chain1"] != 1 {
+		t.Fatalf("Should have reported 1 duration for chain1, reported %d", counts["chain1"])
+	}
+
+	if err := s0.Shutdown(); err != nil {
+		t.Fatal(err)
+	} else if durations.DeleteLabelValues("chain0") {
+		t.Fatalf("Shutdown should have removed the chain's durations")
+	}
+}
+
+func gatherLabeledCounter(t *testing.T, registerer *prometheus.Registry, name string, labels prometheus.Labels) float64 {
+	metrics, err := registerer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, metric := range metrics {
+		if metric.GetName() != name {
+			continue
+		}
+		for _, m := range metric.GetMetric() {
+			matches := 0
+			for _, label := range m.GetLabel() {
+				if value, ok := labels[label.GetName()]; ok && value == label.GetValue() {
+					matches++
+				}
+			}
+			if matches == len(labels) {
+				return m.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+func TestSetOutcomes(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	s := NewSetWithConfig(SetConfig{
+		Factory:    NewNoEarlyTermFactory(),
+		Log:        logging.NoLog{},
+		Registerer: registerer,
+		Outcomes:   true,
+	})
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2} // k = 2
+
+	newVdrs := func() ids.ShortBag {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(vdr1, vdr2)
+		return vdrs
+	}
+
+	if !s.Add(0, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if !s.Add(1, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if !s.Add(2, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := s.Vote(0, vdr1, ids.ID{1}); finished {
+		t.Fatalf("Poll finished after less than alpha votes")
+	} else if _, finished := s.Drop(0, vdr2); !finished {
+		t.Fatalf("Should have finished the poll")
+	} else if _, finished := s.Drop(1, vdr1); finished {
+		t.Fatalf("Poll finished after less than alpha votes")
+	} else if _, finished := s.Drop(1, vdr2); !finished {
+		t.Fatalf("Should have finished the poll")
+	} else if !s.Cancel(2) {
+		t.Fatalf("Should have cancelled the poll")
+	}
+
+	outcome := func(outcome string) float64 {
+		return gatherLabeledCounter(t, registerer, "poll_outcomes", prometheus.Labels{outcomeLabel: outcome})
+	}
+	response := func(outcome string) float64 {
+		return gatherLabeledCounter(t, registerer, "poll_responses", prometheus.Labels{outcomeLabel: outcome})
+	}
+	if successful := outcome(successfulOutcome); successful != 1 {
+		t.Fatalf("Should have reported 1 successful poll, reported %v", successful)
+	} else if failed := outcome(failedOutcome); failed != 1 {
+		t.Fatalf("Should have reported 1 failed poll, reported %v", failed)
+	} else if cancelled := outcome(cancelledOutcome); cancelled != 1 {
+		t.Fatalf("Should have reported 1 cancelled poll, reported %v", cancelled)
+	} else if voted := response(votedOutcome); voted != 1 {
+		t.Fatalf("Should have reported 1 vote, reported %v", voted)
+	} else if dropped := response(droppedOutcome); dropped != 3 {
+		t.Fatalf("Should have reported 3 drops, reported %v", dropped)
+	}
+}
+
+func TestSetSharedOutcomes(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	outcomes, err := NewSharedOutcomes("", registerer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	responses, err := NewSharedResponses("", registerer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newSet := func(chain string) Set {
+		return NewSetWithConfig(SetConfig{
+			Factory:         NewNoEarlyTermFactory(),
+			Log:             logging.NoLog{},
+			Namespace:       chain,
+			Registerer:      registerer,
+			SharedOutcomes:  outcomes,
+			SharedResponses: responses,
+			Chain:           chain,
+		})
+	}
+	s0 := newSet("chain0")
+	s1 := newSet("chain1")
+
+	vdr1 := ids.ShortID{1} // k = 1
+
+	newVdrs := func() ids.ShortBag {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(vdr1)
+		return vdrs
+	}
+
+	if !s0.Add(0, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if !s0.Add(1, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if !s1.Add(0, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := s0.Vote(0, vdr1, ids.ID{1}); !finished {
+		t.Fatalf("Should have finished the poll")
+	} else if _, finished := s0.Vote(1, vdr1, ids.ID{1}); !finished {
+		t.Fatalf("Should have finished the poll")
+	} else if _, finished := s1.Drop(0, vdr1); !finished {
+		t.Fatalf("Should have finished the poll")
+	}
+
+	labels := func(chain, outcome string) prometheus.Labels {
+		return prometheus.Labels{chainLabel: chain, outcomeLabel: outcome}
+	}
+	if successful := gatherLabeledCounter(t, registerer, "poll_outcomes", labels("chain0", successfulOutcome)); successful != 2 {
+		t.Fatalf("Should have reported 2 successful polls for chain0, reported %v", successful)
+	} else if failed := gatherLabeledCounter(t, registerer, "poll_outcomes", labels("chain1", failedOutcome)); failed != 1 {
+		t.Fatalf("Should have reported 1 failed poll for chain1, reported %v", failed)
+	} else if voted := gatherLabeledCounter(t, registerer, "poll_responses", labels("chain0", votedOutcome)); voted != 2 {
+		t.Fatalf("Should have reported 2 votes for chain0, reported %v", voted)
+	} else if dropped := gatherLabeledCounter(t, registerer, "poll_responses", labels("chain1", droppedOutcome)); dropped != 1 {
+		t.Fatalf("Should have reported 1 drop for chain1, reported %v", dropped)
+	}
+
+	if err := s0.Shutdown(); err != nil {
+		t.Fatal(err)
+	} else if outcomes.DeleteLabelValues("chain0", successfulOutcome) {
+		t.Fatalf("Shutdown should have removed the chain's outcomes")
+	} else if responses.DeleteLabelValues("chain0", votedOutcome) {
+		t.Fatalf("Shutdown should have removed the chain's responses")
+	} else if !outcomes.DeleteLabelValues("chain1", failedOutcome) {
+		t.Fatalf("Shutdown shouldn't have removed other chains' outcomes")
+	}
+}
+
+func TestSetTransferTo(t *testing.T) {
+	factory := NewNoEarlyTermFactory()
+	log := logging.NoLog{}
+	src := NewSet(factory, log, "src", prometheus.NewRegistry())
+	dst := NewSet(factory, log, "dst", prometheus.NewRegistry())
+
+	start := time.Now()
+	src.(*set).clock.Set(start)
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2} // k = 2
+
+	newVdrs := func() ids.ShortBag {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(
+			vdr1,
+			vdr2,
+		)
+		return vdrs
+	}
+
+	if !src.Add(0, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if !src.Add(1, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := src.Vote(0, vdr1, vtxID); finished {
+		t.Fatalf("Shouldn't have been able to finish an ongoing poll")
+	} else if err := src.TransferTo(dst); err != nil {
+		t.Fatal(err)
+	} else if src.Len() != 0 {
+		t.Fatalf("Source shouldn't have any active polls")
+	} else if dst.Len() != 2 {
+		t.Fatalf("Destination should have two active polls")
+	} else if _, finished := src.Vote(0, vdr2, vtxID); finished {
+		t.Fatalf("Shouldn't have been able to finish a transferred poll in the source")
+	} else if p := dst.(*set).polls[0]; !p.start.Equal(start) {
+		t.Fatalf("Transferred poll should have kept its start time")
+	} else if result, finished := dst.Vote(0, vdr2, vtxID); !finished {
+		t.Fatalf("Should have finished the transferred poll")
+	} else if result.Count(vtxID) != 2 {
+		t.Fatalf("Transferred poll should have kept its votes")
+	}
+}
+
+func TestSetTransferToDuplicated(t *testing.T) {
+	factory := NewNoEarlyTermFactory()
+	log := logging.NoLog{}
+	src := NewSet(factory, log, "src", prometheus.NewRegistry())
+	dst := NewSet(factory, log, "dst", prometheus.NewRegistry())
+
+	vdr1 := ids.ShortID{1} // k = 1
+
+	newVdrs := func() ids.ShortBag {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(vdr1)
+		return vdrs
+	}
+
+	if !src.Add(0, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if !src.Add(1, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if !dst.Add(1, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if err := src.TransferTo(dst); err == nil {
+		t.Fatalf("Should have failed to transfer a duplicated requestID")
+	} else if src.Len() != 2 {
+		t.Fatalf("Source should have kept its polls")
+	} else if dst.Len() != 1 {
+		t.Fatalf("Destination shouldn't have received any polls")
+	} else if err := src.TransferTo(src); err == nil {
+		t.Fatalf("Should have failed to transfer to itself")
+	}
+}
+
+func TestSetTransferToRejected(t *testing.T) {
+	factory := NewNoEarlyTermFactory()
+	log := logging.NoLog{}
+	src := NewSet(factory, log, "src", prometheus.NewRegistry())
+	limited := NewSetWithConfig(SetConfig{
+		Factory:        factory,
+		Log:            log,
+		Namespace:      "limited",
+		Registerer:     prometheus.NewRegistry(),
+		MaxOutstanding: 2,
+	})
+	shutdown := NewSet(factory, log, "shutdown", prometheus.NewRegistry())
+	if err := shutdown.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+
+	vdr1 := ids.ShortID{1} // k = 1
+
+	newVdrs := func() ids.ShortBag {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(vdr1)
+		return vdrs
+	}
+
+	if !src.Add(0, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if !src.Add(1, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if !limited.Add(2, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if err := src.TransferTo(limited); err == nil {
+		t.Fatalf("Should have failed to exceed the destination's maximum outstanding polls")
+	} else if src.Len() != 2 {
+		t.Fatalf("Source should have kept its polls")
+	} else if limited.Len() != 1 {
+		t.Fatalf("Destination shouldn't have received any polls")
+	} else if err := src.TransferTo(shutdown); err == nil {
+		t.Fatalf("Should have failed to transfer to a set that was shut down")
+	} else if src.Len() != 2 {
+		t.Fatalf("Source should have kept its polls")
+	} else if shutdown.Len() != 0 {
+		t.Fatalf("Destination shouldn't have received any polls")
+	}
+}
+
+func TestSetTransferToConcurrent(t *testing.T) {
+	factory := NewNoEarlyTermFactory()
+	log := logging.NoLog{}
+	s1 := NewSet(factory, log, "s1", prometheus.NewRegistry())
+	s2 := NewSet(factory, log, "s2", prometheus.NewRegistry())
+
+	// Transfers in opposite directions must not deadlock
+	wg := sync.WaitGroup{}
+	for i := 0; i < 2; i++ {
+		src, dst := s1, s2
+		if i == 1 {
+			src, dst = s2, s1
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				if err := src.TransferTo(dst); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestSetAddEmptyPoll(t *testing.T) {
+	factory := NewNoEarlyTermFactory()
+	log := logging.NoLog{}
+	namespace := ""
+	registerer := prometheus.NewRegistry()
+	s := NewSet(factory, log, namespace, registerer)
+
+	var nilVdrs ids.ShortBag
+	emptyVdrs := ids.ShortBag{}
+	emptyVdrs.Add(ids.ShortID{1})
+	emptyVdrs.Remove(ids.ShortID{1})
+
+	if s.Add(0, nilVdrs) {
+		t.Fatalf("Shouldn't have been able to add a poll without validators")
+	} else if s.Add(1, emptyVdrs) {
+		t.Fatalf("Shouldn't have been able to add a poll without validators")
+	} else if s.Len() != 0 {
+		t.Fatalf("Shouldn't have any active polls")
+	}
+
+	metrics, err := registerer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, metric := range metrics {
+		if metric.GetName() != "empty_polls" {
+			continue
+		}
+		if value := metric.GetMetric()[0].GetCounter().GetValue(); value != 2 {
+			t.Fatalf("Should have reported 2 empty polls, reported %f", value)
+		}
+		return
+	}
+	t.Fatalf("Empty polls metric wasn't registered")
+}
+
+func TestSetDropWithRetryThenVote(t *testing.T) {
+	factory := NewNoEarlyTermFactory()
+	log := logging.NoLog{}
+	namespace := ""
+	registerer := prometheus.NewRegistry()
+	s := NewSet(factory, log, namespace, registerer)
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2} // k = 2
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(
+		vdr1,
+		vdr2,
+	)
+
+	if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := s.Vote(0, vdr1, vtxID); finished {
+		t.Fatalf("Shouldn't have been able to finish an ongoing poll")
+	} else if _, finished, retry := s.DropWithRetry(0, vdr2, 1); finished {
+		t.Fatalf("Shouldn't have finished the poll while retrying")
+	} else if !retry {
+		t.Fatalf("Should have retried the dropped validator")
+	} else if result, finished := s.Vote(0, vdr2, vtxID); !finished {
+		t.Fatalf("Should have finished the poll")
+	} else if result.Count(vtxID) != 2 {
+		t.Fatalf("Should have counted the retried validator's vote")
+	}
+}
+
+func TestSetDropWithRetryExhausted(t *testing.T) {
+	factory := NewNoEarlyTermFactory()
+	log := logging.NoLog{}
+	namespace := ""
+	registerer := prometheus.NewRegistry()
+	s := NewSet(factory, log, namespace, registerer)
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2} // k = 2
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(
+		vdr1,
+		vdr2,
+	)
+
+	if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := s.Vote(0, vdr1, vtxID); finished {
+		t.Fatalf("Shouldn't have been able to finish an ongoing poll")
+	} else if _, finished, retry := s.DropWithRetry(0, vdr2, 2); finished || !retry {
+		t.Fatalf("Should have retried the dropped validator")
+	} else if _, finished, retry := s.DropWithRetry(0, vdr2, 2); finished || !retry {
+		t.Fatalf("Should have retried the dropped validator")
+	} else if result, finished, retry := s.DropWithRetry(0, vdr2, 2); !finished {
+		t.Fatalf("Should have finished the poll after exhausting the retries")
+	} else if retry {
+		t.Fatalf("Shouldn't have retried the dropped validator")
+	} else if result.Count(vtxID) != 1 {
+		t.Fatalf("Wrong number of votes returned")
+	} else if _, _, retry := s.DropWithRetry(0, vdr2, 2); retry {
+		t.Fatalf("Shouldn't have retried a validator of an unknown poll")
+	}
+}
+
+func TestSetAddFromValidators(t *testing.T) {
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+
+	vdrs := validators.NewSet()
+	if err := vdrs.AddWeight(vdr1, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewSetWithConfig(SetConfig{
+		Factory:    NewNoEarlyTermFactory(),
+		Log:        logging.NoLog{},
+		Registerer: prometheus.NewRegistry(),
+		Validators: vdrs,
+	})
+
+	vtxID := ids.ID{1}
+
+	if polled, added := s.AddFromValidators(0, 1); !added {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if polled.Len() != 1 || polled.Count(vdr1) != 1 {
+		t.Fatalf("Should have polled the current validator")
+	} else if _, added := s.AddFromValidators(1, 2); added {
+		t.Fatalf("Shouldn't have been able to sample more validators than exist")
+	} else if result, finished := s.Vote(0, vdr1, vtxID); !finished {
+		t.Fatalf("Should have finished the poll")
+	} else if result.Count(vtxID) != 1 {
+		t.Fatalf("Wrong number of votes returned")
+	}
+
+	if err := vdrs.Set([]validators.Validator{
+		validators.NewValidator(vdr2, 1),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if polled, added := s.AddFromValidators(2, 1); !added {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if polled.Len() != 1 || polled.Count(vdr2) != 1 {
+		t.Fatalf("Should have polled the updated validator")
+	} else if _, finished := s.Vote(2, vdr1, vtxID); finished {
+		t.Fatalf("Shouldn't have finished the poll with a vote from a removed validator")
+	} else if _, finished := s.Vote(2, vdr2, vtxID); !finished {
+		t.Fatalf("Should have finished the poll")
+	}
+}
+
+func TestSetAddFromValidatorsAdversarialSampler(t *testing.T) {
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+	vdr3 := ids.ShortID{3}
+	vdr4 := ids.ShortID{4}
+
+	sampler := &polltest.AdversarialSampler{
+		Targets: []ids.ShortID{vdr4, vdr3},
+	}
+	s := NewSetWithConfig(SetConfig{
+		Factory:    NewNoEarlyTermFactory(),
+		Log:        logging.NoLog{},
+		Registerer: prometheus.NewRegistry(),
+		Validators: validators.NewTestSet(map[ids.ShortID]uint64{
+			vdr1: 1000,
+			vdr2: 1000,
+			vdr3: 1,
+		}),
+		Sampler: sampler,
+	})
+
+	polled, added := s.AddFromValidators(0, 4)
+	if !added {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if sampler.Sampled() != 1 {
+		t.Fatalf("Should have used the adversarial sampler")
+	} else if polled.Len() != 4 || polled.Count(vdr3) != 4 {
+		t.Fatalf("Should have only polled the targeted validator, polled %s", &polled)
+	}
+
+	sampler.Targets = []ids.ShortID{vdr4}
+	if _, added := s.AddFromValidators(1, 1); added {
+		t.Fatalf("Shouldn't have been able to add a poll without sampling any validators")
+	}
+}
+
+func TestSetAddFromValidatorsAdversarialSamplerConcurrent(t *testing.T) {
+	vdr1 := ids.ShortID{1}
+
+	sampler := &polltest.AdversarialSampler{
+		Targets: []ids.ShortID{vdr1},
+	}
+	s := NewSetWithConfig(SetConfig{
+		Factory:    NewNoEarlyTermFactory(),
+		Log:        logging.NoLog{},
+		Registerer: prometheus.NewRegistry(),
+		Validators: validators.NewTestSet(map[ids.ShortID]uint64{
+			vdr1: 1,
+		}),
+		Sampler: sampler,
+	})
+
+	numPolls := 100
+	wg := sync.WaitGroup{}
+	wg.Add(numPolls)
+	for i := 0; i < numPolls; i++ {
+		go func(requestID uint32) {
+			defer wg.Done()
+			s.AddFromValidators(requestID, 1)
+		}(uint32(i))
+	}
+	wg.Wait()
+
+	if sampled := sampler.Sampled(); sampled != numPolls {
+		t.Fatalf("Should have sampled %d times, sampled %d times", numPolls, sampled)
+	} else if s.Len() != numPolls {
+		t.Fatalf("Should have added %d polls, added %d", numPolls, s.Len())
+	}
+}
+
+func TestSetAddFromValidatorsWithoutValidators(t *testing.T) {
+	factory := NewNoEarlyTermFactory()
+	log := logging.NoLog{}
+	namespace := ""
+	registerer := prometheus.NewRegistry()
+	s := NewSet(factory, log, namespace, registerer)
+
+	if _, added := s.AddFromValidators(0, 1); added {
+		t.Fatalf("Shouldn't have been able to add a poll without a validator set")
+	}
+}
+
+func TestSetOnCreate(t *testing.T) {
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2} // k = 2
+
+	var (
+		s       Set
+		created []uint32
+	)
+	s = NewSetWithConfig(SetConfig{
+		Factory:    NewNoEarlyTermFactory(),
+		Log:        logging.NoLog{},
+		Registerer: prometheus.NewRegistry(),
+		OnCreate: func(requestID uint32, vdrs ids.ShortBag) {
+			created = append(created, requestID)
+			if vdrs.Len() != 2 {
+				t.Fatalf("Wrong number of validators reported: %d", vdrs.Len())
+			}
+			// The set's lock must not be held
+			if s.Len() != len(created) {
+				t.Fatalf("Wrong number of polls reported: %d", s.Len())
+			}
+		},
+	})
+
+	newVdrs := func() ids.ShortBag {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(
+			vdr1,
+			vdr2,
+		)
+		return vdrs
+	}
+
+	if !s.Add(0, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if s.Add(0, newVdrs()) {
+		t.Fatalf("Shouldn't have been able to add a duplicated poll")
+	} else if s.Add(1, ids.ShortBag{}) {
+		t.Fatalf("Shouldn't have been able to add a poll without validators")
+	} else if !s.Add(2, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if len(created) != 2 {
+		t.Fatalf("OnCreate should have been called twice, was called %d times", len(created))
+	} else if created[0] != 0 || created[1] != 2 {
+		t.Fatalf("OnCreate was called with the wrong requestIDs: %v", created)
+	}
+}
+
+func TestSetShutdownWhileActive(t *testing.T) {
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2} // k = 2
+
+	registerer := prometheus.NewRegistry()
+	var (
+		s         Set
+		shutdown  = make(chan struct{})
+		callbacks sync.WaitGroup
+	)
+	s = NewSetWithConfig(SetConfig{
+		Factory:    NewNoEarlyTermFactory(),
+		Log:        logging.NoLog{},
+		Registerer: registerer,
+		OnCreate: func(uint32, ids.ShortBag) {
+			select {
+			case <-shutdown:
+				// Once shutdown has completed, no callbacks should be executing
+				t.Errorf("OnCreate was called after shutdown completed")
+			default:
+			}
+			_ = s.Len()
+		},
+	})
+
+	for i := 0; i < 4; i++ {
+		callbacks.Add(1)
+		go func(offset uint32) {
+			defer callbacks.Done()
+
+			for requestID := offset; requestID < 1000; requestID += 4 {
+				vdrs := ids.ShortBag{}
+				vdrs.Add(
+					vdr1,
+					vdr2,
+				)
+				s.Add(requestID, vdrs)
+				s.Vote(requestID, vdr1, ids.ID{1})
+				s.Drop(requestID, vdr2)
+			}
+		}(uint32(i))
+	}
+
+	if err := s.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	close(shutdown)
+	callbacks.Wait()
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1)
+	if s.Add(1000, vdrs) {
+		t.Fatalf("Shouldn't have been able to add a poll after shutdown")
+	} else if s.Len() != 0 {
+		t.Fatalf("Shouldn't have any active polls after shutdown")
+	}
+
+	metrics, err := registerer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(metrics) != 0 {
+		t.Fatalf("Shutdown should have unregistered all metrics")
+	}
+}
+
+func TestSetConfig(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	s := NewSetWithConfig(SetConfig{
+		Factory:           NewEarlyTermNoTraversalFactory(3),
+		Log:               logging.NoLog{},
+		Namespace:         "chain",
+		Registerer:        registerer,
+		RedactIDs:         true,
+		Validators:        validators.NewSet(),
+		UnknownValidators: FilterUnknownValidators,
+		MaxPollAge:        time.Minute,
+		OldestPollAge:     true,
+		OnFinish:          func(PollResult) {},
+	})
+
+	config := s.Config()
+	if config.Factory != "EarlyTermNoTraversal(Alpha = 3)" {
+		t.Fatalf("Wrong factory reported: %s", config.Factory)
+	} else if config.ShadowFactory != "" {
+		t.Fatalf("Shouldn't have reported a shadow factory")
+	} else if config.Namespace != "chain" {
+		t.Fatalf("Wrong namespace reported: %s", config.Namespace)
+	} else if !config.RedactIDs {
+		t.Fatalf("Should have reported redacting IDs")
+	} else if !config.Validators {
+		t.Fatalf("Should have reported sampling from validators")
+	} else if config.Sampler != "ValidatorSampler" {
+		t.Fatalf("Should have reported the default sampler, reported %s", config.Sampler)
+	} else if config.UnknownValidators != "Filter" {
+		t.Fatalf("Wrong unknown validator policy reported: %s", config.UnknownValidators)
+	} else if config.MaxPollAge != time.Minute {
+		t.Fatalf("Wrong maximum poll age reported: %s", config.MaxPollAge)
+	} else if config.Aggregated {
+		t.Fatalf("Shouldn't have reported an aggregator")
+	} else if config.SharedDurations {
+		t.Fatalf("Shouldn't have reported shared durations")
+	} else if !config.OldestPollAge {
+		t.Fatalf("Should have reported the oldest poll age being enabled")
+	} else if config.SlowDurations {
+		t.Fatalf("Shouldn't have reported slow durations being enabled")
+	}
+
+	configBytes, err := json.Marshal(config)
+	if err != nil {
+		t.Fatalf("Should have been able to marshal the config: %s", err)
+	}
+	unmarshalled := EffectiveConfig{}
+	if err := json.Unmarshal(configBytes, &unmarshalled); err != nil {
+		t.Fatal(err)
+	} else if unmarshalled != config {
+		t.Fatalf("The config should have survived marshalling")
+	}
+
+	s = NewSetWithConfig(SetConfig{
+		Factory:    NewNoEarlyTermFactory(),
+		Log:        logging.NoLog{},
+		Registerer: prometheus.NewRegistry(),
+		Validators: validators.NewSet(),
+		Sampler:    &polltest.AdversarialSampler{},
+	})
+	if sampler := s.Config().Sampler; sampler != "*polltest.AdversarialSampler" {
+		t.Fatalf("Should have reported the configured sampler, reported %s", sampler)
+	}
+}
+
+func gatherCounter(t *testing.T, registerer *prometheus.Registry, name string) float64 {
+	metrics, err := registerer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, metric := range metrics {
+		if metric.GetName() == name {
+			return metric.GetMetric()[0].GetCounter().GetValue()
+		}
+	}
+	t.Fatalf("%s metric wasn't registered", name)
+	return 0
+}
+
+func TestSetShadowAgrees(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	s := NewSetWithShadow(
+		NewEarlyTermNoTraversalFactory(2),
+		NewInternedEarlyTermNoTraversalFactory(2),
+		logging.NoLog{},
+		"",
+		registerer,
+	)
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+	vdr3 := ids.ShortID{3} // k = 3
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(
+		vdr1,
+		vdr2,
+		vdr3,
+	)
+
+	if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := s.Vote(0, vdr1, vtxID); finished {
+		t.Fatalf("Shouldn't have been able to finish an ongoing poll")
+	} else if result, finished := s.Vote(0, vdr2, vtxID); !finished {
+		t.Fatalf("Should have finished the poll")
+	} else if result.Count(vtxID) != 2 {
+		t.Fatalf("Wrong number of votes returned")
+	} else if divergences := gatherCounter(t, registerer, "shadow_poll_divergences"); divergences != 0 {
+		t.Fatalf("Shouldn't have reported any divergences, reported %f", divergences)
+	}
+}
+
+func TestSetShadowDiverges(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	s := NewSetWithShadow(
+		NewEarlyTermNoTraversalFactory(2),
+		NewNoEarlyTermFactory(),
+		logging.NoLog{},
+		"",
+		registerer,
+	)
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+	vdr3 := ids.ShortID{3} // k = 3
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(
+		vdr1,
+		vdr2,
+		vdr3,
+	)
+
+	if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := s.Vote(0, vdr1, vtxID); finished {
+		t.Fatalf("Shouldn't have been able to finish an ongoing poll")
+	} else if result, finished := s.Vote(0, vdr2, vtxID); !finished {
+		t.Fatalf("The shadow poll shouldn't have impacted finishing the poll")
+	} else if result.Count(vtxID) != 2 {
+		t.Fatalf("Wrong number of votes returned")
+	} else if divergences := gatherCounter(t, registerer, "shadow_poll_divergences"); divergences != 1 {
+		t.Fatalf("Should have reported 1 divergence, reported %f", divergences)
+	}
+}
+
+func TestSetMaxObservedDuration(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	s := NewSetWithConfig(SetConfig{
+		Factory:             NewNoEarlyTermFactory(),
+		Log:                 logging.NoLog{},
+		Registerer:          registerer,
+		MaxObservedDuration: time.Second,
+		SlowDurations:       true,
+	})
+
+	now := time.Now()
+	s.(*set).clock.Set(now)
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1} // k = 1
+
+	newVdrs := func() ids.ShortBag {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(vdr1)
+		return vdrs
+	}
+
+	if !s.Add(0, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if !s.Add(1, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	}
+
+	s.(*set).clock.Set(now.Add(500 * time.Millisecond))
+	if _, finished := s.Vote(0, vdr1, vtxID); !finished {
+		t.Fatalf("Should have finished the poll")
+	}
+
+	s.(*set).clock.Set(now.Add(time.Hour))
+	if _, finished := s.Vote(1, vdr1, vtxID); !finished {
+		t.Fatalf("Should have finished the poll")
+	}
+
+	if clamped := gatherCounter(t, registerer, "clamped_poll_durations"); clamped != 1 {
+		t.Fatalf("Should have reported 1 clamped duration, reported %f", clamped)
+	}
+
+	if count, sum := gatherHistogram(t, registerer, "poll_duration"); count != 2 {
+		t.Fatalf("Should have reported 2 durations, reported %d", count)
+	} else if sum != 1500 {
+		t.Fatalf("Should have reported a total duration of 1500ms, reported %f", sum)
+	}
+	if count, sum := gatherHistogram(t, registerer, "slow_poll_duration"); count != 1 {
+		t.Fatalf("Should have reported 1 slow duration, reported %d", count)
+	} else if sum != 1 {
+		t.Fatalf("Should have reported a total slow duration of 1s, reported %f", sum)
+	}
+}
+
+func TestSetSlowPollDuration(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	s := NewSetWithConfig(SetConfig{
+		Factory:       NewNoEarlyTermFactory(),
+		Log:           logging.NoLog{},
+		Registerer:    registerer,
+		SlowDurations: true,
+	})
+
+	now := time.Now()
+	s.(*set).clock.Set(now)
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1} // k = 1
+
+	newVdrs := func() ids.ShortBag {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(vdr1)
+		return vdrs
+	}
+
+	for requestID := uint32(0); requestID < 3; requestID++ {
+		if !s.Add(requestID, newVdrs()) {
+			t.Fatalf("Should have been able to add a new poll")
+		}
+	}
+
+	s.(*set).clock.Set(now.Add(250 * time.Millisecond))
+	if _, finished := s.Vote(0, vdr1, vtxID); !finished {
+		t.Fatalf("Should have finished the poll")
+	}
+
+	s.(*set).clock.Set(now.Add(30 * time.Second))
+	if _, finished := s.Vote(1, vdr1, vtxID); !finished {
+		t.Fatalf("Should have finished the poll")
+	}
+
+	s.(*set).clock.Set(now.Add(2 * time.Minute))
+	if _, finished := s.Vote(2, vdr1, vtxID); !finished {
+		t.Fatalf("Should have finished the poll")
+	}
+
+	// Slow polls are reported in both histograms
+	if count, sum := gatherHistogram(t, registerer, "poll_duration"); count != 3 {
+		t.Fatalf("Should have reported 3 durations, reported %d", count)
+	} else if sum != 150250 {
+		t.Fatalf("Should have reported a total duration of 150250ms, reported %f", sum)
+	}
+	if count, sum := gatherHistogram(t, registerer, "slow_poll_duration"); count != 2 {
+		t.Fatalf("Should have reported 2 slow durations, reported %d", count)
+	} else if sum != 150 {
+		t.Fatalf("Should have reported a total slow duration of 150s, reported %f", sum)
+	}
+}
+
+// gatherHistogram returns the sample count and sum of the histogram [name]
+func gatherHistogram(t *testing.T, registerer *prometheus.Registry, name string) (uint64, float64) {
+	metrics, err := registerer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, metric := range metrics {
+		if metric.GetName() == name {
+			histogram := metric.GetMetric()[0].GetHistogram()
+			return histogram.GetSampleCount(), histogram.GetSampleSum()
+		}
+	}
+	t.Fatalf("%s metric wasn't registered", name)
+	return 0, 0
+}
+
+func TestSetConcurrentVotes(t *testing.T) {
+	factory := NewNoEarlyTermFactory()
+	log := logging.NoLog{}
+	namespace := ""
+	registerer := prometheus.NewRegistry()
+	s := NewSet(factory, log, namespace, registerer)
+
+	vtxID := ids.ID{1}
+
+	numPolls := 100
+	vdrList := []ids.ShortID{{1}, {2}, {3}, {4}} // k = 4
+	for requestID := 0; requestID < numPolls; requestID++ {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(vdrList...)
+		if !s.Add(uint32(requestID), vdrs) {
+			t.Fatalf("Should have been able to add a new poll")
+		}
+	}
+
+	var (
+		wg       sync.WaitGroup
+		lock     sync.Mutex
+		finished = make(map[uint32]int)
+	)
+	for _, vdr := range vdrList {
+		wg.Add(1)
+		go func(vdr ids.ShortID) {
+			defer wg.Done()
+
+			for requestID := 0; requestID < numPolls; requestID++ {
+				// Respond twice to make sure duplicated responses are ignored
+				for i := 0; i < 2; i++ {
+					result, done := s.Vote(uint32(requestID), vdr, vtxID)
+					if !done {
+						continue
+					}
+					if result.Count(vtxID) != len(vdrList) {
+						t.Errorf("Wrong number of votes returned")
+					}
+
+					lock.Lock()
+					finished[uint32(requestID)]++
+					lock.Unlock()
+				}
+			}
+		}(vdr)
+	}
+	wg.Wait()
+
+	if s.Len() != 0 {
+		t.Fatalf("Shouldn't have any active polls")
+	} else if len(finished) != numPolls {
+		t.Fatalf("Should have finished %d polls, finished %d", numPolls, len(finished))
+	}
+	for requestID, count := range finished {
+		if count != 1 {
+			t.Fatalf("Poll with requestID %d was finished %d times", requestID, count)
+		}
+	}
+}
+
+func BenchmarkSetConcurrentVotes(b *testing.B) {
+	factory := NewNoEarlyTermFactory()
+	log := logging.NoLog{}
+	namespace := ""
+	registerer := prometheus.NewRegistry()
+	s := NewSet(factory, log, namespace, registerer)
+
+	vtxID := ids.ID{1}
+
+	vdrList := make([]ids.ShortID, 20)
+	for i := range vdrList {
+		vdrList[i] = ids.ShortID{byte(i)}
+	}
+
+	var nextRequestID uint32
+	var lock sync.Mutex
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			lock.Lock()
+			requestID := nextRequestID
+			nextRequestID++
+			lock.Unlock()
+
+			vdrs := ids.ShortBag{}
+			vdrs.Add(vdrList...)
+			s.Add(requestID, vdrs)
+			for _, vdr := range vdrList {
+				s.Vote(requestID, vdr, vtxID)
+			}
+		}
+	})
+}
+
+func TestSetChurningBlocks(t *testing.T) {
+	s := NewSetWithConfig(SetConfig{
+		Factory:    NewNoEarlyTermFactory(),
+		Log:        logging.NoLog{},
+		Registerer: prometheus.NewRegistry(),
+		Alpha:      2,
+	})
+
+	blkID1 := ids.ID{1}
+	blkID2 := ids.ID{2}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2} // k = 2
+
+	poll := func(requestID uint32, vote1, vote2 ids.ID) {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(
+			vdr1,
+			vdr2,
+		)
+		if !s.Add(requestID, vdrs) {
+			t.Fatalf("Should have been able to add a new poll")
+		} else if _, finished := s.Vote(requestID, vdr1, vote1); finished {
+			t.Fatalf("Shouldn't have been able to finish an ongoing poll")
+		} else if _, finished := s.Vote(requestID, vdr2, vote2); !finished {
+			t.Fatalf("Should have finished the poll")
+		}
+	}
+
+	for requestID := uint32(0); requestID < 3; requestID++ {
+		poll(requestID, blkID1, blkID2)
+	}
+
+	if churning := s.ChurningBlocks(4); len(churning) != 0 {
+		t.Fatalf("Shouldn't have reported any churning blocks, reported %v", churning)
+	} else if churning := s.ChurningBlocks(3); len(churning) != 2 {
+		t.Fatalf("Should have reported 2 churning blocks, reported %v", churning)
+	} else if churning[0] != blkID1 || churning[1] != blkID2 {
+		t.Fatalf("Wrong churning blocks reported: %v", churning)
+	}
+
+	poll(3, blkID1, blkID1)
+	if churning := s.ChurningBlocks(1); len(churning) != 0 {
+		t.Fatalf("Shouldn't have reported any churning blocks after alpha was reached, reported %v", churning)
+	}
+
+	poll(4, blkID1, blkID2)
+	if churning := s.ChurningBlocks(2); len(churning) != 0 {
+		t.Fatalf("Streaks should have been reset, reported %v", churning)
+	} else if churning := s.ChurningBlocks(1); len(churning) != 2 {
+		t.Fatalf("Should have reported 2 churning blocks, reported %v", churning)
+	}
+}
+
+func TestSetUnknownValidators(t *testing.T) {
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2} // not a validator
+
+	vdrSet := validators.NewSet()
+	if err := vdrSet.AddWeight(vdr1, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	newVdrs := func() ids.ShortBag {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(
+			vdr1,
+			vdr2,
+		)
+		return vdrs
+	}
+
+	filterRegisterer := prometheus.NewRegistry()
+	filter := NewSetWithConfig(SetConfig{
+		Factory:           NewNoEarlyTermFactory(),
+		Log:               logging.NoLog{},
+		Registerer:        filterRegisterer,
+		Validators:        vdrSet,
+		UnknownValidators: FilterUnknownValidators,
+	})
+	if !filter.Add(0, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if result, finished := filter.Vote(0, vdr1, vtxID); !finished {
+		t.Fatalf("Should have finished the poll once the known validator voted")
+	} else if result.Count(vtxID) != 1 {
+		t.Fatalf("Wrong number of votes returned")
+	} else if unknown := gatherCounter(t, filterRegisterer, "unknown_poll_validators"); unknown != 1 {
+		t.Fatalf("Should have reported 1 unknown validator, reported %f", unknown)
+	}
+
+	rejectRegisterer := prometheus.NewRegistry()
+	reject := NewSetWithConfig(SetConfig{
+		Factory:           NewNoEarlyTermFactory(),
+		Log:               logging.NoLog{},
+		Registerer:        rejectRegisterer,
+		Validators:        vdrSet,
+		UnknownValidators: RejectUnknownValidators,
+	})
+	if reject.Add(0, newVdrs()) {
+		t.Fatalf("Shouldn't have been able to add a poll with an unknown validator")
+	} else if reject.Len() != 0 {
+		t.Fatalf("Shouldn't have any active polls")
+	} else if unknown := gatherCounter(t, rejectRegisterer, "unknown_poll_validators"); unknown != 1 {
+		t.Fatalf("Should have reported 1 unknown validator, reported %f", unknown)
+	}
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1)
+	if !reject.Add(1, vdrs) {
+		t.Fatalf("Should have been able to add a poll of known validators")
+	}
+}
+
+func TestSetRecentResults(t *testing.T) {
+	s := NewSetWithConfig(SetConfig{
+		Factory:       NewNoEarlyTermFactory(),
+		Log:           logging.NoLog{},
+		Registerer:    prometheus.NewRegistry(),
+		RecentResults: 2,
+	})
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1} // k = 1
+
+	for requestID := uint32(0); requestID < 3; requestID++ {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(vdr1)
+		if !s.Add(requestID, vdrs) {
+			t.Fatalf("Should have been able to add a new poll")
+		} else if _, finished := s.Vote(requestID, vdr1, vtxID); !finished {
+			t.Fatalf("Should have finished the poll")
+		}
+	}
+
+	results := s.RecentResults()
+	if len(results) != 2 {
+		t.Fatalf("Should have reported 2 results, reported %d", len(results))
+	} else if results[0].RequestID != 2 || results[1].RequestID != 1 {
+		t.Fatalf("Wrong results reported: %v", results)
+	} else if results[0].Result.Count(vtxID) != 1 {
+		t.Fatalf("Wrong number of votes reported")
+	}
+}
+
+func TestSetPollID(t *testing.T) {
+	factory := NewNoEarlyTermFactory()
+	log := logging.NoLog{}
+	namespace := ""
+	registerer := prometheus.NewRegistry()
+	s := NewSet(factory, log, namespace, registerer)
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1, vdr2)
+
+	if _, exists := s.PollID(0); exists {
+		t.Fatalf("Shouldn't have reported an ID for an unknown poll")
+	}
+
+	expectedID := pollID(0, vdrs)
+	if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	}
+	if id, exists := s.PollID(0); !exists {
+		t.Fatalf("Should have reported an ID for an outstanding poll")
+	} else if id != expectedID {
+		t.Fatalf("Should have reported %s, reported %s", expectedID, id)
+	}
+
+	s.Drop(0, vdr1)
+	s.Drop(0, vdr2)
+	if _, exists := s.PollID(0); exists {
+		t.Fatalf("Shouldn't have reported an ID for a finished poll")
+	}
+}
+
+func TestSetRequeue(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	s := NewSetWithConfig(SetConfig{
+		Factory:       NewNoEarlyTermFactory(),
+		Log:           logging.NoLog{},
+		Registerer:    registerer,
+		RecentResults: 1,
+		MaxRequeues:   2,
+	})
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1} // k = 1
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1)
+	if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := s.Vote(0, vdr1, vtxID); !finished {
+		t.Fatalf("Should have finished the poll")
+	}
+
+	result := s.RecentResults()[0]
+	if requeued := s.Requeued(); len(requeued) != 0 {
+		t.Fatalf("Shouldn't have any requeued results")
+	}
+
+	for i := 1; i <= 2; i++ {
+		if !s.Requeue(result) {
+			t.Fatalf("Should have been able to requeue the result")
+		}
+		requeued := s.Requeued()
+		if len(requeued) != 1 {
+			t.Fatalf("Should have redelivered 1 result, redelivered %d", len(requeued))
+		}
+		result = requeued[0]
+		if result.RequestID != 0 {
+			t.Fatalf("Wrong result redelivered")
+		} else if result.Requeues != i {
+			t.Fatalf("Result should have been requeued %d times, was requeued %d times", i, result.Requeues)
+		} else if result.Result.Count(vtxID) != 1 {
+			t.Fatalf("Wrong number of votes redelivered")
+		}
+		if requeued := s.Requeued(); len(requeued) != 0 {
+			t.Fatalf("Requeued results should only be redelivered once")
+		}
+	}
+
+	if s.Requeue(result) {
+		t.Fatalf("Shouldn't have been able to requeue the result more than the maximum number of times")
+	} else if requeued := s.Requeued(); len(requeued) != 0 {
+		t.Fatalf("Shouldn't have redelivered a result that exceeded the maximum number of requeues")
+	}
+
+	// The set tracks the number of requeues, so resetting the count on the
+	// result doesn't allow it to be requeued again
+	result.Requeues = 0
+	if s.Requeue(result) {
+		t.Fatalf("Shouldn't have been able to requeue a result by resetting its requeue count")
+	}
+	if rejected := gatherCounter(t, registerer, "rejected_poll_requeues"); rejected != 2 {
+		t.Fatalf("Should have reported 2 rejected requeues, reported %f", rejected)
+	}
+
+	// A new poll with the same requestID can be requeued again
+	vdrs = ids.ShortBag{}
+	vdrs.Add(vdr1)
+	if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := s.Vote(0, vdr1, vtxID); !finished {
+		t.Fatalf("Should have finished the poll")
+	} else if !s.Requeue(s.RecentResults()[0]) {
+		t.Fatalf("Should have been able to requeue the result of the new poll")
+	} else if requeued := s.Requeued(); len(requeued) != 1 || requeued[0].Requeues != 1 {
+		t.Fatalf("Should have redelivered the new result once")
+	}
+}
+
+func TestSetCancelForContainer(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	s := NewSet(NewNoEarlyTermFactory(), logging.NoLog{}, "", registerer)
+
+	blkID1 := ids.ID{1}
+	blkID2 := ids.ID{2}
+
+	vdr1 := ids.ShortID{1} // k = 1
+
+	newVdrs := func() ids.ShortBag {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(vdr1)
+		return vdrs
+	}
+
+	if !s.AddForContainer(0, blkID1, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if !s.AddForContainer(1, blkID2, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if !s.AddForContainer(2, blkID1, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if !s.Add(3, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	}
+
+	cancelled := s.CancelForContainer(blkID1)
+	if len(cancelled) != 2 {
+		t.Fatalf("Should have cancelled 2 polls, cancelled %d", len(cancelled))
+	} else if cancelled[0] != 0 || cancelled[1] != 2 {
+		t.Fatalf("Wrong polls cancelled: %v", cancelled)
+	} else if s.Len() != 2 {
+		t.Fatalf("Should have 2 outstanding polls, have %d", s.Len())
+	}
+
+	if _, finished := s.Vote(0, vdr1, blkID1); finished {
+		t.Fatalf("Cancelled poll shouldn't have finished")
+	} else if _, finished := s.Vote(1, vdr1, blkID2); !finished {
+		t.Fatalf("Should have finished the poll")
+	}
+
+	if cancelled := s.CancelForContainer(ids.ID{}); len(cancelled) != 0 {
+		t.Fatalf("Shouldn't have cancelled polls without a container")
+	}
+	if numCancelled := gatherCounter(t, registerer, "cancelled_polls"); numCancelled != 2 {
+		t.Fatalf("Should have reported 2 cancelled polls, reported %f", numCancelled)
+	}
+	if numPolls := gatherGauge(t, registerer, "polls"); numPolls != 1 {
+		t.Fatalf("Should have reported 1 outstanding poll, reported %f", numPolls)
+	}
+}
+
+// gatherGauge returns the value of the gauge [name]
+func gatherGauge(t *testing.T, registerer *prometheus.Registry, name string) float64 {
+	metrics, err := registerer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, metric := range metrics {
+		if metric.GetName() == name {
+			return metric.GetMetric()[0].GetGauge().GetValue()
+		}
+	}
+	t.Fatalf("%s metric wasn't registered", name)
+	return 0
+}
+
+type warnLog struct {
+	logging.NoLog
+	warnings []string
+}
+
+func (l *warnLog) Warn(format string, args ...interface{}) {
+	l.warnings = append(l.warnings, fmt.Sprintf(format, args...))
+}
+
+func TestSetSlowPollThreshold(t *testing.T) {
+	log := &warnLog{}
+	s := NewSetWithConfig(SetConfig{
+		Factory:           NewNoEarlyTermFactory(),
+		Log:               log,
+		Registerer:        prometheus.NewRegistry(),
+		SlowPollThreshold: time.Second,
+	})
+
+	now := time.Now()
+	s.(*set).clock.Set(now)
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2} // k = 2
+
+	newVdrs := func() ids.ShortBag {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(vdr1, vdr2)
+		return vdrs
+	}
+
+	if !s.Add(0, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if !s.Add(1, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	}
+
+	s.(*set).clock.Set(now.Add(100 * time.Millisecond))
+	s.Vote(0, vdr1, vtxID)
+	if _, finished := s.Vote(0, vdr2, vtxID); !finished {
+		t.Fatalf("Should have finished the poll")
+	} else if len(log.warnings) != 0 {
+		t.Fatalf("Shouldn't have logged a fast poll: %v", log.warnings)
+	}
+
+	s.Vote(1, vdr2, vtxID)
+	s.(*set).clock.Set(now.Add(5 * time.Second))
+	if _, finished := s.Vote(1, vdr1, vtxID); !finished {
+		t.Fatalf("Should have finished the poll")
+	} else if len(log.warnings) != 1 {
+		t.Fatalf("Should have logged the slow poll")
+	}
+
+	expected := fmt.Sprintf("poll with requestID 1 took 5s to finish. Last responders: %v", []ids.ShortID{vdr2, vdr1})
+	if log.warnings[0] != expected {
+		t.Fatalf("Expected warning %q, logged %q", expected, log.warnings[0])
+	}
+}
+
+func TestSetDropRate(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	s := NewSetWithConfig(SetConfig{
+		Factory:        NewNoEarlyTermFactory(),
+		Log:            logging.NoLog{},
+		Registerer:     registerer,
+		DropRateWindow: time.Minute,
+	})
+
+	now := time.Now()
+	s.(*set).clock.Set(now)
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+	vdr3 := ids.ShortID{3}
+	vdr4 := ids.ShortID{4} // k = 4
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1, vdr2, vdr3, vdr4)
+
+	if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	}
+	s.Vote(0, vdr1, vtxID)
+	s.Vote(0, vdr1, vtxID) // duplicate responses aren't counted
+	s.Drop(0, vdr2)
+	s.Drop(0, vdr3)
+	s.Drop(0, vdr4)
+
+	if rate := gatherGauge(t, registerer, "poll_drop_rate"); rate != .75 {
+		t.Fatalf("Should have reported a drop rate of 0.75, reported %f", rate)
+	}
+
+	s.(*set).clock.Set(now.Add(2 * time.Minute))
+	if rate := gatherGauge(t, registerer, "poll_drop_rate"); rate != 0 {
+		t.Fatalf("Should have reported a drop rate of 0 once the responses expired, reported %f", rate)
+	}
+}
+
+func TestSetOnFinish(t *testing.T) {
+	results := []PollResult(nil)
+	s := NewSetWithConfig(SetConfig{
+		Factory:    NewNoEarlyTermFactory(),
+		Log:        logging.NoLog{},
+		Registerer: prometheus.NewRegistry(),
+		OnFinish: func(result PollResult) {
+			results = append(results, result)
+		},
+	})
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1} // k = 1
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1)
+
+	if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := s.Vote(0, vdr1, vtxID); !finished {
+		t.Fatalf("Should have finished the poll")
+	} else if len(results) != 1 {
+		t.Fatalf("Should have reported 1 result, reported %d", len(results))
+	} else if results[0].RequestID != 0 {
+		t.Fatalf("Wrong result reported")
+	} else if results[0].Result.Count(vtxID) != 1 {
+		t.Fatalf("Wrong number of votes reported")
+	}
+}
+
+func TestSetOrderedFinish(t *testing.T) {
+	const numPolls = 8
+
+	first := make(chan struct{})
+	release := make(chan struct{})
+	reported := []uint32(nil)
+	s := NewSetWithConfig(SetConfig{
+		Factory:       NewNoEarlyTermFactory(),
+		Log:           logging.NoLog{},
+		Registerer:    prometheus.NewRegistry(),
+		OrderedFinish: true,
+		OnFinish: func(result PollResult) {
+			if len(reported) == 0 {
+				close(first)
+				<-release
+			}
+			reported = append(reported, result.RequestID)
+		},
+	})
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1} // k = 1
+
+	for requestID := uint32(0); requestID < numPolls; requestID++ {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(vdr1)
+		if !s.Add(requestID, vdrs) {
+			t.Fatalf("Should have been able to add a new poll")
+		}
+	}
+
+	// The first poll to finish blocks the reporting of the remaining polls
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.Vote(numPolls-1, vdr1, vtxID)
+	}()
+	<-first
+
+	// The remaining polls finish concurrently in a random order
+	order := rand.New(rand.NewSource(0)).Perm(numPolls - 1) // #nosec G404
+	for _, requestID := range order {
+		wg.Add(1)
+		go func(requestID uint32) {
+			defer wg.Done()
+			s.Vote(requestID, vdr1, vtxID)
+		}(uint32(requestID))
+	}
+	for {
+		s.(*set).finishedLock.Lock()
+		numBuffered := len(s.(*set).finished)
+		s.(*set).finishedLock.Unlock()
+		if numBuffered == numPolls-1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	close(release)
+	wg.Wait()
+
+	if len(reported) != numPolls {
+		t.Fatalf("Should have reported %d results, reported %d", numPolls, len(reported))
+	} else if reported[0] != numPolls-1 {
+		t.Fatalf("Should have reported the first finished poll first")
+	}
+	for i, requestID := range reported[1:] {
+		if requestID != uint32(i) {
+			t.Fatalf("Buffered results should have been reported in order of their requestIDs: %v", reported)
+		}
+	}
+}
+
+func TestSetForceFinalize(t *testing.T) {
+	results := []PollResult(nil)
+	registerer := prometheus.NewRegistry()
+	s := NewSetWithConfig(SetConfig{
+		Factory:            NewNoEarlyTermFactory(),
+		Log:                logging.NoLog{},
+		Registerer:         registerer,
+		AllowForceFinalize: true,
+		OnFinish: func(result PollResult) {
+			results = append(results, result)
+		},
+	})
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2} // k = 2
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1, vdr2)
+
+	if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := s.Vote(0, vdr1, vtxID); finished {
+		t.Fatalf("Shouldn't have finished the poll")
+	}
+
+	forced := ids.Bag{}
+	forced.AddCount(ids.ID{2}, 2)
+	if !s.ForceFinalize(0, forced) {
+		t.Fatalf("Should have force finalized the poll")
+	} else if s.Len() != 0 {
+		t.Fatalf("Should have removed the poll")
+	} else if len(results) != 1 {
+		t.Fatalf("Should have reported 1 result, reported %d", len(results))
+	} else if !results[0].Result.Equals(forced) {
+		t.Fatalf("Should have reported the forced result, reported %s", &results[0].Result)
+	} else if numPolls := gatherGauge(t, registerer, "polls"); numPolls != 0 {
+		t.Fatalf("Should have reported 0 outstanding polls, reported %f", numPolls)
+	} else if count, _ := gatherHistogram(t, registerer, "poll_duration"); count != 1 {
+		t.Fatalf("Should have reported 1 duration, reported %d", count)
+	}
+
+	if _, finished := s.Vote(0, vdr2, vtxID); finished {
+		t.Fatalf("Force finalized poll shouldn't finish again")
+	} else if s.ForceFinalize(0, forced) {
+		t.Fatalf("Shouldn't have force finalized an unknown poll")
+	}
+}
+
+func TestSetForceFinalizeDisabled(t *testing.T) {
+	s := NewSet(NewNoEarlyTermFactory(), logging.NoLog{}, "", prometheus.NewRegistry())
+
+	vdr1 := ids.ShortID{1} // k = 1
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1)
+
+	if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if s.ForceFinalize(0, ids.Bag{}) {
+		t.Fatalf("Shouldn't have force finalized the poll while disabled")
+	} else if s.Len() != 1 {
+		t.Fatalf("Shouldn't have removed the poll")
+	}
+}
+
+func TestSetValidatorSeconds(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	s := NewSetWithConfig(SetConfig{
+		Factory:          NewNoEarlyTermFactory(),
+		Log:              logging.NoLog{},
+		Registerer:       registerer,
+		ValidatorSeconds: true,
+	})
+
+	now := time.Now()
+	s.(*set).clock.Set(now)
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+	vdr3 := ids.ShortID{3}
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1, vdr2) // k = 2
+	if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	}
+
+	// 2 validators are polled for 10 seconds
+	s.(*set).clock.Set(now.Add(10 * time.Second))
+	vdrs = ids.ShortBag{}
+	vdrs.Add(vdr1, vdr2, vdr3) // k = 3
+	if !s.Add(1, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	}
+
+	// 5 validators are polled for 4 seconds
+	s.(*set).clock.Set(now.Add(14 * time.Second))
+	s.Vote(0, vdr1, vtxID)
+	if _, finished := s.Vote(0, vdr2, vtxID); !finished {
+		t.Fatalf("Should have finished the poll")
+	}
+
+	// 3 validators are polled for 2 seconds
+	s.(*set).clock.Set(now.Add(16 * time.Second))
+	s.Vote(1, vdr1, vtxID)
+	s.Vote(1, vdr2, vtxID)
+	if _, finished := s.Vote(1, vdr3, vtxID); !finished {
+		t.Fatalf("Should have finished the poll")
+	}
+
+	// No validators are polled
+	s.(*set).clock.Set(now.Add(time.Minute))
+	if value := gatherCounter(t, registerer, "poll_validator_seconds"); value != 46 {
+		t.Fatalf("Should have reported 46 validator seconds, reported %f", value)
+	}
+}
+
+func TestSetFinalizeAndReissue(t *testing.T) {
+	s := NewSet(NewNoEarlyTermFactory(), logging.NoLog{}, "", prometheus.NewRegistry())
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2} // k = 2
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1, vdr2)
+
+	if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := s.Vote(0, vdr1, vtxID); finished {
+		t.Fatalf("Shouldn't have finished the poll")
+	}
+
+	result, reissued := s.FinalizeAndReissue(0, 1)
+	if !reissued {
+		t.Fatalf("Should have reissued the poll")
+	} else if count := result.Count(vtxID); count != 1 {
+		t.Fatalf("Should have reported 1 vote, reported %d", count)
+	} else if s.Len() != 1 {
+		t.Fatalf("Should have 1 outstanding poll, have %d", s.Len())
+	} else if _, finished := s.Vote(0, vdr2, vtxID); finished {
+		t.Fatalf("The old poll shouldn't be outstanding")
+	}
+
+	if _, finished := s.Vote(1, vdr1, vtxID); finished {
+		t.Fatalf("Shouldn't have finished the reissued poll")
+	}
+	result, finished := s.Vote(1, vdr2, vtxID)
+	if !finished {
+		t.Fatalf("Should have finished the reissued poll")
+	} else if count := result.Count(vtxID); count != 2 {
+		t.Fatalf("Should have reported 2 votes, reported %d", count)
+	}
+}
+
+func TestSetFinalizeAndReissueDuplicateRequestID(t *testing.T) {
+	s := NewSet(NewNoEarlyTermFactory(), logging.NoLog{}, "", prometheus.NewRegistry())
+
+	vdr1 := ids.ShortID{1} // k = 1
+
+	newVdrs := func() ids.ShortBag {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(vdr1)
+		return vdrs
+	}
+
+	if !s.Add(0, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if !s.Add(1, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, reissued := s.FinalizeAndReissue(0, 1); reissued {
+		t.Fatalf("Shouldn't have reissued the poll with an outstanding requestID")
+	} else if _, reissued := s.FinalizeAndReissue(2, 3); reissued {
+		t.Fatalf("Shouldn't have reissued an unknown poll")
+	} else if s.Len() != 2 {
+		t.Fatalf("Shouldn't have modified the outstanding polls")
+	}
+}
+
+func TestSetFinalizeAndReissueMandatory(t *testing.T) {
+	s := NewSetWithConfig(SetConfig{
+		Factory:        NewEarlyTermNoTraversalFactory(1),
+		Log:            logging.NoLog{},
+		Registerer:     prometheus.NewRegistry(),
+		MaxOutstanding: 1,
+	})
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2} // k = 2
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1, vdr2)
+	mandatory := ids.ShortSet{}
+	mandatory.Add(vdr2)
+
+	if !s.AddWithMandatory(0, vdrs, mandatory) {
+		t.Fatalf("Should have been able to add a new poll")
+	}
+
+	// The set is at its maximum number of outstanding polls, but the old poll
+	// is replaced by the reissued poll
+	if _, reissued := s.FinalizeAndReissue(0, 1); !reissued {
+		t.Fatalf("Should have reissued the poll")
+	} else if s.Len() != 1 {
+		t.Fatalf("Should have 1 outstanding poll, have %d", s.Len())
+	}
+
+	// An alpha majority doesn't finish the reissued poll until the mandatory
+	// validator has responded
+	if _, finished := s.Vote(1, vdr1, vtxID); finished {
+		t.Fatalf("Shouldn't have finished the reissued poll before the mandatory validator responded")
+	} else if result, finished := s.Vote(1, vdr2, vtxID); !finished {
+		t.Fatalf("Should have finished the reissued poll once the mandatory validator responded")
+	} else if count := result.Count(vtxID); count != 2 {
+		t.Fatalf("Should have reported 2 votes, reported %d", count)
+	}
+}
+
+func TestSetFinalizeAndReissueFailedAdd(t *testing.T) {
+	vdr1 := ids.ShortID{1} // k = 1
+
+	vdrSet := validators.NewSet()
+	if err := vdrSet.AddWeight(vdr1, 1); err != nil {
+		t.Fatal(err)
+	}
+	s := NewSetWithConfig(SetConfig{
+		Factory:           NewNoEarlyTermFactory(),
+		Log:               logging.NoLog{},
+		Registerer:        prometheus.NewRegistry(),
+		Validators:        vdrSet,
+		UnknownValidators: RejectUnknownValidators,
+	})
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1)
+	if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	}
+
+	// Once the validator leaves the validator set, the poll can't be reissued
+	if err := vdrSet.RemoveWeight(vdr1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, reissued := s.FinalizeAndReissue(0, 1); reissued {
+		t.Fatalf("Shouldn't have reissued a poll of unknown validators")
+	} else if s.Len() != 1 {
+		t.Fatalf("Should have kept the old poll outstanding")
+	} else if _, finished := s.Vote(0, vdr1, ids.ID{1}); !finished {
+		t.Fatalf("Should have been able to finish the old poll")
+	}
+}
+
+func TestSetSubsystem(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	s := NewSetWithConfig(SetConfig{
+		Factory:    NewNoEarlyTermFactory(),
+		Log:        logging.NoLog{},
+		Namespace:  "namespace",
+		Subsystem:  "subsystem",
+		Registerer: registerer,
+	})
+
+	vdr1 := ids.ShortID{1} // k = 1
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1)
+	if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	}
+
+	if numPolls := gatherGauge(t, registerer, "namespace_subsystem_polls"); numPolls != 1 {
+		t.Fatalf("Should have reported 1 outstanding poll, reported %f", numPolls)
+	}
+	if count, _ := gatherHistogram(t, registerer, "namespace_subsystem_poll_duration"); count != 0 {
+		t.Fatalf("Shouldn't have reported any durations, reported %d", count)
+	}
+}
+
+func TestSetPruneDisconnected(t *testing.T) {
+	results := []PollResult(nil)
+	registerer := prometheus.NewRegistry()
+	s := NewSetWithConfig(SetConfig{
+		Factory:    NewNoEarlyTermFactory(),
+		Log:        logging.NoLog{},
+		Registerer: registerer,
+		OnFinish: func(result PollResult) {
+			results = append(results, result)
+		},
+	})
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+	vdr3 := ids.ShortID{3}
+
+	// Poll 0 only polls disconnected validators
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1, vdr2)
+	if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	}
+
+	// Poll 1 polls a connected validator that hasn't responded
+	vdrs = ids.ShortBag{}
+	vdrs.Add(vdr1, vdr3)
+	if !s.Add(1, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	}
+
+	// Poll 2 finishes once its disconnected validator is dropped
+	vdrs = ids.ShortBag{}
+	vdrs.Add(vdr2, vdr3)
+	if !s.Add(2, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := s.Vote(2, vdr3, vtxID); finished {
+		t.Fatalf("Shouldn't have finished the poll")
+	}
+
+	connected := ids.ShortSet{}
+	connected.Add(vdr3)
+
+	cancelled, finished := s.PruneDisconnected(connected)
+	if len(cancelled) != 1 || cancelled[0] != 0 {
+		t.Fatalf("Should have only cancelled poll 0, cancelled %v", cancelled)
+	} else if len(finished) != 1 {
+		t.Fatalf("Should have returned 1 finished poll, returned %d", len(finished))
+	} else if finished[0].RequestID != 2 || finished[0].Result.Count(vtxID) != 1 {
+		t.Fatalf("Wrong result returned")
+	} else if s.Len() != 1 {
+		t.Fatalf("Should have 1 outstanding poll, have %d", s.Len())
+	} else if len(results) != 1 {
+		t.Fatalf("Should have finished 1 poll, finished %d", len(results))
+	} else if results[0].RequestID != 2 || results[0].Result.Count(vtxID) != 1 {
+		t.Fatalf("Wrong result reported")
+	}
+
+	if result, finished := s.Vote(1, vdr3, vtxID); !finished {
+		t.Fatalf("Should have finished the poll once the connected validator responded")
+	} else if count := result.Count(vtxID); count != 1 {
+		t.Fatalf("Should have reported 1 vote, reported %d", count)
+	}
+
+	if numCancelled := gatherCounter(t, registerer, "cancelled_polls"); numCancelled != 1 {
+		t.Fatalf("Should have reported 1 cancelled poll, reported %f", numCancelled)
+	} else if numPolls := gatherGauge(t, registerer, "polls"); numPolls != 0 {
+		t.Fatalf("Should have reported 0 outstanding polls, reported %f", numPolls)
+	}
+}
+
+func TestSetLastResultFor(t *testing.T) {
+	s := NewSetWithConfig(SetConfig{
+		Factory:          NewNoEarlyTermFactory(),
+		Log:              logging.NoLog{},
+		Registerer:       prometheus.NewRegistry(),
+		ContainerResults: 2,
+	})
+
+	blkID1 := ids.ID{1}
+	blkID2 := ids.ID{2}
+	blkID3 := ids.ID{3}
+
+	vdr1 := ids.ShortID{1} // k = 1
+
+	finish := func(requestID uint32, blkID, vote ids.ID) {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(vdr1)
+		if !s.AddForContainer(requestID, blkID, vdrs) {
+			t.Fatalf("Should have been able to add a new poll")
+		} else if _, finished := s.Vote(requestID, vdr1, vote); !finished {
+			t.Fatalf("Should have finished the poll")
+		}
+	}
+
+	if _, ok := s.LastResultFor(blkID1); ok {
+		t.Fatalf("Shouldn't have reported a result for an unpolled container")
+	}
+
+	finish(0, blkID1, blkID1)
+	finish(1, blkID2, blkID2)
+	finish(2, blkID1, blkID2)
+
+	if result, ok := s.LastResultFor(blkID1); !ok {
+		t.Fatalf("Should have reported a result for blkID1")
+	} else if result.Count(blkID2) != 1 || result.Count(blkID1) != 0 {
+		t.Fatalf("Should have reported the most recent result for blkID1, reported %s", &result)
+	}
+	if result, ok := s.LastResultFor(blkID2); !ok {
+		t.Fatalf("Should have reported a result for blkID2")
+	} else if result.Count(blkID2) != 1 {
+		t.Fatalf("Wrong result reported for blkID2: %s", &result)
+	}
+
+	// blkID1 was used more recently than blkID2, so blkID2 should be evicted
+	s.LastResultFor(blkID1)
+	finish(3, blkID3, blkID3)
+	if _, ok := s.LastResultFor(blkID2); ok {
+		t.Fatalf("Should have evicted the least recently used result")
+	} else if _, ok := s.LastResultFor(blkID1); !ok {
+		t.Fatalf("Shouldn't have evicted a recently used result")
+	} else if _, ok := s.LastResultFor(blkID3); !ok {
+		t.Fatalf("Should have reported a result for blkID3")
+	}
+}
+
+func TestSetBatchedMetrics(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	s := NewSetWithConfig(SetConfig{
+		Factory:             NewNoEarlyTermFactory(),
+		Log:                 logging.NoLog{},
+		Registerer:          registerer,
+		MetricBatchSize:     4,
+		MetricFlushInterval: time.Minute,
+		SlowDurations:       true,
+	})
+
+	now := time.Now()
+	s.(*set).clock.Set(now)
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1} // k = 1
+
+	add := func(requestID uint32) {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(vdr1)
+		if !s.Add(requestID, vdrs) {
+			t.Fatalf("Should have been able to add a new poll")
+		}
+	}
+
+	add(0)
+	add(1)
+	add(2)
+	if numPolls := gatherGauge(t, registerer, "polls"); numPolls != 0 {
+		t.Fatalf("Shouldn't have reported the pending polls before flushing, reported %f", numPolls)
+	}
+
+	// Finishing a poll reaches the batch size
+	if _, finished := s.Vote(0, vdr1, vtxID); !finished {
+		t.Fatalf("Should have finished the poll")
+	}
+	if numPolls := gatherGauge(t, registerer, "polls"); numPolls != 2 {
+		t.Fatalf("Should have reported 2 pending polls, reported %f", numPolls)
+	} else if count, _ := gatherHistogram(t, registerer, "poll_duration"); count != 1 {
+		t.Fatalf("Should have reported 1 duration, reported %d", count)
+	}
+
+	if _, finished := s.Vote(1, vdr1, vtxID); !finished {
+		t.Fatalf("Should have finished the poll")
+	}
+	if numPolls := gatherGauge(t, registerer, "polls"); numPolls != 2 {
+		t.Fatalf("Shouldn't have reported the finished poll before flushing, reported %f", numPolls)
+	}
+
+	// Passing the flush interval causes the next update to flush
+	s.(*set).clock.Set(now.Add(time.Minute))
+	if _, finished := s.Vote(2, vdr1, vtxID); !finished {
+		t.Fatalf("Should have finished the poll")
+	}
+	if numPolls := gatherGauge(t, registerer, "polls"); numPolls != 0 {
+		t.Fatalf("Should have reported 0 pending polls, reported %f", numPolls)
+	} else if count, _ := gatherHistogram(t, registerer, "poll_duration"); count != 3 {
+		t.Fatalf("Should have reported 3 durations, reported %d", count)
+	} else if count, _ := gatherHistogram(t, registerer, "slow_poll_duration"); count != 1 {
+		t.Fatalf("Should have reported 1 slow duration, reported %d", count)
+	}
+}
+
+func TestSetBatchedMetricsFlushedPeriodically(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	s := NewSetWithConfig(SetConfig{
+		Factory:             NewNoEarlyTermFactory(),
+		Log:                 logging.NoLog{},
+		Registerer:          registerer,
+		MetricBatchSize:     100,
+		MetricFlushInterval: time.Millisecond,
+	})
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(ids.ShortID{1})
+	if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	}
+
+	// The set is idle, so only the periodic flush can report the poll
+	deadline := time.Now().Add(5 * time.Second)
+	for gatherGauge(t, registerer, "polls") != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Should have periodically flushed the pending poll")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := s.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// retainingRegistry reports metrics as unregistered without removing them, so
+// that they can be gathered after a set has been shut down
+type retainingRegistry struct {
+	*prometheus.Registry
+}
+
+func (retainingRegistry) Unregister(prometheus.Collector) bool { return true }
+
+func TestSetBatchedMetricsFlushedOnShutdown(t *testing.T) {
+	registerer := retainingRegistry{Registry: prometheus.NewRegistry()}
+	s := NewSetWithConfig(SetConfig{
+		Factory:         NewNoEarlyTermFactory(),
+		Log:             logging.NoLog{},
+		Registerer:      registerer,
+		MetricBatchSize: 100,
+	})
+
+	vdr1 := ids.ShortID{1} // k = 1
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1)
+	if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := s.Vote(0, vdr1, ids.ID{1}); !finished {
+		t.Fatalf("Should have finished the poll")
+	} else if count, _ := gatherHistogram(t, registerer.Registry, "poll_duration"); count != 0 {
+		t.Fatalf("Shouldn't have reported the duration before flushing, reported %d", count)
+	}
+
+	if err := s.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := gatherHistogram(t, registerer.Registry, "poll_duration"); count != 1 {
+		t.Fatalf("Should have flushed the duration on shutdown, reported %d", count)
+	}
+}
+
+func TestSetSizeDistribution(t *testing.T) {
+	s := NewSet(NewNoEarlyTermFactory(), logging.NoLog{}, "", prometheus.NewRegistry())
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+	vdr3 := ids.ShortID{3}
+
+	add := func(requestID uint32, vdrList ...ids.ShortID) {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(vdrList...)
+		if !s.Add(requestID, vdrs) {
+			t.Fatalf("Should have been able to add a new poll")
+		}
+	}
+
+	if distribution := s.SizeDistribution(); len(distribution) != 0 {
+		t.Fatalf("Shouldn't have reported any polls, reported %v", distribution)
+	}
+
+	add(0, vdr1)
+	add(1, vdr1, vdr2)
+	add(2, vdr2, vdr3)
+	add(3, vdr1, vdr1, vdr3) // duplicates count towards the size
+
+	distribution := s.SizeDistribution()
+	if len(distribution) != 3 {
+		t.Fatalf("Should have reported 3 sizes, reported %v", distribution)
+	} else if distribution[1] != 1 {
+		t.Fatalf("Should have reported 1 poll of size 1, reported %d", distribution[1])
+	} else if distribution[2] != 2 {
+		t.Fatalf("Should have reported 2 polls of size 2, reported %d", distribution[2])
+	} else if distribution[3] != 1 {
+		t.Fatalf("Should have reported 1 poll of size 3, reported %d", distribution[3])
+	}
+}
+
+func TestSetRetainAfterFinish(t *testing.T) {
+	s := NewSetWithConfig(SetConfig{
+		Factory:           NewNoEarlyTermFactory(),
+		Log:               logging.NoLog{},
+		Registerer:        prometheus.NewRegistry(),
+		RetainAfterFinish: time.Second,
+	})
+
+	now := time.Now()
+	s.(*set).clock.Set(now)
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1} // k = 1
+
+	finish := func(requestID uint32) {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(vdr1)
+		if !s.Add(requestID, vdrs) {
+			t.Fatalf("Should have been able to add a new poll")
+		} else if _, finished := s.Vote(requestID, vdr1, vtxID); !finished {
+			t.Fatalf("Should have finished the poll")
+		}
+	}
+
+	if _, ok := s.Result(0); ok {
+		t.Fatalf("Shouldn't have reported the result of an unknown poll")
+	}
+
+	finish(0)
+	s.(*set).clock.Set(now.Add(500 * time.Millisecond))
+	finish(1)
+
+	if result, ok := s.Result(0); !ok {
+		t.Fatalf("Should have retained the finished poll")
+	} else if count := result.Count(vtxID); count != 1 {
+		t.Fatalf("Should have reported 1 vote, reported %d", count)
+	} else if s.Len() != 0 {
+		t.Fatalf("Retained polls shouldn't be outstanding")
+	}
+
+	s.(*set).clock.Set(now.Add(time.Second))
+	if _, ok := s.Result(0); ok {
+		t.Fatalf("Should have removed the result once the retention expired")
+	} else if _, ok := s.Result(1); !ok {
+		t.Fatalf("Should have retained the more recent poll")
+	}
+
+	s.(*set).clock.Set(now.Add(2 * time.Second))
+	if _, ok := s.Result(1); ok {
+		t.Fatalf("Should have removed the result once the retention expired")
+	} else if len(s.(*set).completed) != 0 {
+		t.Fatalf("Should have removed every expired result")
+	}
+}
+
+func TestSetWeightToWin(t *testing.T) {
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+	vdr3 := ids.ShortID{3}
+	vdr4 := ids.ShortID{4} // k = 4
+
+	s := NewSetWithConfig(SetConfig{
+		Factory:    NewNoEarlyTermFactory(),
+		Log:        logging.NoLog{},
+		Registerer: prometheus.NewRegistry(),
+		Validators: validators.NewTestSet(map[ids.ShortID]uint64{
+			vdr1: 40,
+			vdr2: 30,
+			vdr3: 20,
+			vdr4: 10,
+		}),
+		AlphaWeight: 60,
+	})
+
+	blkID1 := ids.ID{1}
+	blkID2 := ids.ID{2}
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1, vdr2, vdr3, vdr4)
+	if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	}
+
+	if _, ok := s.WeightToWin(1, blkID1); ok {
+		t.Fatalf("Shouldn't have reported the weight of an unknown poll")
+	}
+	if weight, ok := s.WeightToWin(0, blkID1); !ok {
+		t.Fatalf("Should have reported the weight of an outstanding poll")
+	} else if weight != 60 {
+		t.Fatalf("Should have needed 60 weight, needed %d", weight)
+	}
+
+	// blkID1 is leading and blkID2 is trailing
+	s.Vote(0, vdr1, blkID1)
+	s.Vote(0, vdr3, blkID2)
+	if weight, _ := s.WeightToWin(0, blkID1); weight != 20 {
+		t.Fatalf("Leading ID should have needed 20 weight, needed %d", weight)
+	} else if weight, _ := s.WeightToWin(0, blkID2); weight != 40 {
+		t.Fatalf("Trailing ID should have needed 40 weight, needed %d", weight)
+	}
+
+	// Only 10 weight remains, so blkID2 can't be decided
+	s.Vote(0, vdr2, blkID1)
+	if weight, _ := s.WeightToWin(0, blkID1); weight != 0 {
+		t.Fatalf("Winning ID shouldn't have needed any weight, needed %d", weight)
+	} else if weight, _ := s.WeightToWin(0, blkID2); weight != ImpossibleWeight {
+		t.Fatalf("Should have reported that the trailing ID can't win, needed %d", weight)
+	}
+}
+
+func TestSetWeightToWinUnconfigured(t *testing.T) {
+	s := NewSet(NewNoEarlyTermFactory(), logging.NoLog{}, "", prometheus.NewRegistry())
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1, vdr2)
+	if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, ok := s.WeightToWin(0, ids.ID{1}); ok {
+		t.Fatalf("Shouldn't have reported a weight without validator weights configured")
+	}
+
+	// The votes are only needed to report the weight to win, so they
+	// shouldn't be tracked
+	if _, finished := s.Vote(0, vdr1, ids.ID{1}); finished {
+		t.Fatalf("Poll finished before receiving all the votes")
+	} else if votes := s.(*set).polls[0].votes; votes != nil {
+		t.Fatalf("Shouldn't have tracked the votes without validator weights configured")
+	}
+}
+
+func TestSetCombineResults(t *testing.T) {
+	s := NewSetWithConfig(SetConfig{
+		Factory:           NewNoEarlyTermFactory(),
+		Log:               logging.NoLog{},
+		Registerer:        prometheus.NewRegistry(),
+		RetainAfterFinish: time.Minute,
+	})
+
+	blkID1 := ids.ID{1}
+	blkID2 := ids.ID{2}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+	vdr3 := ids.ShortID{3}
+	vdr4 := ids.ShortID{4}
+
+	// The query is split across two polls of disjoint validators
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1, vdr2)
+	if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	}
+	vdrs = ids.ShortBag{}
+	vdrs.Add(vdr3, vdr4)
+	if !s.Add(1, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	}
+
+	s.Vote(0, vdr1, blkID1)
+	if _, finished := s.Vote(0, vdr2, blkID1); !finished {
+		t.Fatalf("Should have finished the poll")
+	} else if _, combined := s.CombineResults(0, 1); combined {
+		t.Fatalf("Shouldn't have combined the results before every poll finished")
+	}
+
+	s.Vote(1, vdr3, blkID1)
+	if _, finished := s.Vote(1, vdr4, blkID2); !finished {
+		t.Fatalf("Should have finished the poll")
+	}
+
+	result, combined := s.CombineResults(0, 1)
+	if !combined {
+		t.Fatalf("Should have combined the finished polls")
+	} else if result.Len() != 4 {
+		t.Fatalf("Should have combined 4 votes, combined %d", result.Len())
+	} else if count := result.Count(blkID1); count != 3 {
+		t.Fatalf("Should have combined 3 votes for blkID1, combined %d", count)
+	} else if count := result.Count(blkID2); count != 1 {
+		t.Fatalf("Should have combined 1 vote for blkID2, combined %d", count)
+	}
+}
+
+func TestSetMaxPollAge(t *testing.T) {
+	results := []PollResult(nil)
+	registerer := prometheus.NewRegistry()
+	s := NewSetWithConfig(SetConfig{
+		Factory:       NewNoEarlyTermFactory(),
+		Log:           logging.NoLog{},
+		Registerer:    registerer,
+		MaxPollAge:    time.Minute,
+		SlowDurations: true,
+		OnFinish: func(result PollResult) {
+			results = append(results, result)
+		},
+	})
+
+	now := time.Now()
+	s.(*set).clock.Set(now)
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2} // k = 2
+
+	newVdrs := func() ids.ShortBag {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(vdr1, vdr2)
+		return vdrs
+	}
+
+	if !s.Add(0, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := s.Vote(0, vdr1, vtxID); finished {
+		t.Fatalf("Shouldn't have finished the poll")
+	}
+
+	s.(*set).clock.Set(now.Add(30 * time.Second))
+	if !s.Add(1, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if expired := s.ExpireStale(); len(expired) != 0 {
+		t.Fatalf("Shouldn't have expired a poll that hasn't reached the maximum age")
+	} else if s.Len() != 2 {
+		t.Fatalf("Shouldn't have expired a poll that hasn't reached the maximum age")
+	}
+
+	// Adding a poll doesn't expire poll 0
+	s.(*set).clock.Set(now.Add(time.Minute))
+	if !s.Add(2, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if s.Len() != 3 {
+		t.Fatalf("Shouldn't have expired a poll when adding a poll")
+	}
+
+	// Sweeping expires poll 0
+	expired := s.ExpireStale()
+	if s.Len() != 2 {
+		t.Fatalf("Should have expired the stale poll")
+	} else if len(expired) != 1 {
+		t.Fatalf("Should have returned the expired poll")
+	} else if expired[0].RequestID != 0 || expired[0].Result.Count(vtxID) != 1 {
+		t.Fatalf("Wrong result returned for the expired poll")
+	} else if len(results) != 1 {
+		t.Fatalf("Should have finished the expired poll")
+	} else if results[0].RequestID != 0 || results[0].Result.Count(vtxID) != 1 {
+		t.Fatalf("Wrong result reported for the expired poll")
+	} else if expired := s.ExpireStale(); len(expired) != 0 {
+		t.Fatalf("Shouldn't have expired the poll twice")
+	}
+
+	// Responses to the expired poll are ignored
+	if _, finished := s.Vote(0, vdr2, vtxID); finished {
+		t.Fatalf("Shouldn't have finished an expired poll")
+	}
+
+	if expired := gatherCounter(t, registerer, "expired_polls"); expired != 1 {
+		t.Fatalf("Should have reported 1 expired poll, reported %f", expired)
+	} else if numPolls := gatherGauge(t, registerer, "polls"); numPolls != 2 {
+		t.Fatalf("Should have reported 2 outstanding polls, reported %f", numPolls)
+	} else if count, sum := gatherHistogram(t, registerer, "slow_poll_duration"); count != 1 || sum != 60 {
+		t.Fatalf("Should have reported the expired poll's duration once, reported %d durations totalling %fs", count, sum)
+	}
+}
+
+func TestSetExpireStaleWithoutOnFinish(t *testing.T) {
+	s := NewSetWithConfig(SetConfig{
+		Factory:    NewNoEarlyTermFactory(),
+		Log:        logging.NoLog{},
+		Registerer: prometheus.NewRegistry(),
+		MaxPollAge: time.Minute,
+	})
+
+	now := time.Now()
+	s.(*set).clock.Set(now)
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+
+	for requestID := uint32(0); requestID < 2; requestID++ {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(vdr1, vdr2)
+		if !s.Add(requestID, vdrs) {
+			t.Fatalf("Should have been able to add a new poll")
+		}
+	}
+	if _, finished := s.Vote(1, vdr1, ids.ID{1}); finished {
+		t.Fatalf("Shouldn't have finished the poll")
+	}
+
+	// Without any new polls, the stale polls are expired by sweeping
+	s.(*set).clock.Set(now.Add(time.Minute))
+	expired := s.ExpireStale()
+	if len(expired) != 2 {
+		t.Fatalf("Should have returned both expired polls, returned %d", len(expired))
+	} else if expired[0].RequestID != 0 || expired[0].Result.Len() != 0 {
+		t.Fatalf("Wrong result returned for the first expired poll")
+	} else if expired[1].RequestID != 1 || expired[1].Result.Count(ids.ID{1}) != 1 {
+		t.Fatalf("Wrong result returned for the second expired poll")
+	} else if s.Len() != 0 {
+		t.Fatalf("Should have removed the expired polls")
+	}
+}
+
+func TestSetExpiresStalePollsPeriodically(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	s := NewSetWithConfig(SetConfig{
+		Factory:    NewNoEarlyTermFactory(),
+		Log:        logging.NoLog{},
+		Registerer: registerer,
+		MaxPollAge: 10 * time.Millisecond,
+	})
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(ids.ShortID{1})
+	if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	}
+
+	// The poll never receives a response, and ExpireStale is never called
+	deadline := time.Now().Add(5 * time.Second)
+	for gatherGauge(t, registerer, "polls") != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Should have expired the stale poll")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if s.Len() != 0 {
+		t.Fatalf("Should have removed the expired poll")
+	} else if expired := gatherCounter(t, registerer, "expired_polls"); expired != 1 {
+		t.Fatalf("Should have reported 1 expired poll, reported %f", expired)
+	} else if err := s.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetVoteTerminatesEarly(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	s := NewSet(NewEarlyTermNoTraversalFactory(2), logging.NoLog{}, "", registerer)
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+	vdr3 := ids.ShortID{3} // k = 3
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1, vdr2, vdr3)
+
+	if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := s.Vote(0, vdr1, vtxID); finished {
+		t.Fatalf("Shouldn't have finished the poll")
+	} else if result, finished := s.Vote(0, vdr2, vtxID); !finished {
+		t.Fatalf("Should have finished the poll once alpha votes were received")
+	} else if result.Count(vtxID) != 2 {
+		t.Fatalf("Wrong number of votes returned")
+	} else if s.Len() != 0 {
+		t.Fatalf("Should have removed the finished poll")
+	} else if _, finished := s.Vote(0, vdr3, vtxID); finished {
+		t.Fatalf("Should have ignored the vote for the finished poll")
+	}
+
+	if numPolls := gatherGauge(t, registerer, "polls"); numPolls != 0 {
+		t.Fatalf("Should have reported 0 outstanding polls, reported %f", numPolls)
+	} else if count, _ := gatherHistogram(t, registerer, "poll_duration"); count != 1 {
+		t.Fatalf("Should have reported 1 poll duration, reported %d", count)
+	}
+}
+
+func TestSetFirstResponseDuration(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	s := NewSetWithConfig(SetConfig{
+		Factory:         NewNoEarlyTermFactory(),
+		Log:             logging.NoLog{},
+		Registerer:      registerer,
+		ResponseMetrics: true,
+	})
+
+	now := time.Now()
+	s.(*set).clock.Set(now)
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+	vdr3 := ids.ShortID{3} // k = 3
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1, vdr2, vdr3)
+
+	if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	}
+
+	// Responses from validators that weren't polled aren't the first response
+	s.(*set).clock.Set(now.Add(10 * time.Millisecond))
+	if _, finished := s.Vote(0, ids.ShortID{4}, vtxID); finished {
+		t.Fatalf("Shouldn't have finished the poll")
+	} else if count, _ := gatherHistogram(t, registerer, "poll_first_response"); count != 0 {
+		t.Fatalf("Shouldn't have observed a response from an unpolled validator")
+	}
+
+	s.(*set).clock.Set(now.Add(20 * time.Millisecond))
+	if _, finished := s.Drop(0, vdr1); finished {
+		t.Fatalf("Shouldn't have finished the poll")
+	}
+
+	s.(*set).clock.Set(now.Add(50 * time.Millisecond))
+	if _, finished := s.Vote(0, vdr2, vtxID); finished {
+		t.Fatalf("Shouldn't have finished the poll")
+	}
+
+	s.(*set).clock.Set(now.Add(100 * time.Millisecond))
+	if _, finished := s.Vote(0, vdr3, vtxID); !finished {
+		t.Fatalf("Should have finished the poll")
+	}
+
+	if count, sum := gatherHistogram(t, registerer, "poll_first_response"); count != 1 {
+		t.Fatalf("Should have observed the first response once, observed %d", count)
+	} else if sum != 20 {
+		t.Fatalf("Should have observed the first response after 20ms, observed %fms", sum)
+	} else if count, sum := gatherHistogram(t, registerer, "poll_duration"); count != 1 || sum != 100 {
+		t.Fatalf("Should have observed the poll duration separately, observed %d durations totalling %fms", count, sum)
+	}
+}
+
+func TestSetDropAll(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	s := NewSet(NewNoEarlyTermFactory(), logging.NoLog{}, "", registerer)
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+	vdr3 := ids.ShortID{3}
+
+	newVdrs := func(vdrIDs ...ids.ShortID) ids.ShortBag {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(vdrIDs...)
+		return vdrs
+	}
+
+	if !s.Add(0, newVdrs(vdr1, vdr2)) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if !s.Add(1, newVdrs(vdr1, vdr3)) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if !s.Add(2, newVdrs(vdr2, vdr3)) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if !s.Add(3, newVdrs(vdr1, vdr2)) {
+		t.Fatalf("Should have been able to add a new poll")
+	}
+
+	if _, finished := s.Vote(0, vdr2, vtxID); finished {
+		t.Fatalf("Shouldn't have finished the poll")
+	} else if _, finished := s.Vote(1, vdr3, vtxID); finished {
+		t.Fatalf("Shouldn't have finished the poll")
+	} else if _, finished := s.Vote(3, vdr1, vtxID); finished {
+		t.Fatalf("Shouldn't have finished the poll")
+	}
+
+	// Poll 3 has already received a vote from vdr1 and poll 2 never polled
+	// vdr1, so only polls 0 and 1 finish
+	results := s.DropAll(vdr1)
+	if len(results) != 2 {
+		t.Fatalf("Should have finished 2 polls, finished %d", len(results))
+	} else if results[0].RequestID != 0 || results[1].RequestID != 1 {
+		t.Fatalf("Should have finished polls 0 and 1 in order")
+	} else if results[0].Result.Count(vtxID) != 1 || results[1].Result.Count(vtxID) != 1 {
+		t.Fatalf("Wrong results returned")
+	} else if s.Len() != 2 {
+		t.Fatalf("Should have left the unfinished polls")
+	} else if _, finished := s.Vote(2, vdr2, vtxID); finished {
+		t.Fatalf("Shouldn't have finished the untouched poll")
+	} else if result, finished := s.Vote(3, vdr2, vtxID); !finished {
+		t.Fatalf("Should have finished the poll")
+	} else if result.Count(vtxID) != 2 {
+		t.Fatalf("Should have kept the vote that was received before the drop")
+	}
+
+	if numPolls := gatherGauge(t, registerer, "polls"); numPolls != 1 {
+		t.Fatalf("Should have reported 1 outstanding poll, reported %f", numPolls)
+	} else if count, _ := gatherHistogram(t, registerer, "poll_duration"); count != 3 {
+		t.Fatalf("Should have reported 3 poll durations, reported %d", count)
+	}
+}
+
+func TestSetGetPendingVoters(t *testing.T) {
+	factory := NewNoEarlyTermFactory()
+	log := logging.NoLog{}
+	namespace := ""
+	registerer := prometheus.NewRegistry()
+	s := NewSet(factory, log, namespace, registerer)
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+	vdr3 := ids.ShortID{3} // k = 4
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1, vdr2, vdr3, vdr3)
+
+	if _, exists := s.GetPendingVoters(0); exists {
+		t.Fatalf("Shouldn't have reported pending voters for an unknown poll")
+	} else if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if pending, exists := s.GetPendingVoters(0); !exists {
+		t.Fatalf("Should have reported pending voters for the poll")
+	} else if pending.Len() != 3 || !pending.Contains(vdr1) || !pending.Contains(vdr2) || !pending.Contains(vdr3) {
+		t.Fatalf("Should be waiting on every polled validator, waiting on %s", pending)
+	}
+
+	if _, finished := s.Vote(0, vdr1, vtxID); finished {
+		t.Fatalf("Shouldn't have finished the poll")
+	} else if _, finished := s.Drop(0, vdr3); finished {
+		t.Fatalf("Shouldn't have finished the poll")
+	} else if pending, exists := s.GetPendingVoters(0); !exists {
+		t.Fatalf("Should have reported pending voters for the poll")
+	} else if pending.Len() != 1 || !pending.Contains(vdr2) {
+		t.Fatalf("Should only be waiting on %s, waiting on %s", vdr2, pending)
+	}
+
+	if _, finished := s.Vote(0, vdr2, vtxID); !finished {
+		t.Fatalf("Should have finished the poll")
+	} else if _, exists := s.GetPendingVoters(0); exists {
+		t.Fatalf("Shouldn't have reported pending voters for a finished poll")
+	}
+}
+
+func TestSetMaxOutstanding(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	s := NewSetWithConfig(SetConfig{
+		Factory:        NewNoEarlyTermFactory(),
+		Log:            logging.NoLog{},
+		Registerer:     registerer,
+		MaxOutstanding: 2,
+	})
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1} // k = 1
+
+	newVdrs := func() ids.ShortBag {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(vdr1)
+		return vdrs
+	}
+
+	if !s.Add(0, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if !s.Add(1, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if s.Add(2, newVdrs()) {
+		t.Fatalf("Shouldn't have been able to add a poll while at capacity")
+	} else if s.Len() != 2 {
+		t.Fatalf("Should only have 2 outstanding polls")
+	} else if _, finished := s.Vote(0, vdr1, vtxID); !finished {
+		t.Fatalf("Should have finished the poll")
+	} else if !s.Add(2, newVdrs()) {
+		t.Fatalf("Should have been able to add a poll once below capacity")
+	}
+
+	if rejected := gatherCounter(t, registerer, "rejected_polls"); rejected != 1 {
+		t.Fatalf("Should have reported 1 rejected poll, reported %f", rejected)
+	}
+}
+
+func TestSetVotesHistogram(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	s := NewSetWithConfig(SetConfig{
+		Factory:         NewNoEarlyTermFactory(),
+		Log:             logging.NoLog{},
+		Registerer:      registerer,
+		ResponseMetrics: true,
+	})
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+	vdr3 := ids.ShortID{3} // k = 3
+
+	newVdrs := func() ids.ShortBag {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(vdr1, vdr2, vdr3)
+		return vdrs
+	}
+
+	if !s.Add(0, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if !s.Add(1, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	}
+
+	if _, finished := s.Vote(0, vdr1, vtxID); finished {
+		t.Fatalf("Shouldn't have finished the poll")
+	} else if _, finished := s.Vote(0, vdr2, vtxID); finished {
+		t.Fatalf("Shouldn't have finished the poll")
+	} else if _, finished := s.Vote(0, vdr3, vtxID); !finished {
+		t.Fatalf("Should have finished the poll")
+	}
+
+	if _, finished := s.Vote(1, vdr1, vtxID); finished {
+		t.Fatalf("Shouldn't have finished the poll")
+	} else if _, finished := s.Drop(1, vdr2); finished {
+		t.Fatalf("Shouldn't have finished the poll")
+	} else if _, finished := s.Drop(1, vdr3); !finished {
+		t.Fatalf("Should have finished the poll")
+	}
+
+	if count, sum := gatherHistogram(t, registerer, "poll_votes"); count != 2 {
+		t.Fatalf("Should have observed 2 polls, observed %d", count)
+	} else if sum != 4 {
+		t.Fatalf("Should have observed 4 votes, observed %f", sum)
+	}
+}
+
+func TestSetCancel(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	s := NewSet(NewNoEarlyTermFactory(), logging.NoLog{}, "", registerer)
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2} // k = 2
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1, vdr2)
+
+	if s.Cancel(0) {
+		t.Fatalf("Shouldn't have cancelled an unknown poll")
+	} else if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := s.Vote(0, vdr1, vtxID); finished {
+		t.Fatalf("Shouldn't have finished the poll")
+	} else if !s.Cancel(0) {
+		t.Fatalf("Should have cancelled the poll")
+	} else if s.Len() != 0 {
+		t.Fatalf("Should have removed the cancelled poll")
+	} else if _, finished := s.Vote(0, vdr2, vtxID); finished {
+		t.Fatalf("Should have ignored the vote for the cancelled poll")
+	} else if s.Cancel(0) {
+		t.Fatalf("Shouldn't have cancelled the poll twice")
+	}
+
+	if numPolls := gatherGauge(t, registerer, "polls"); numPolls != 0 {
+		t.Fatalf("Should have reported 0 outstanding polls, reported %f", numPolls)
+	} else if count, _ := gatherHistogram(t, registerer, "poll_duration"); count != 0 {
+		t.Fatalf("Shouldn't have reported the duration of a cancelled poll")
+	} else if cancelled := gatherCounter(t, registerer, "cancelled_polls"); cancelled != 1 {
+		t.Fatalf("Should have reported 1 cancelled poll, reported %f", cancelled)
+	}
+}
+
+func TestSetVoteMany(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	s := NewSet(NewNoEarlyTermFactory(), logging.NoLog{}, "", registerer)
+
+	vtxID1 := ids.ID{1}
+	vtxID2 := ids.ID{2}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+
+	newVdrs := func(vdrIDs ...ids.ShortID) ids.ShortBag {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(vdrIDs...)
+		return vdrs
+	}
+
+	if !s.Add(0, newVdrs(vdr1)) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if !s.Add(1, newVdrs(vdr1, vdr2)) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if !s.Add(2, newVdrs(vdr1, vdr2)) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := s.Vote(2, vdr2, vtxID1); finished {
+		t.Fatalf("Shouldn't have finished the poll")
+	}
+
+	results := s.VoteMany(vdr1, map[uint32]ids.ID{
+		0: vtxID1,
+		1: vtxID2,
+		2: vtxID2,
+		3: vtxID1, // unknown poll
+	})
+	if len(results) != 2 {
+		t.Fatalf("Should have finished 2 polls, finished %d", len(results))
+	} else if result, ok := results[0]; !ok || result.Len() != 1 || result.Count(vtxID1) != 1 {
+		t.Fatalf("Wrong result returned for poll 0")
+	} else if result, ok := results[2]; !ok || result.Len() != 2 || result.Count(vtxID1) != 1 || result.Count(vtxID2) != 1 {
+		t.Fatalf("Wrong result returned for poll 2")
+	} else if s.Len() != 1 {
+		t.Fatalf("Should have left the unfinished poll")
+	} else if result, finished := s.Vote(1, vdr2, vtxID2); !finished {
+		t.Fatalf("Should have finished the poll")
+	} else if result.Count(vtxID2) != 2 {
+		t.Fatalf("Should have registered the vote from VoteMany")
+	}
+
+	if numPolls := gatherGauge(t, registerer, "polls"); numPolls != 0 {
+		t.Fatalf("Should have reported 0 outstanding polls, reported %f", numPolls)
+	} else if count, _ := gatherHistogram(t, registerer, "poll_duration"); count != 3 {
+		t.Fatalf("Should have reported 3 poll durations, reported %d", count)
+	}
+}
+
+func TestSetOldestPollAge(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	s := NewSetWithConfig(SetConfig{
+		Factory:       NewNoEarlyTermFactory(),
+		Log:           logging.NoLog{},
+		Registerer:    registerer,
+		OldestPollAge: true,
+	})
+
+	now := time.Now()
+	s.(*set).clock.Set(now)
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1} // k = 1
+
+	newVdrs := func() ids.ShortBag {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(vdr1)
+		return vdrs
+	}
+
+	if age := gatherGauge(t, registerer, "oldest_poll_age_ms"); age != 0 {
+		t.Fatalf("Should have reported 0 with no outstanding polls, reported %f", age)
+	}
+
+	if !s.Add(0, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	}
+	s.(*set).clock.Set(now.Add(100 * time.Millisecond))
+	if !s.Add(1, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	}
+	s.(*set).clock.Set(now.Add(250 * time.Millisecond))
+
+	if age := gatherGauge(t, registerer, "oldest_poll_age_ms"); age != 250 {
+		t.Fatalf("Should have reported the age of poll 0, reported %f", age)
+	} else if _, finished := s.Vote(0, vdr1, vtxID); !finished {
+		t.Fatalf("Should have finished the poll")
+	} else if age := gatherGauge(t, registerer, "oldest_poll_age_ms"); age != 150 {
+		t.Fatalf("Should have reported the age of poll 1, reported %f", age)
+	} else if _, finished := s.Vote(1, vdr1, vtxID); !finished {
+		t.Fatalf("Should have finished the poll")
+	} else if age := gatherGauge(t, registerer, "oldest_poll_age_ms"); age != 0 {
+		t.Fatalf("Should have reported 0 with no outstanding polls, reported %f", age)
+	}
+}
+
+func TestSetOldestPollAgeAfterTransfer(t *testing.T) {
+	srcRegisterer := prometheus.NewRegistry()
+	src := NewSetWithConfig(SetConfig{
+		Factory:       NewNoEarlyTermFactory(),
+		Log:           logging.NoLog{},
+		Registerer:    srcRegisterer,
+		OldestPollAge: true,
+	})
+	dstRegisterer := prometheus.NewRegistry()
+	dst := NewSetWithConfig(SetConfig{
+		Factory:       NewNoEarlyTermFactory(),
+		Log:           logging.NoLog{},
+		Registerer:    dstRegisterer,
+		OldestPollAge: true,
+	})
+
+	now := time.Now()
+	src.(*set).clock.Set(now)
+	dst.(*set).clock.Set(now)
+
+	vdr1 := ids.ShortID{1} // k = 1
+
+	newVdrs := func() ids.ShortBag {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(vdr1)
+		return vdrs
+	}
+
+	if !src.Add(0, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	}
+	src.(*set).clock.Set(now.Add(100 * time.Millisecond))
+	dst.(*set).clock.Set(now.Add(100 * time.Millisecond))
+	if !src.Add(1, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if !dst.Add(2, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	}
+	src.(*set).clock.Set(now.Add(250 * time.Millisecond))
+	dst.(*set).clock.Set(now.Add(250 * time.Millisecond))
+
+	// The transferred polls keep their start times
+	if err := src.TransferTo(dst); err != nil {
+		t.Fatal(err)
+	} else if age := gatherGauge(t, srcRegisterer, "oldest_poll_age_ms"); age != 0 {
+		t.Fatalf("Should have reported 0 with no outstanding polls, reported %f", age)
+	} else if age := gatherGauge(t, dstRegisterer, "oldest_poll_age_ms"); age != 250 {
+		t.Fatalf("Should have reported the age of poll 0, reported %f", age)
+	} else if !dst.Cancel(0) {
+		t.Fatalf("Should have cancelled the poll")
+	} else if age := gatherGauge(t, dstRegisterer, "oldest_poll_age_ms"); age != 150 {
+		t.Fatalf("Should have reported the age of the next oldest poll, reported %f", age)
+	}
+}
+
+// failingUnregisterer fails to unregister [collector], but otherwise behaves
+// like the embedded registerer
+type failingUnregisterer struct {
+	prometheus.Registerer
+	collector prometheus.Collector
+}
+
+func (r *failingUnregisterer) Unregister(collector prometheus.Collector) bool {
+	if collector == r.collector {
+		return false
+	}
+	return r.Registerer.Unregister(collector)
+}
+
+func TestSetShutdownUnregistersAfterFailure(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	registerer := &failingUnregisterer{Registerer: registry}
+	s := NewSetWithConfig(SetConfig{
+		Factory:    NewNoEarlyTermFactory(),
+		Log:        logging.NoLog{},
+		Namespace:  "test",
+		Registerer: registerer,
+	})
+	registerer.collector = s.(*set).metrics[0]
+
+	err := s.Shutdown()
+	if !errors.Is(err, errFailedUnregister) {
+		t.Fatalf("Should have failed to unregister the metrics")
+	} else if !strings.Contains(err.Error(), "test_polls") {
+		t.Fatalf("Should have named the metric that failed to be unregistered, got: %s", err)
+	}
+
+	metrics, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(metrics) != 1 || metrics[0].GetName() != "test_polls" {
+		t.Fatalf("Should have unregistered every other metric, %d remain", len(metrics))
+	}
+}
+
+func TestSetWeightedFactory(t *testing.T) {
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+	vdr3 := ids.ShortID{3} // k = 3
+
+	s := NewSetWithConfig(SetConfig{
+		Factory:    NewWeightedEarlyTermFactory(60),
+		Log:        logging.NoLog{},
+		Registerer: prometheus.NewRegistry(),
+		Validators: validators.NewTestSet(map[ids.ShortID]uint64{
+			vdr1: 60,
+			vdr2: 20,
+			vdr3: 20,
+		}),
+	})
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1, vdr2, vdr3)
+
+	if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if result, finished := s.Vote(0, vdr1, vtxID); !finished {
+		t.Fatalf("Should have finished the poll once alpha weight was received")
+	} else if result.Count(vtxID) != 1 {
+		t.Fatalf("Wrong number of votes returned")
+	}
+}
+
+func TestSetTimeout(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	s := NewSet(NewNoEarlyTermFactory(), logging.NoLog{}, "", registerer)
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+	vdr3 := ids.ShortID{3} // k = 3
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1, vdr2, vdr3)
+
+	if _, timedOut := s.Timeout(0); timedOut {
+		t.Fatalf("Shouldn't have timed out an unknown poll")
+	} else if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := s.Vote(0, vdr1, vtxID); finished {
+		t.Fatalf("Shouldn't have finished the poll")
+	} else if result, timedOut := s.Timeout(0); !timedOut {
+		t.Fatalf("Should have timed out the poll")
+	} else if result.Count(vtxID) != 1 {
+		t.Fatalf("Should have finished with the votes received so far")
+	} else if s.Len() != 0 {
+		t.Fatalf("Should have removed the timed out poll")
+	} else if _, finished := s.Vote(0, vdr2, vtxID); finished {
+		t.Fatalf("Should have ignored the vote for the timed out poll")
+	} else if _, timedOut := s.Timeout(0); timedOut {
+		t.Fatalf("Shouldn't have timed out the poll twice")
+	}
+
+	if timedOut := gatherCounter(t, registerer, "poll_expired"); timedOut != 1 {
+		t.Fatalf("Should have reported 1 timed out poll, reported %f", timedOut)
+	} else if numPolls := gatherGauge(t, registerer, "polls"); numPolls != 0 {
+		t.Fatalf("Should have reported 0 outstanding polls, reported %f", numPolls)
+	} else if count, _ := gatherHistogram(t, registerer, "poll_duration"); count != 1 {
+		t.Fatalf("Should have reported 1 poll duration, reported %d", count)
+	}
+}
+
+func TestSetAge(t *testing.T) {
+	s := NewSet(NewNoEarlyTermFactory(), logging.NoLog{}, "", prometheus.NewRegistry())
+
+	now := time.Now()
+	s.(*set).clock.Set(now)
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(ids.ShortID{1})
+
+	if _, ok := s.Age(0); ok {
+		t.Fatalf("Shouldn't have reported the age of an unknown poll")
+	} else if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	}
+
+	s.(*set).clock.Set(now.Add(time.Second))
+	if age, ok := s.Age(0); !ok {
+		t.Fatalf("Should have reported the age of the poll")
+	} else if age != time.Second {
+		t.Fatalf("Wrong age reported: %s", age)
+	} else if _, timedOut := s.Timeout(0); !timedOut {
+		t.Fatalf("Should have timed out the poll")
+	} else if _, ok := s.Age(0); ok {
+		t.Fatalf("Shouldn't have reported the age of a finished poll")
+	}
+}
+
+func TestSetValidatorLatencies(t *testing.T) {
+	s := NewSetWithConfig(SetConfig{
+		Factory:         NewNoEarlyTermFactory(),
+		Log:             logging.NoLog{},
+		Registerer:      prometheus.NewRegistry(),
+		LatencyHalflife: time.Minute,
+	})
+
+	now := time.Now()
+	s.(*set).clock.Set(now)
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+	vdr3 := ids.ShortID{3} // k = 3
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1, vdr2, vdr3)
+
+	if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	}
+
+	s.(*set).clock.Set(now.Add(100 * time.Millisecond))
+	if _, finished := s.Vote(0, vdr1, vtxID); finished {
+		t.Fatalf("Shouldn't have finished the poll")
+	}
+
+	s.(*set).clock.Set(now.Add(200 * time.Millisecond))
+	if _, finished := s.Vote(0, vdr2, vtxID); finished {
+		t.Fatalf("Shouldn't have finished the poll")
+	} else if _, finished := s.Drop(0, vdr3); !finished {
+		t.Fatalf("Should have finished the poll")
+	}
+
+	latencies := s.ValidatorLatencies()
+	if len(latencies) != 2 {
+		t.Fatalf("Should have reported the latencies of the 2 validators that voted")
+	} else if latency := latencies[vdr1]; latency != 100*time.Millisecond {
+		t.Fatalf("Wrong latency reported for %s: %s", vdr1, latency)
+	} else if latency := latencies[vdr2]; latency != 200*time.Millisecond {
+		t.Fatalf("Wrong latency reported for %s: %s", vdr2, latency)
+	}
+}
+
+func TestSetValidatorLatenciesPrunedOnRemoval(t *testing.T) {
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2} // k = 2
+
+	vdrSet := validators.NewSet()
+	if err := vdrSet.AddWeight(vdr1, 1); err != nil {
+		t.Fatal(err)
+	} else if err := vdrSet.AddWeight(vdr2, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewSetWithConfig(SetConfig{
+		Factory:         NewNoEarlyTermFactory(),
+		Log:             logging.NoLog{},
+		Registerer:      prometheus.NewRegistry(),
+		Validators:      vdrSet,
+		LatencyHalflife: time.Minute,
+	})
+
+	vtxID := ids.ID{1}
+
+	newVdrs := func() ids.ShortBag {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(vdr1, vdr2)
+		return vdrs
+	}
+
+	if !s.Add(0, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := s.Vote(0, vdr1, vtxID); finished {
+		t.Fatalf("Shouldn't have finished the poll")
+	} else if _, finished := s.Vote(0, vdr2, vtxID); !finished {
+		t.Fatalf("Should have finished the poll")
+	} else if latencies := s.ValidatorLatencies(); len(latencies) != 2 {
+		t.Fatalf("Should have reported the latencies of the 2 validators that voted")
+	}
+
+	// Removing a validator forgets its latency
+	if err := vdrSet.RemoveWeight(vdr1, 1); err != nil {
+		t.Fatal(err)
+	} else if latencies := s.ValidatorLatencies(); len(latencies) != 1 {
+		t.Fatalf("Should have forgotten the latency of the removed validator")
+	} else if _, exists := latencies[vdr2]; !exists {
+		t.Fatalf("Should have kept the latency of %s", vdr2)
+	}
+
+	// A late vote from the removed validator isn't tracked
+	if !s.Add(1, newVdrs()) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := s.Vote(1, vdr1, vtxID); finished {
+		t.Fatalf("Shouldn't have finished the poll")
+	} else if latencies := s.ValidatorLatencies(); len(latencies) != 1 {
+		t.Fatalf("Shouldn't have tracked the latency of a removed validator")
+	}
+}
+
+func TestSetValidatorLatenciesPrunedOnDisconnect(t *testing.T) {
+	s := NewSetWithConfig(SetConfig{
+		Factory:         NewNoEarlyTermFactory(),
+		Log:             logging.NoLog{},
+		Registerer:      prometheus.NewRegistry(),
+		LatencyHalflife: time.Minute,
+	})
+
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2} // k = 2
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1, vdr2)
+
+	if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := s.Vote(0, vdr1, vtxID); finished {
+		t.Fatalf("Shouldn't have finished the poll")
+	} else if _, finished := s.Vote(0, vdr2, vtxID); !finished {
+		t.Fatalf("Should have finished the poll")
+	}
+
+	connected := ids.ShortSet{}
+	connected.Add(vdr2)
+	s.PruneDisconnected(connected)
+
+	if latencies := s.ValidatorLatencies(); len(latencies) != 1 {
+		t.Fatalf("Should have forgotten the latency of the disconnected validator")
+	} else if _, exists := latencies[vdr2]; !exists {
+		t.Fatalf("Should have kept the latency of %s", vdr2)
+	}
+}
+
+func TestSetValidatorLatenciesDisabled(t *testing.T) {
+	s := NewSet(NewNoEarlyTermFactory(), logging.NoLog{}, "", prometheus.NewRegistry())
+
+	vdr1 := ids.ShortID{1} // k = 1
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1)
+
+	if !s.Add(0, vdrs) {
+		t.Fatalf("Should have been able to add a new poll")
+	} else if _, finished := s.Vote(0, vdr1, ids.ID{1}); !finished {
+		t.Fatalf("Should have finished the poll")
+	} else if latencies := s.ValidatorLatencies(); len(latencies) != 0 {
+		t.Fatalf("Shouldn't have tracked latencies")
+	}
+}
@@ -0,0 +1,59 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package poll
+
+import (
+	"bytes"
+
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+type lowestIDTieBreaker struct{}
+
+// NewLowestIDTieBreaker returns a tie breaker that prefers the candidate with
+// the lowest byte representation
+func NewLowestIDTieBreaker() TieBreaker { return lowestIDTieBreaker{} }
+
+func (lowestIDTieBreaker) Break(candidates []ids.ID, _ ids.Bag) ids.ID {
+	lowest := candidates[0]
+	for _, candidate := range candidates[1:] {
+		if bytes.Compare(candidate[:], lowest[:]) < 0 {
+			lowest = candidate
+		}
+	}
+	return lowest
+}
+
+// breakTies returns a bag with the same counts as [votes]. If multiple IDs
+// share the highest count, [tieBreaker] is consulted and the chosen ID is
+// reported as the mode of the returned bag.
+func breakTies(tieBreaker TieBreaker, votes ids.Bag) ids.Bag {
+	if tieBreaker == nil {
+		return votes
+	}
+
+	_, freq := votes.Mode()
+	candidates := []ids.ID(nil)
+	for _, vote := range votes.List() {
+		if votes.Count(vote) == freq {
+			candidates = append(candidates, vote)
+		}
+	}
+	if len(candidates) < 2 {
+		return votes
+	}
+
+	winner := tieBreaker.Break(candidates, votes)
+
+	// The mode of a bag is the first ID to be seen the reported number of
+	// times, so the winner must be added first.
+	result := ids.Bag{}
+	result.AddCount(winner, freq)
+	for _, vote := range votes.List() {
+		if vote != winner {
+			result.AddCount(vote, votes.Count(vote))
+		}
+	}
+	return result
+}
@@ -0,0 +1,147 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package poll
+
+import (
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+type testTieBreaker struct {
+	calls      int
+	candidates []ids.ID
+	winner     ids.ID
+}
+
+func (tb *testTieBreaker) Break(candidates []ids.ID, _ ids.Bag) ids.ID {
+	tb.calls++
+	tb.candidates = candidates
+	return tb.winner
+}
+
+func TestLowestIDTieBreaker(t *testing.T) {
+	tieBreaker := NewLowestIDTieBreaker()
+
+	candidates := []ids.ID{{3}, {1, 2}, {2}, {1, 1}}
+	if winner := tieBreaker.Break(candidates, ids.Bag{}); winner != (ids.ID{1, 1}) {
+		t.Fatalf("Wrong ID chosen: %s", winner)
+	}
+}
+
+func TestTieBreakerConsultedOnTie(t *testing.T) {
+	vtxID1 := ids.ID{1}
+	vtxID2 := ids.ID{2}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2} // k = 2
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(
+		vdr1,
+		vdr2,
+	)
+
+	tieBreaker := &testTieBreaker{winner: vtxID2}
+	factory := NewNoEarlyTermFactoryWithTieBreaker(tieBreaker)
+	poll := factory.New(vdrs)
+
+	poll.Vote(vdr1, vtxID1)
+	poll.Vote(vdr2, vtxID2)
+	if !poll.Finished() {
+		t.Fatalf("Poll did not terminate after receiving k votes")
+	}
+
+	result := poll.Result()
+	if tieBreaker.calls != 1 {
+		t.Fatalf("Tie breaker should have been called once, was called %d times", tieBreaker.calls)
+	} else if len(tieBreaker.candidates) != 2 {
+		t.Fatalf("Tie breaker should have been given 2 candidates, was given %d", len(tieBreaker.candidates))
+	} else if mode, freq := result.Mode(); mode != vtxID2 {
+		t.Fatalf("Wrong mode returned: %s", mode)
+	} else if freq != 1 {
+		t.Fatalf("Wrong mode frequency returned: %d", freq)
+	} else if result.Count(vtxID1) != 1 {
+		t.Fatalf("Wrong number of votes returned")
+	} else if result.Count(vtxID2) != 1 {
+		t.Fatalf("Wrong number of votes returned")
+	}
+}
+
+func TestTieBreakerNotConsultedWithoutTie(t *testing.T) {
+	alpha := 2
+
+	vtxID1 := ids.ID{1}
+	vtxID2 := ids.ID{2}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+	vdr3 := ids.ShortID{3} // k = 3
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(
+		vdr1,
+		vdr2,
+		vdr3,
+	)
+
+	tieBreaker := &testTieBreaker{winner: vtxID2}
+	factory := NewEarlyTermNoTraversalFactoryWithTieBreaker(alpha, tieBreaker)
+	poll := factory.New(vdrs)
+
+	poll.Vote(vdr1, vtxID1)
+	poll.Vote(vdr2, vtxID2)
+	poll.Vote(vdr3, vtxID1)
+	if !poll.Finished() {
+		t.Fatalf("Poll did not terminate after receiving k votes")
+	}
+
+	result := poll.Result()
+	if tieBreaker.calls != 0 {
+		t.Fatalf("Tie breaker shouldn't have been called, was called %d times", tieBreaker.calls)
+	} else if mode, freq := result.Mode(); mode != vtxID1 {
+		t.Fatalf("Wrong mode returned: %s", mode)
+	} else if freq != 2 {
+		t.Fatalf("Wrong mode frequency returned: %d", freq)
+	}
+}
+
+func TestDefaultFactoriesDontBreakTies(t *testing.T) {
+	// vtxID2 has the higher byte representation, so it would lose a tie
+	// broken by the lowest ID
+	vtxID1 := ids.ID{1}
+	vtxID2 := ids.ID{2}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2} // k = 2
+
+	factories := []Factory{
+		NewNoEarlyTermFactory(),
+		NewEarlyTermNoTraversalFactory(2),
+		NewWeightedEarlyTermFactory(2),
+	}
+	for _, factory := range factories {
+		vdrs := ids.ShortBag{}
+		vdrs.Add(
+			vdr1,
+			vdr2,
+		)
+
+		poll := factory.New(vdrs)
+		poll.Vote(vdr1, vtxID2)
+		poll.Vote(vdr2, vtxID1)
+		if !poll.Finished() {
+			t.Fatalf("%s: Poll did not terminate after receiving k votes", factory)
+		}
+
+		// The mode reported by ids.Bag is the first ID to receive the most
+		// votes
+		result := poll.Result()
+		if mode, freq := result.Mode(); mode != vtxID2 {
+			t.Fatalf("%s: Wrong mode returned: %s", factory, mode)
+		} else if freq != 1 {
+			t.Fatalf("%s: Wrong mode frequency returned: %d", factory, freq)
+		}
+	}
+}
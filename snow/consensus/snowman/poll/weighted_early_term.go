@@ -0,0 +1,134 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package poll
+
+import (
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow/validators"
+)
+
+type weightedEarlyTermFactory struct {
+	alphaWeight uint64
+	tieBreaker  TieBreaker
+}
+
+// NewWeightedEarlyTermFactory returns a factory that returns polls with early
+// termination based on the weight of the validators that have responded,
+// rather than on the number of times they were sampled
+func NewWeightedEarlyTermFactory(alphaWeight uint64) WeightedFactory {
+	return &weightedEarlyTermFactory{alphaWeight: alphaWeight}
+}
+
+// NewWeightedEarlyTermFactoryWithTieBreaker returns a factory that returns
+// polls with early termination based on the weight of the validators that
+// have responded, whose results break ties with [tieBreaker]
+func NewWeightedEarlyTermFactoryWithTieBreaker(alphaWeight uint64, tieBreaker TieBreaker) WeightedFactory {
+	return &weightedEarlyTermFactory{
+		alphaWeight: alphaWeight,
+		tieBreaker:  tieBreaker,
+	}
+}
+
+// New returns a poll that weighs each validator by the number of times it was
+// sampled
+func (f *weightedEarlyTermFactory) New(vdrs ids.ShortBag) Poll {
+	return f.NewWeighted(vdrs, nil)
+}
+
+func (f *weightedEarlyTermFactory) NewWeighted(vdrs ids.ShortBag, weights validators.Set) Poll {
+	return &weightedEarlyTermPoll{
+		polled:      vdrs,
+		weights:     weights,
+		alphaWeight: f.alphaWeight,
+		votedWeight: make(map[ids.ID]uint64),
+		tieBreaker:  f.tieBreaker,
+	}
+}
+
+func (f *weightedEarlyTermFactory) String() string {
+	return fmt.Sprintf("WeightedEarlyTerm(AlphaWeight = %d)", f.alphaWeight)
+}
+
+// weightedEarlyTermPoll finishes when an ID has received alpha weight, or when
+// the remaining validators don't have enough weight for any ID to receive
+// alpha weight. The result is reported in the number of times the voting
+// validators were sampled, so it can be used with count based consensus
+// parameters.
+type weightedEarlyTermPoll struct {
+	votes  ids.Bag
+	polled ids.ShortBag
+
+	// weights, if non-nil, is used to look up the weight of the polled
+	// validators. Otherwise, validators are weighed by the number of times
+	// they were sampled.
+	weights validators.Set
+
+	alphaWeight uint64
+
+	// Each validator's weight is only counted once, so the weights can't
+	// overflow as the validator set's total weight fits in a uint64
+	votedWeight    map[ids.ID]uint64
+	receivedWeight uint64
+
+	tieBreaker TieBreaker
+}
+
+// weight returns the weight of [vdr], which must not have responded yet
+func (p *weightedEarlyTermPoll) weight(vdr ids.ShortID) uint64 {
+	if p.weights == nil {
+		return uint64(p.polled.Count(vdr))
+	}
+	weight, _ := p.weights.GetWeight(vdr)
+	return weight
+}
+
+// Vote registers a response for this poll
+func (p *weightedEarlyTermPoll) Vote(vdr ids.ShortID, vote ids.ID) {
+	count := p.polled.Count(vdr)
+	if count == 0 {
+		// make sure that a validator can't respond multiple times
+		return
+	}
+	weight := p.weight(vdr)
+	p.polled.Remove(vdr)
+
+	// track the votes the validator responded with
+	p.votes.AddCount(vote, count)
+	p.votedWeight[vote] += weight
+	p.receivedWeight += weight
+}
+
+// Drop any future response for this poll
+func (p *weightedEarlyTermPoll) Drop(vdr ids.ShortID) { p.polled.Remove(vdr) }
+
+// Finished returns true when all validators have voted, an ID has received
+// alpha weight, or alpha weight can no longer be received
+func (p *weightedEarlyTermPoll) Finished() bool {
+	if p.polled.Len() == 0 { // All k nodes responded
+		return true
+	}
+
+	for _, weight := range p.votedWeight {
+		if weight >= p.alphaWeight { // An alpha majority has returned
+			return true
+		}
+	}
+
+	remainingWeight := uint64(0)
+	for _, vdr := range p.polled.List() {
+		remainingWeight += p.weight(vdr)
+	}
+	return p.receivedWeight+remainingWeight < p.alphaWeight // An alpha majority can never return
+}
+
+// Result returns the result of this poll
+func (p *weightedEarlyTermPoll) Result() ids.Bag { return breakTies(p.tieBreaker, p.votes) }
+
+func (p *weightedEarlyTermPoll) PrefixedString(prefix string) string {
+	return fmt.Sprintf("waiting on %s", p.polled.PrefixedString(prefix))
+}
+
+func (p *weightedEarlyTermPoll) String() string { return p.PrefixedString("") }
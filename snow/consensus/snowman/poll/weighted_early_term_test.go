@@ -0,0 +1,164 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package poll
+
+import (
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow/validators"
+)
+
+func TestWeightedEarlyTermFinishesOnAlphaWeight(t *testing.T) {
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+	vdr3 := ids.ShortID{3} // k = 3
+
+	weights := validators.NewTestSet(map[ids.ShortID]uint64{
+		vdr1: 60,
+		vdr2: 20,
+		vdr3: 20,
+	})
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1, vdr2, vdr3)
+
+	factory := NewWeightedEarlyTermFactory(60)
+	poll := factory.NewWeighted(vdrs, weights)
+
+	// A single validator with alpha weight is enough to finish the poll
+	poll.Vote(vdr1, vtxID)
+	if !poll.Finished() {
+		t.Fatalf("Poll did not terminate after receiving alpha weight")
+	}
+
+	result := poll.Result()
+	if list := result.List(); len(list) != 1 {
+		t.Fatalf("Wrong number of vertices returned")
+	} else if retVtxID := list[0]; retVtxID != vtxID {
+		t.Fatalf("Wrong vertex returned")
+	} else if result.Count(vtxID) != 1 {
+		t.Fatalf("Wrong number of votes returned")
+	}
+}
+
+func TestWeightedEarlyTermWaitsForWeight(t *testing.T) {
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+	vdr3 := ids.ShortID{3} // k = 3
+
+	weights := validators.NewTestSet(map[ids.ShortID]uint64{
+		vdr1: 60,
+		vdr2: 20,
+		vdr3: 20,
+	})
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1, vdr2, vdr3)
+
+	factory := NewWeightedEarlyTermFactory(60)
+	poll := factory.NewWeighted(vdrs, weights)
+
+	// Two of the three validators have voted, but only with 40 weight
+	poll.Vote(vdr2, vtxID)
+	poll.Vote(vdr3, vtxID)
+	if poll.Finished() {
+		t.Fatalf("Poll terminated without alpha weight")
+	}
+
+	poll.Vote(vdr1, vtxID)
+	if !poll.Finished() {
+		t.Fatalf("Poll did not terminate after receiving alpha weight")
+	} else if result := poll.Result(); result.Count(vtxID) != 3 {
+		t.Fatalf("Wrong number of votes returned")
+	}
+}
+
+func TestWeightedEarlyTermTerminatesWithoutEnoughWeight(t *testing.T) {
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+	vdr3 := ids.ShortID{3} // k = 3
+
+	weights := validators.NewTestSet(map[ids.ShortID]uint64{
+		vdr1: 60,
+		vdr2: 20,
+		vdr3: 20,
+	})
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1, vdr2, vdr3)
+
+	factory := NewWeightedEarlyTermFactory(60)
+	poll := factory.NewWeighted(vdrs, weights)
+
+	poll.Vote(vdr2, vtxID)
+	if poll.Finished() {
+		t.Fatalf("Poll terminated while alpha weight could still be received")
+	}
+
+	// Without vdr1, only 40 weight can be received
+	poll.Drop(vdr1)
+	if !poll.Finished() {
+		t.Fatalf("Poll did not terminate once alpha weight couldn't be received")
+	}
+}
+
+func TestWeightedEarlyTermWithoutWeights(t *testing.T) {
+	vtxID := ids.ID{1}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2} // k = 3
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1, vdr1, vdr2)
+
+	factory := NewWeightedEarlyTermFactory(2)
+	poll := factory.New(vdrs)
+
+	// Without weights, validators are weighed by the number of times they
+	// were sampled
+	poll.Vote(vdr1, vtxID)
+	poll.Vote(vdr1, vtxID)
+	if !poll.Finished() {
+		t.Fatalf("Poll did not terminate after receiving alpha votes")
+	} else if result := poll.Result(); result.Count(vtxID) != 2 {
+		t.Fatalf("Wrong number of votes returned")
+	}
+}
+
+func TestWeightedEarlyTermWithTieBreaker(t *testing.T) {
+	vtxID1 := ids.ID{1}
+	vtxID2 := ids.ID{2}
+
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2} // k = 2
+
+	vdrs := ids.ShortBag{}
+	vdrs.Add(vdr1, vdr2)
+
+	tieBreaker := &testTieBreaker{winner: vtxID1}
+	factory := NewWeightedEarlyTermFactoryWithTieBreaker(2, tieBreaker)
+	poll := factory.New(vdrs)
+
+	poll.Vote(vdr1, vtxID2)
+	poll.Vote(vdr2, vtxID1)
+	if !poll.Finished() {
+		t.Fatalf("Poll did not terminate after receiving k votes")
+	}
+
+	result := poll.Result()
+	if tieBreaker.calls != 1 {
+		t.Fatalf("Tie breaker should have been called once, was called %d times", tieBreaker.calls)
+	} else if mode, freq := result.Mode(); mode != vtxID1 {
+		t.Fatalf("Wrong mode returned: %s", mode)
+	} else if freq != 1 {
+		t.Fatalf("Wrong mode frequency returned: %d", freq)
+	}
+}
@@ -27,6 +27,11 @@ const (
 	// TODO define this constant in one place rather than here and in snowman
 	// Max containers size in a MultiPut message
 	maxContainersLen = int(4 * network.DefaultMaxMessageSize / 5)
+
+	// maxPollAge is the duration after which an outstanding poll is expired.
+	// Every query is answered or fails well within this duration, so only
+	// polls whose responses were lost are expired.
+	maxPollAge = time.Minute
 )
 
 // Transitive implements the Engine interface by attempting to fetch all
@@ -40,6 +45,9 @@ type Transitive struct {
 
 	polls poll.Set // track people I have asked for their preference
 
+	// queryTimeout is the longest a query can take to time out
+	queryTimeout time.Duration
+
 	// The set of vertices that have been requested in Get messages but not yet received
 	outstandingVtxReqs common.Requests
 
@@ -68,13 +76,21 @@ func (t *Transitive) Initialize(config Config) error {
 
 	t.Params = config.Params
 	t.Consensus = config.Consensus
+	t.queryTimeout = config.QueryTimeout
 
 	factory := poll.NewEarlyTermNoTraversalFactory(config.Params.Alpha)
-	t.polls = poll.NewSet(factory,
-		config.Ctx.Log,
-		config.Params.Namespace,
-		config.Params.Metrics,
-	)
+	t.polls = poll.NewSetWithConfig(poll.SetConfig{
+		Factory:         factory,
+		Log:             config.Ctx.Log,
+		Namespace:       config.Params.Namespace,
+		Registerer:      config.Params.Metrics,
+		Aggregator:      config.PollAggregator,
+		SharedDurations: config.PollDurations,
+		Chain:           config.Ctx.ChainID.String(),
+		SharedOutcomes:  config.PollOutcomes,
+		SharedResponses: config.PollResponses,
+		MaxPollAge:      maxPollAge,
+	})
 
 	if err := t.metrics.Initialize(config.Params.Namespace, config.Params.Metrics); err != nil {
 		return err
@@ -135,7 +151,12 @@ func (t *Transitive) Gossip() error {
 // Shutdown implements the Engine interface
 func (t *Transitive) Shutdown() error {
 	t.Ctx.Log.Info("shutting down consensus engine")
-	return t.VM.Shutdown()
+	errs := wrappers.Errs{}
+	errs.Add(
+		t.polls.Shutdown(),
+		t.VM.Shutdown(),
+	)
+	return errs.Err
 }
 
 // Get implements the Engine interface
@@ -332,7 +353,33 @@ func (t *Transitive) Chits(vdr ids.ShortID, requestID uint32, votes []ids.ID) er
 
 // QueryFailed implements the Engine interface
 func (t *Transitive) QueryFailed(vdr ids.ShortID, requestID uint32) error {
-	return t.Chits(vdr, requestID, nil)
+	if !t.Ctx.IsBootstrapped() {
+		t.Ctx.Log.Debug("dropping QueryFailed(%s, %d) due to bootstrapping", vdr, requestID)
+		return nil
+	}
+
+	t.vtxBlocked.Register(&voter{
+		t:         t,
+		vdr:       vdr,
+		requestID: requestID,
+		failed:    true,
+	})
+	return t.attemptToIssueTxs()
+}
+
+// timeoutPoll times out the poll with [requestID] if it has been outstanding
+// for at least the query timeout, in which case every query of the poll has
+// either been answered or timed out
+func (t *Transitive) timeoutPoll(requestID uint32) (ids.UniqueBag, bool) {
+	if t.queryTimeout <= 0 {
+		return nil, false
+	}
+	age, ok := t.polls.Age(requestID)
+	if !ok || age < t.queryTimeout {
+		return nil, false
+	}
+	t.Ctx.Log.Debug("timing out poll [%d] after %s", requestID, age)
+	return t.polls.Timeout(requestID)
 }
 
 // Notify implements the Engine interface
@@ -632,6 +679,19 @@ func (t *Transitive) sendRequest(vdr ids.ShortID, vtxID ids.ID) {
 	t.numVtxRequests.Set(float64(t.outstandingVtxReqs.Len())) // Tracks performance statistics
 }
 
+// ConsensusState implements the common.Debuggable interface
+func (t *Transitive) ConsensusState() map[string]interface{} {
+	state := map[string]interface{}{
+		"bootstrapped": t.Ctx.IsBootstrapped(),
+		"polls":        t.polls,
+	}
+	if t.Ctx.IsBootstrapped() {
+		state["preferences"] = t.Consensus.Preferences().List()
+		state["numProcessing"] = t.Consensus.NumProcessing()
+	}
+	return state
+}
+
 // Health implements the common.Engine interface
 func (t *Transitive) HealthCheck() (interface{}, error) {
 	var (
@@ -643,8 +703,9 @@ func (t *Transitive) HealthCheck() (interface{}, error) {
 	}
 	vmIntf, vmErr := t.VM.HealthCheck()
 	intf := map[string]interface{}{
-		"consensus": consensusIntf,
-		"vm":        vmIntf,
+		"consensus":        consensusIntf,
+		"vm":               vmIntf,
+		"outstandingPolls": t.polls.Len(),
 	}
 	if consensusErr == nil {
 		return intf, vmErr
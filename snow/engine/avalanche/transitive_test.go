@@ -7,6 +7,7 @@ import (
 	"bytes"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -592,6 +593,140 @@ func TestEngineMultipleQuery(t *testing.T) {
 	}
 }
 
+func TestEngineQueryFailedTimesOutPoll(t *testing.T) {
+	config := DefaultConfig()
+	// Every query has timed out once it has been outstanding for a nanosecond
+	config.QueryTimeout = time.Nanosecond
+
+	config.Params = avalanche.Parameters{
+		Parameters: snowball.Parameters{
+			Metrics:               prometheus.NewRegistry(),
+			K:                     3,
+			Alpha:                 2,
+			BetaVirtuous:          1,
+			BetaRogue:             2,
+			ConcurrentRepolls:     1,
+			OptimalProcessing:     100,
+			MaxOutstandingItems:   1,
+			MaxItemProcessingTime: 1,
+		},
+		Parents:   2,
+		BatchSize: 1,
+	}
+
+	vals := validators.NewSet()
+	config.Validators = vals
+
+	vdr0 := ids.GenerateTestShortID()
+	vdr1 := ids.GenerateTestShortID()
+	vdr2 := ids.GenerateTestShortID()
+
+	errs := wrappers.Errs{}
+	errs.Add(
+		vals.AddWeight(vdr0, 1),
+		vals.AddWeight(vdr1, 1),
+		vals.AddWeight(vdr2, 1),
+	)
+	if errs.Errored() {
+		t.Fatal(errs.Err)
+	}
+
+	sender := &common.SenderTest{}
+	sender.T = t
+	config.Sender = sender
+
+	sender.Default(true)
+	sender.CantGetAcceptedFrontier = false
+
+	manager := vertex.NewTestManager(t)
+	config.Manager = manager
+
+	gVtx := &avalanche.TestVertex{TestDecidable: choices.TestDecidable{
+		IDV:     ids.GenerateTestID(),
+		StatusV: choices.Accepted,
+	}}
+	mVtx := &avalanche.TestVertex{TestDecidable: choices.TestDecidable{
+		IDV:     ids.GenerateTestID(),
+		StatusV: choices.Accepted,
+	}}
+
+	vts := []avalanche.Vertex{gVtx, mVtx}
+	utxos := []ids.ID{ids.GenerateTestID()}
+
+	manager.EdgeF = func() []ids.ID { return []ids.ID{vts[0].ID(), vts[1].ID()} }
+	manager.GetF = func(id ids.ID) (avalanche.Vertex, error) {
+		switch id {
+		case gVtx.ID():
+			return gVtx, nil
+		case mVtx.ID():
+			return mVtx, nil
+		}
+		t.Fatalf("Unknown vertex")
+		panic("Should have errored")
+	}
+
+	tx0 := &snowstorm.TestTx{TestDecidable: choices.TestDecidable{
+		IDV:     ids.GenerateTestID(),
+		StatusV: choices.Processing,
+	}}
+	tx0.InputIDsV = append(tx0.InputIDsV, utxos[0])
+
+	vtx0 := &avalanche.TestVertex{
+		TestDecidable: choices.TestDecidable{
+			IDV:     ids.GenerateTestID(),
+			StatusV: choices.Processing,
+		},
+		ParentsV: vts,
+		HeightV:  1,
+		TxsV:     []snowstorm.Tx{tx0},
+	}
+
+	te := &Transitive{}
+	if err := te.Initialize(config); err != nil {
+		t.Fatal(err)
+	}
+
+	queried := new(bool)
+	queryRequestID := new(uint32)
+	sender.PushQueryF = func(inVdrs ids.ShortSet, requestID uint32, vtxID ids.ID, vtx []byte) {
+		if *queried {
+			t.Fatalf("Asked multiple times")
+		}
+		*queried = true
+		*queryRequestID = requestID
+		vdrSet := ids.ShortSet{}
+		vdrSet.Add(vdr0, vdr1, vdr2)
+		if !inVdrs.Equals(vdrSet) {
+			t.Fatalf("Asking wrong validator for preference")
+		}
+		if vtx0.ID() != vtxID {
+			t.Fatalf("Asking for wrong vertex")
+		}
+	}
+
+	if err := te.issue(vtx0); err != nil {
+		t.Fatal(err)
+	}
+
+	// Polls time out once any query times out, as every other query of the
+	// poll has also timed out
+	time.Sleep(time.Millisecond)
+	repolled := new(bool)
+	sender.PullQueryF = func(inVdrs ids.ShortSet, requestID uint32, vtxID ids.ID) {
+		*repolled = true
+	}
+	if err := te.QueryFailed(vdr0, *queryRequestID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := te.polls.Age(*queryRequestID); ok {
+		t.Fatalf("Should have timed out the poll")
+	}
+	if !*repolled {
+		t.Fatalf("Should have repolled the network")
+	}
+}
+
 func TestEngineBlockedIssue(t *testing.T) {
 	config := DefaultConfig()
 
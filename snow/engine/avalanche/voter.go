@@ -16,6 +16,9 @@ type voter struct {
 	requestID uint32
 	response  []ids.ID
 	deps      ids.Set
+
+	// failed is true if the query to [vdr] failed
+	failed bool
 }
 
 func (v *voter) Dependencies() ids.Set { return v.deps }
@@ -35,6 +38,9 @@ func (v *voter) Update() {
 	}
 
 	results, finished := v.t.polls.Vote(v.requestID, v.vdr, v.response)
+	if !finished && v.failed {
+		results, finished = v.t.timeoutPoll(v.requestID)
+	}
 	if !finished {
 		return
 	}
@@ -0,0 +1,12 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package common
+
+// Debuggable defines the functionality an engine may support to report its
+// consensus state for debugging
+type Debuggable interface {
+	// Returns a summary of the engine's consensus state. The summary must be
+	// serializable to JSON. Assumes the context lock is held.
+	ConsensusState() map[string]interface{}
+}
@@ -4,9 +4,15 @@
 package snowman
 
 import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
 	"github.com/ava-labs/avalanchego/snow/consensus/snowball"
 	"github.com/ava-labs/avalanchego/snow/consensus/snowman"
 	"github.com/ava-labs/avalanchego/snow/engine/snowman/bootstrap"
+
+	conmetrics "github.com/ava-labs/avalanchego/snow/consensus/metrics"
 )
 
 // Config wraps all the parameters needed for a snowman engine
@@ -15,4 +21,21 @@ type Config struct {
 
 	Params    snowball.Parameters
 	Consensus snowman.Consensus
+
+	// PollDurations, PollOutcomes, and PollResponses, if non-nil, are the
+	// poll statistics shared with the engines of the other chains in the
+	// process. Otherwise, the engine registers its own poll statistics.
+	PollDurations *prometheus.HistogramVec
+	PollOutcomes  *prometheus.CounterVec
+	PollResponses *prometheus.CounterVec
+
+	// PollAggregator, if non-nil, reports the engine's pending polls along
+	// with those of the other chains in the process
+	PollAggregator conmetrics.PollAggregator
+
+	// QueryTimeout is the longest a query can take to time out. A query that
+	// fails after its poll has been outstanding for QueryTimeout timed out,
+	// as did every other query of the poll, so the poll is timed out rather
+	// than waiting for the remaining failures. If 0, polls aren't timed out.
+	QueryTimeout time.Duration
 }
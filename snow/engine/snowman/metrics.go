@@ -6,12 +6,14 @@ package snowman
 import (
 	"github.com/prometheus/client_golang/prometheus"
 
+	"github.com/ava-labs/avalanchego/utils/timer"
 	"github.com/ava-labs/avalanchego/utils/wrappers"
 )
 
 type metrics struct {
 	numRequests, numBlocked prometheus.Gauge
 	getAncestorsBlks        prometheus.Histogram
+	verifyBlks              prometheus.Histogram
 }
 
 // Initialize the metrics
@@ -43,11 +45,19 @@ func (m *metrics) Initialize(namespace string, registerer prometheus.Registerer)
 		},
 	})
 
+	m.verifyBlks = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "block_verification",
+		Help:      "Length of time verifying a block took in milliseconds",
+		Buckets:   timer.MillisecondsBuckets,
+	})
+
 	errs := wrappers.Errs{}
 	errs.Add(
 		registerer.Register(m.numRequests),
 		registerer.Register(m.numBlocked),
 		registerer.Register(m.getAncestorsBlks),
+		registerer.Register(m.verifyBlks),
 	)
 	return errs.Err
 }
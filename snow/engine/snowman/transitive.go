@@ -25,6 +25,11 @@ const (
 	// TODO define this constant in one place rather than here and in snowman
 	// Max containers size in a MultiPut message
 	maxContainersLen = int(4 * network.DefaultMaxMessageSize / 5)
+
+	// maxPollAge is the duration after which an outstanding poll is expired.
+	// Every query is answered or fails well within this duration, so only
+	// polls whose responses were lost are expired.
+	maxPollAge = time.Minute
 )
 
 // Transitive implements the Engine interface by attempting to fetch all
@@ -39,6 +44,9 @@ type Transitive struct {
 	// track outstanding preference requests
 	polls poll.Set
 
+	// queryTimeout is the longest a query can take to time out
+	queryTimeout time.Duration
+
 	// blocks that have we have sent get requests for but haven't yet received
 	blkReqs common.Requests
 
@@ -63,13 +71,22 @@ func (t *Transitive) Initialize(config Config) error {
 
 	t.Params = config.Params
 	t.Consensus = config.Consensus
+	t.queryTimeout = config.QueryTimeout
 
 	factory := poll.NewEarlyTermNoTraversalFactory(config.Params.Alpha)
-	t.polls = poll.NewSet(factory,
-		config.Ctx.Log,
-		config.Params.Namespace,
-		config.Params.Metrics,
-	)
+	t.polls = poll.NewSetWithConfig(poll.SetConfig{
+		Factory:         factory,
+		Log:             config.Ctx.Log,
+		Namespace:       config.Params.Namespace,
+		Registerer:      config.Params.Metrics,
+		Aggregator:      config.PollAggregator,
+		SharedDurations: config.PollDurations,
+		Chain:           config.Ctx.ChainID.String(),
+		SharedOutcomes:  config.PollOutcomes,
+		SharedResponses: config.PollResponses,
+		MaxPollAge:      maxPollAge,
+		OldestPollAge:   true,
+	})
 
 	if err := t.metrics.Initialize(config.Params.Namespace, config.Params.Metrics); err != nil {
 		return err
@@ -146,7 +163,12 @@ func (t *Transitive) Gossip() error {
 // Shutdown implements the Engine interface
 func (t *Transitive) Shutdown() error {
 	t.Ctx.Log.Info("shutting down consensus engine")
-	return t.VM.Shutdown()
+	errs := wrappers.Errs{}
+	errs.Add(
+		t.polls.Shutdown(),
+		t.VM.Shutdown(),
+	)
+	return errs.Err
 }
 
 // Get implements the Engine interface
@@ -374,6 +396,21 @@ func (t *Transitive) QueryFailed(vdr ids.ShortID, requestID uint32) error {
 	return t.buildBlocks()
 }
 
+// timeoutPoll times out the poll with [requestID] if it has been outstanding
+// for at least the query timeout, in which case every query of the poll has
+// either been answered or timed out
+func (t *Transitive) timeoutPoll(requestID uint32) (ids.Bag, bool) {
+	if t.queryTimeout <= 0 {
+		return ids.Bag{}, false
+	}
+	age, ok := t.polls.Age(requestID)
+	if !ok || age < t.queryTimeout {
+		return ids.Bag{}, false
+	}
+	t.Ctx.Log.Debug("timing out poll [%d] after %s", requestID, age)
+	return t.polls.Timeout(requestID)
+}
+
 // Notify implements the Engine interface
 func (t *Transitive) Notify(msg common.Message) error {
 	// if the engine hasn't been bootstrapped, we shouldn't build/issue blocks from the VM
@@ -644,7 +681,7 @@ func (t *Transitive) deliver(blk snowman.Block) error {
 	// calling Verify on this block is allowed.
 
 	// make sure this block is valid
-	if err := blk.Verify(); err != nil {
+	if err := t.verify(blk); err != nil {
 		t.Ctx.Log.Debug("block failed verification due to %s, dropping block", err)
 
 		// if verify fails, then all descendants are also invalid
@@ -669,7 +706,7 @@ func (t *Transitive) deliver(blk snowman.Block) error {
 			return err
 		}
 		for _, blk := range options {
-			if err := blk.Verify(); err != nil {
+			if err := t.verify(blk); err != nil {
 				t.Ctx.Log.Debug("block failed verification due to %s, dropping block", err)
 				dropped = append(dropped, blk)
 			} else {
@@ -718,11 +755,32 @@ func (t *Transitive) deliver(blk snowman.Block) error {
 	return t.errs.Err
 }
 
+// verify verifies [blk], reporting how long verification took
+func (t *Transitive) verify(blk snowman.Block) error {
+	startTime := time.Now()
+	err := blk.Verify()
+	t.verifyBlks.Observe(float64(time.Since(startTime).Milliseconds()))
+	return err
+}
+
 // IsBootstrapped returns true iff this chain is done bootstrapping
 func (t *Transitive) IsBootstrapped() bool {
 	return t.Ctx.IsBootstrapped()
 }
 
+// ConsensusState implements the common.Debuggable interface
+func (t *Transitive) ConsensusState() map[string]interface{} {
+	state := map[string]interface{}{
+		"bootstrapped": t.Ctx.IsBootstrapped(),
+		"polls":        t.polls,
+	}
+	if t.Ctx.IsBootstrapped() {
+		state["preference"] = t.Consensus.Preference()
+		state["numProcessing"] = t.Consensus.NumProcessing()
+	}
+	return state
+}
+
 // Health implements the common.Engine interface
 func (t *Transitive) HealthCheck() (interface{}, error) {
 	var (
@@ -734,8 +792,9 @@ func (t *Transitive) HealthCheck() (interface{}, error) {
 	}
 	vmIntf, vmErr := t.VM.HealthCheck()
 	intf := map[string]interface{}{
-		"consensus": consensusIntf,
-		"vm":        vmIntf,
+		"consensus":        consensusIntf,
+		"vm":               vmIntf,
+		"outstandingPolls": t.polls.Len(),
 	}
 	if consensusErr == nil {
 		return intf, vmErr
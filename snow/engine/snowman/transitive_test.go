@@ -5,8 +5,10 @@ package snowman
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 
@@ -97,6 +99,71 @@ func TestEngineShutdown(t *testing.T) {
 	}
 }
 
+func TestEngineConsensusState(t *testing.T) {
+	vdr, _, sender, vm, te, gBlk := setup(t)
+
+	state := te.ConsensusState()
+	if bootstrapped, _ := state["bootstrapped"].(bool); !bootstrapped {
+		t.Fatalf("Should have reported being bootstrapped")
+	} else if preference, _ := state["preference"].(ids.ID); preference != gBlk.ID() {
+		t.Fatalf("Should have reported the genesis block as preferred, reported %v", state["preference"])
+	} else if numProcessing, _ := state["numProcessing"].(int); numProcessing != 0 {
+		t.Fatalf("Shouldn't have reported any processing blocks, reported %v", state["numProcessing"])
+	}
+
+	blk := &snowman.TestBlock{
+		TestDecidable: choices.TestDecidable{
+			IDV:     ids.GenerateTestID(),
+			StatusV: choices.Processing,
+		},
+		ParentV: gBlk,
+		HeightV: 1,
+		BytesV:  []byte{1},
+	}
+
+	queryRequestID := new(uint32)
+	sender.PushQueryF = func(_ ids.ShortSet, requestID uint32, _ ids.ID, _ []byte) {
+		*queryRequestID = requestID
+	}
+	vm.BuildBlockF = func() (snowman.Block, error) { return blk, nil }
+	if err := te.Notify(common.PendingTxs); err != nil {
+		t.Fatal(err)
+	}
+
+	stateBytes, err := json.Marshal(te.ConsensusState())
+	if err != nil {
+		t.Fatalf("Should have been able to marshal the state: %s", err)
+	}
+	reported := struct {
+		NumProcessing int `json:"numProcessing"`
+		Polls         struct {
+			Pending int `json:"pending"`
+			Polls   []struct {
+				RequestID  uint32   `json:"requestID"`
+				Validators []string `json:"validators"`
+				Responded  []string `json:"responded"`
+			} `json:"polls"`
+		} `json:"polls"`
+	}{}
+	if err := json.Unmarshal(stateBytes, &reported); err != nil {
+		t.Fatal(err)
+	}
+
+	if reported.NumProcessing != 1 {
+		t.Fatalf("Should have reported one processing block, reported %d", reported.NumProcessing)
+	} else if reported.Polls.Pending != 1 {
+		t.Fatalf("Should have reported one pending poll, reported %d", reported.Polls.Pending)
+	} else if len(reported.Polls.Polls) != 1 {
+		t.Fatalf("Should have reported one poll, reported %d", len(reported.Polls.Polls))
+	} else if p := reported.Polls.Polls[0]; p.RequestID != *queryRequestID {
+		t.Fatalf("Should have reported requestID %d, reported %d", *queryRequestID, p.RequestID)
+	} else if len(p.Validators) != 1 || p.Validators[0] != vdr.String() {
+		t.Fatalf("Should have reported %s as polled, reported %v", vdr, p.Validators)
+	} else if len(p.Responded) != 0 {
+		t.Fatalf("Shouldn't have reported any responses, reported %v", p.Responded)
+	}
+}
+
 func TestEngineAdd(t *testing.T) {
 	vdr, _, sender, vm, te, _ := setup(t)
 
@@ -945,6 +1012,136 @@ func TestVoteCanceling(t *testing.T) {
 	}
 }
 
+func TestQueryFailedTimesOutPoll(t *testing.T) {
+	config := DefaultConfig()
+	// Every query has timed out once it has been outstanding for a nanosecond
+	config.QueryTimeout = time.Nanosecond
+
+	config.Params = snowball.Parameters{
+		Metrics:               prometheus.NewRegistry(),
+		K:                     3,
+		Alpha:                 2,
+		BetaVirtuous:          1,
+		BetaRogue:             2,
+		ConcurrentRepolls:     1,
+		OptimalProcessing:     1,
+		MaxOutstandingItems:   1,
+		MaxItemProcessingTime: 1,
+	}
+
+	vals := validators.NewSet()
+	config.Validators = vals
+
+	vdr0 := ids.GenerateTestShortID()
+	vdr1 := ids.GenerateTestShortID()
+	vdr2 := ids.GenerateTestShortID()
+
+	errs := wrappers.Errs{}
+	errs.Add(
+		vals.AddWeight(vdr0, 1),
+		vals.AddWeight(vdr1, 1),
+		vals.AddWeight(vdr2, 1),
+	)
+	if errs.Errored() {
+		t.Fatal(errs.Err)
+	}
+
+	sender := &common.SenderTest{}
+	sender.T = t
+	config.Sender = sender
+
+	sender.Default(true)
+
+	vm := &block.TestVM{}
+	vm.T = t
+	config.VM = vm
+
+	vm.Default(true)
+	vm.CantSetPreference = false
+
+	gBlk := &snowman.TestBlock{TestDecidable: choices.TestDecidable{
+		IDV:     ids.GenerateTestID(),
+		StatusV: choices.Accepted,
+	}}
+
+	vm.LastAcceptedF = func() (ids.ID, error) { return gBlk.ID(), nil }
+	vm.GetBlockF = func(id ids.ID) (snowman.Block, error) {
+		switch id {
+		case gBlk.ID():
+			return gBlk, nil
+		default:
+			t.Fatalf("Loaded unknown block")
+			panic("Should have failed")
+		}
+	}
+	sender.CantGetAcceptedFrontier = false
+
+	vm.CantBootstrapping = false
+	vm.CantBootstrapped = false
+
+	te := &Transitive{}
+	if err := te.Initialize(config); err != nil {
+		t.Fatal(err)
+	}
+
+	vm.CantBootstrapping = false
+	vm.CantBootstrapped = false
+
+	vm.LastAcceptedF = nil
+	sender.CantGetAcceptedFrontier = true
+
+	blk := &snowman.TestBlock{
+		TestDecidable: choices.TestDecidable{
+			IDV:     ids.GenerateTestID(),
+			StatusV: choices.Processing,
+		},
+		ParentV: gBlk,
+		HeightV: 1,
+		BytesV:  []byte{1},
+	}
+
+	queried := new(bool)
+	queryRequestID := new(uint32)
+	sender.PushQueryF = func(inVdrs ids.ShortSet, requestID uint32, blkID ids.ID, blkBytes []byte) {
+		if *queried {
+			t.Fatalf("Asked multiple times")
+		}
+		*queried = true
+		*queryRequestID = requestID
+		vdrSet := ids.ShortSet{}
+		vdrSet.Add(vdr0, vdr1, vdr2)
+		if !inVdrs.Equals(vdrSet) {
+			t.Fatalf("Asking wrong validator for preference")
+		}
+		if blk.ID() != blkID {
+			t.Fatalf("Asking for wrong block")
+		}
+	}
+
+	if err := te.issue(blk); err != nil {
+		t.Fatal(err)
+	}
+
+	if te.polls.Len() != 1 {
+		t.Fatalf("Shouldn't have finished blocking issue")
+	}
+
+	// Polls time out once any query times out, as every other query of the
+	// poll has also timed out
+	time.Sleep(time.Millisecond)
+	repolled := new(bool)
+	sender.PullQueryF = func(inVdrs ids.ShortSet, requestID uint32, blkID ids.ID) {
+		*repolled = true
+	}
+	if err := te.QueryFailed(vdr0, *queryRequestID); err != nil {
+		t.Fatal(err)
+	}
+
+	if !*repolled {
+		t.Fatalf("Should have timed out the poll and repolled the network")
+	}
+}
+
 func TestEngineNoQuery(t *testing.T) {
 	config := DefaultConfig()
 
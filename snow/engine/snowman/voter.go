@@ -36,6 +36,9 @@ func (v *voter) Update() {
 	finished := false
 	if v.response == ids.Empty {
 		results, finished = v.t.polls.Drop(v.requestID, v.vdr)
+		if !finished {
+			results, finished = v.t.timeoutPoll(v.requestID)
+		}
 	} else {
 		results, finished = v.t.polls.Vote(v.requestID, v.vdr, v.response)
 	}
@@ -35,6 +35,10 @@ type Benchlist interface {
 	// IsBenched returns true if messages to [validatorID]
 	// should not be sent over the network and should immediately fail.
 	IsBenched(validatorID ids.ShortID) bool
+	// Benched returns the IDs of the validators that are currently benched
+	Benched() []ids.ShortID
+	// Shutdown stops the benchlist from removing validators from the bench
+	Shutdown()
 }
 
 // Data about a validator who is benched
@@ -146,6 +150,9 @@ func NewBenchlist(
 	return benchlist, benchlist.metrics.Initialize(registerer, namespace)
 }
 
+// Shutdown implements the Benchlist interface
+func (b *benchlist) Shutdown() { b.timer.Stop() }
+
 // Update removes benched validators whose time on the bench is over
 func (b *benchlist) update() {
 	b.lock.Lock()
@@ -220,6 +227,13 @@ func (b *benchlist) IsBenched(validatorID ids.ShortID) bool {
 	return b.isBenched(validatorID)
 }
 
+// Benched returns the IDs of the validators that are currently benched
+func (b *benchlist) Benched() []ids.ShortID {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	return b.benchlistSet.List()
+}
+
 // isBenched checks if [validatorID] is currently benched
 // and calls cleanup if its benching period has elapsed
 // Assumes [b.lock] is held.
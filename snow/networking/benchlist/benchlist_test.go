@@ -69,6 +69,7 @@ func TestBenchlistAdd(t *testing.T) {
 	assert.Equal(t, b.benchedQueue.Len(), 0)
 	assert.Equal(t, b.benchlistSet.Len(), 0)
 	b.lock.Unlock()
+	assert.Empty(t, b.Benched())
 
 	// Register [threshold - 1] failures in a row for vdr0
 	for i := 0; i < threshold-1; i++ {
@@ -116,6 +117,7 @@ func TestBenchlistAdd(t *testing.T) {
 	assert.True(t, !next.benchedUntil.Before(now.Add(duration/2)))
 	assert.Len(t, b.failureStreaks, 0)
 	b.lock.Unlock()
+	assert.Equal(t, []ids.ShortID{vdr0.ID()}, b.Benched())
 
 	// Give another validator [threshold-1] failures
 	for i := 0; i < threshold-1; i++ {
@@ -27,6 +27,8 @@ type Manager interface {
 	RegisterFailure(chainID ids.ID, validatorID ids.ShortID)
 	// RegisterChain registers a new chain with metrics under [namespace]
 	RegisterChain(ctx *snow.Context, namespace string) error
+	// DeregisterChain removes the benchlist of [chainID], if any
+	DeregisterChain(chainID ids.ID)
 	// IsBenched returns true if messages to [validatorID] regarding chain [chainID]
 	// should not be sent over the network and should immediately fail.
 	// Returns false if such messages should be sent, or if the chain is unknown.
@@ -35,6 +37,9 @@ type Manager interface {
 	// [validatorID] is benched. If called on an id.ShortID that does
 	// not map to a validator, it will return an empty array.
 	GetBenched(validatorID ids.ShortID) []ids.ID
+	// GetBenchedValidators returns the IDs of the validators that are
+	// currently benched on [chainID]. Returns nil if the chain is unknown.
+	GetBenchedValidators(chainID ids.ID) []ids.ShortID
 }
 
 // Config defines the configuration for a benchlist
@@ -100,6 +105,18 @@ func (m *manager) GetBenched(validatorID ids.ShortID) []ids.ID {
 	return benched
 }
 
+// GetBenchedValidators implements the Manager interface
+func (m *manager) GetBenchedValidators(chainID ids.ID) []ids.ShortID {
+	m.lock.RLock()
+	benchlist, exists := m.chainBenchlists[chainID]
+	m.lock.RUnlock()
+
+	if !exists {
+		return nil
+	}
+	return benchlist.Benched()
+}
+
 func (m *manager) RegisterChain(ctx *snow.Context, namespace string) error {
 	m.lock.Lock()
 	defer m.lock.Unlock()
@@ -131,6 +148,18 @@ func (m *manager) RegisterChain(ctx *snow.Context, namespace string) error {
 	return nil
 }
 
+// DeregisterChain implements the Manager interface
+func (m *manager) DeregisterChain(chainID ids.ID) {
+	m.lock.Lock()
+	benchlist, exists := m.chainBenchlists[chainID]
+	delete(m.chainBenchlists, chainID)
+	m.lock.Unlock()
+
+	if exists {
+		benchlist.Shutdown()
+	}
+}
+
 // RegisterResponse implements the Manager interface
 func (m *manager) RegisterResponse(chainID ids.ID, validatorID ids.ShortID) {
 	m.lock.RLock()
@@ -161,7 +190,9 @@ type noBenchlist struct{}
 func NewNoBenchlist() Manager { return &noBenchlist{} }
 
 func (noBenchlist) RegisterChain(*snow.Context, string) error { return nil }
+func (noBenchlist) DeregisterChain(ids.ID)                    {}
 func (noBenchlist) RegisterResponse(ids.ID, ids.ShortID)      {}
 func (noBenchlist) RegisterFailure(ids.ID, ids.ShortID)       {}
 func (noBenchlist) IsBenched(ids.ShortID, ids.ID) bool        { return false }
 func (noBenchlist) GetBenched(ids.ShortID) []ids.ID           { return nil }
+func (noBenchlist) GetBenchedValidators(ids.ID) []ids.ShortID { return nil }
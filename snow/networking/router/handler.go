@@ -524,6 +524,9 @@ func (h *Handler) Shutdown() {
 	h.serviceQueue.Shutdown()
 }
 
+// Closed returns a channel that is closed once the engine has been shut down
+func (h *Handler) Closed() <-chan struct{} { return h.closed }
+
 func (h *Handler) shutdownDispatch() {
 	h.ctx.Lock.Lock()
 	defer h.ctx.Lock.Unlock()
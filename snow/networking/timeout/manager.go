@@ -45,9 +45,18 @@ func (m *Manager) IsBenched(validatorID ids.ShortID, chainID ids.ID) bool {
 	return m.benchlistMgr.IsBenched(validatorID, chainID)
 }
 
+// Benched returns the IDs of the validators that are currently benched on
+// [chainID]
+func (m *Manager) Benched(chainID ids.ID) []ids.ShortID {
+	return m.benchlistMgr.GetBenchedValidators(chainID)
+}
+
 // RegisterChain ...
 func (m *Manager) RegisterChain(ctx *snow.Context, namespace string) error {
-	if err := m.metrics.RegisterChain(ctx, namespace); err != nil {
+	m.lock.Lock()
+	err := m.metrics.RegisterChain(ctx, namespace)
+	m.lock.Unlock()
+	if err != nil {
 		return fmt.Errorf("couldn't register timeout metrics for chain %s: %w", ctx.ChainID, err)
 	}
 	if err := m.benchlistMgr.RegisterChain(ctx, namespace); err != nil {
@@ -56,6 +65,16 @@ func (m *Manager) RegisterChain(ctx *snow.Context, namespace string) error {
 	return nil
 }
 
+// DeregisterChain removes the state kept for [chainID], so that the chain can
+// be registered again
+func (m *Manager) DeregisterChain(chainID ids.ID) {
+	m.lock.Lock()
+	m.metrics.DeregisterChain(chainID)
+	m.lock.Unlock()
+
+	m.benchlistMgr.DeregisterChain(chainID)
+}
+
 // RegisterRequests notes that we sent a request of type [msgType] to [validatorID]
 // regarding chain [chainID]. If we don't receive a response in time, [timeoutHandler]
 // is executed.
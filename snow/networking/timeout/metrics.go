@@ -75,6 +75,12 @@ func (m *metrics) RegisterChain(ctx *snow.Context, namespace string) error {
 
 }
 
+// DeregisterChain removes the metrics of [chainID]. The metrics must be
+// unregistered by the owner of the chain's registerer.
+func (m *metrics) DeregisterChain(chainID ids.ID) {
+	delete(m.chainToMetrics, chainID)
+}
+
 // Record that a response to a message of type [msgType] regarding chain [chainID] took [latency]
 func (m *metrics) observe(chainID ids.ID, msgType constants.MsgType, latency time.Duration) {
 	cm, exists := m.chainToMetrics[chainID]
@@ -0,0 +1,20 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package validators
+
+import (
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+// SetCallbackListener is notified of changes to the validators in a set.
+// Listeners are notified after a change has been applied, and only if it
+// succeeded. The set's lock isn't held while a listener is called, so the
+// listener may read from the set, for example with Weight or Sample. Changes
+// are reported in the order they were made, so a listener must not modify the
+// set it is listening to, or it will deadlock.
+type SetCallbackListener interface {
+	OnValidatorAdded(validatorID ids.ShortID, weight uint64)
+	OnValidatorRemoved(validatorID ids.ShortID, weight uint64)
+	OnValidatorWeightChanged(validatorID ids.ShortID, oldWeight, newWeight uint64)
+}
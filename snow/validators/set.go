@@ -5,6 +5,9 @@ package validators
 
 import (
 	"fmt"
+	"math"
+	"math/rand"
+	"sort"
 	"strings"
 	"sync"
 
@@ -65,12 +68,24 @@ type Set interface {
 	// If sampling the requested size isn't possible, an error will be returned.
 	Sample(size int) ([]Validator, error)
 
+	// SampleWithoutReplacement returns [size] distinct validators, sampled
+	// with probability proportional to their weight using [rng]. If fewer
+	// than [size] validators can be sampled, every sampleable validator is
+	// returned.
+	SampleWithoutReplacement(size int, rng *rand.Rand) []ids.ShortID
+
 	// MaskValidator hides the named validator from future samplings
 	MaskValidator(ids.ShortID) error
 
 	// RevealValidator ensures the named validator is not hidden from future
 	// samplings
 	RevealValidator(ids.ShortID) error
+
+	// RegisterCallbackListener notifies [listener] of every future change to
+	// the validators in the set. [listener] is immediately notified of every
+	// current validator as if it was added. See SetCallbackListener for the
+	// locking rules that [listener] must follow.
+	RegisterCallbackListener(listener SetCallbackListener)
 }
 
 // NewSet returns a new, empty set of validators.
@@ -101,17 +116,39 @@ type set struct {
 	sampler          sampler.WeightedWithoutReplacement
 	totalWeight      uint64
 	maskedVdrs       ids.ShortSet
+	callbacks        []SetCallbackListener
+
+	// callbackLock is held while listeners are notified, so that they are
+	// notified of changes in the order the changes were made, without
+	// holding [lock]
+	callbackLock sync.Mutex
+}
+
+// change to the weight of a validator. A validator that was added has an old
+// weight of 0, and a validator that was removed has a new weight of 0.
+type change struct {
+	vdrID                ids.ShortID
+	oldWeight, newWeight uint64
 }
 
 // Set implements the Set interface.
 func (s *set) Set(vdrs []Validator) error {
 	s.lock.Lock()
-	defer s.lock.Unlock()
-
-	return s.set(vdrs)
+	changes, err := s.set(vdrs)
+	s.unlockAndNotify(changes, err)
+	return err
 }
 
-func (s *set) set(vdrs []Validator) error {
+func (s *set) set(vdrs []Validator) ([]change, error) {
+	// The previous weights are only needed to notify the listeners
+	var oldWeights map[ids.ShortID]uint64
+	if len(s.callbacks) > 0 {
+		oldWeights = make(map[ids.ShortID]uint64, len(s.vdrSlice))
+		for i, vdr := range s.vdrSlice {
+			oldWeights[vdr.ID()] = s.vdrWeights[i]
+		}
+	}
+
 	lenVdrs := len(vdrs)
 	// If the underlying arrays are much larger than necessary, resize them to
 	// allow garbage collection of unused memory
@@ -157,24 +194,50 @@ func (s *set) set(vdrs []Validator) error {
 
 		newTotalWeight, err := safemath.Add64(s.totalWeight, w)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		s.totalWeight = newTotalWeight
 	}
-	return s.sampler.Initialize(s.vdrMaskedWeights)
+	if err := s.sampler.Initialize(s.vdrMaskedWeights); err != nil {
+		return nil, err
+	}
+
+	var changes []change
+	if len(s.callbacks) > 0 {
+		for i, vdr := range s.vdrSlice {
+			vdrID := vdr.ID()
+			newWeight := s.vdrWeights[i]
+			if oldWeight := oldWeights[vdrID]; oldWeight != newWeight {
+				changes = append(changes, change{
+					vdrID:     vdrID,
+					oldWeight: oldWeight,
+					newWeight: newWeight,
+				})
+			}
+		}
+		for vdrID, oldWeight := range oldWeights {
+			if !s.contains(vdrID) {
+				changes = append(changes, change{
+					vdrID:     vdrID,
+					oldWeight: oldWeight,
+				})
+			}
+		}
+	}
+	return changes, nil
 }
 
 // Add implements the Set interface.
 func (s *set) AddWeight(vdrID ids.ShortID, weight uint64) error {
 	s.lock.Lock()
-	defer s.lock.Unlock()
-
-	return s.addWeight(vdrID, weight)
+	changes, err := s.addWeight(vdrID, weight)
+	s.unlockAndNotify(changes, err)
+	return err
 }
 
-func (s *set) addWeight(vdrID ids.ShortID, weight uint64) error {
+func (s *set) addWeight(vdrID ids.ShortID, weight uint64) ([]change, error) {
 	if weight == 0 {
-		return nil // This validator would never be sampled anyway
+		return nil, nil // This validator would never be sampled anyway
 	}
 
 	var vdr *validator
@@ -192,21 +255,34 @@ func (s *set) addWeight(vdrID ids.ShortID, weight uint64) error {
 		vdr = s.vdrSlice[i]
 	}
 
+	oldWeight := s.vdrWeights[i]
 	s.vdrWeights[i] += weight
 	vdr.addWeight(weight)
 
+	var changes []change
+	if len(s.callbacks) > 0 {
+		changes = []change{{
+			vdrID:     vdrID,
+			oldWeight: oldWeight,
+			newWeight: s.vdrWeights[i],
+		}}
+	}
+
 	if s.maskedVdrs.Contains(vdrID) {
-		return nil
+		return changes, nil
 	}
 	s.vdrMaskedWeights[i] += weight
 
 	newTotalWeight, err := safemath.Add64(s.totalWeight, weight)
 	if err != nil {
-		return nil
+		return changes, nil
 	}
 	s.totalWeight = newTotalWeight
 
-	return s.sampler.Initialize(s.vdrMaskedWeights)
+	if err := s.sampler.Initialize(s.vdrMaskedWeights); err != nil {
+		return nil, err
+	}
+	return changes, nil
 }
 
 // GetWeight implements the Set interface.
@@ -247,25 +323,26 @@ func (s *set) SubsetWeight(subset ids.ShortSet) (uint64, error) {
 // RemoveWeight implements the Set interface.
 func (s *set) RemoveWeight(vdrID ids.ShortID, weight uint64) error {
 	s.lock.Lock()
-	defer s.lock.Unlock()
-
-	return s.removeWeight(vdrID, weight)
+	changes, err := s.removeWeight(vdrID, weight)
+	s.unlockAndNotify(changes, err)
+	return err
 }
 
-func (s *set) removeWeight(vdrID ids.ShortID, weight uint64) error {
+func (s *set) removeWeight(vdrID ids.ShortID, weight uint64) ([]change, error) {
 	if weight == 0 {
-		return nil
+		return nil, nil
 	}
 
 	i, ok := s.vdrMap[vdrID]
 	if !ok {
-		return nil
+		return nil, nil
 	}
 
 	// Validator exists
 	vdr := s.vdrSlice[i]
 
-	weight = safemath.Min64(s.vdrWeights[i], weight)
+	oldWeight := s.vdrWeights[i]
+	weight = safemath.Min64(oldWeight, weight)
 	s.vdrWeights[i] -= weight
 	vdr.removeWeight(weight)
 	if !s.maskedVdrs.Contains(vdrID) {
@@ -273,12 +350,23 @@ func (s *set) removeWeight(vdrID ids.ShortID, weight uint64) error {
 		s.vdrMaskedWeights[i] -= weight
 	}
 
+	var changes []change
+	if len(s.callbacks) > 0 {
+		changes = []change{{
+			vdrID:     vdrID,
+			oldWeight: oldWeight,
+			newWeight: s.vdrWeights[i],
+		}}
+	}
 	if vdr.Weight() == 0 {
 		if err := s.remove(vdrID); err != nil {
-			return err
+			return nil, err
 		}
 	}
-	return s.sampler.Initialize(s.vdrMaskedWeights)
+	if err := s.sampler.Initialize(s.vdrMaskedWeights); err != nil {
+		return nil, err
+	}
+	return changes, nil
 }
 
 // Get implements the Set interface.
@@ -392,6 +480,48 @@ func (s *set) sample(size int) ([]Validator, error) {
 	return list, nil
 }
 
+// SampleWithoutReplacement implements the Set interface.
+func (s *set) SampleWithoutReplacement(size int, rng *rand.Rand) []ids.ShortID {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.sampleWithoutReplacement(size, rng)
+}
+
+// sampleWithoutReplacement uses the A-Res algorithm: each validator is given
+// the key u^(1/weight), where u is uniform in (0, 1], and the validators with
+// the largest keys are sampled. ln(u)/weight is used as the key to avoid
+// losing precision.
+func (s *set) sampleWithoutReplacement(size int, rng *rand.Rand) []ids.ShortID {
+	type sampleKey struct {
+		index int
+		key   float64
+	}
+	keys := make([]sampleKey, 0, len(s.vdrSlice))
+	for i, weight := range s.vdrMaskedWeights {
+		if weight == 0 {
+			continue // masked validators can't be sampled
+		}
+		keys = append(keys, sampleKey{
+			index: i,
+			key:   math.Log(1-rng.Float64()) / float64(weight),
+		})
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].key > keys[j].key })
+
+	if size > len(keys) {
+		size = len(keys)
+	}
+	if size < 0 {
+		size = 0
+	}
+	sampled := make([]ids.ShortID, size)
+	for i, key := range keys[:size] {
+		sampled[i] = s.vdrSlice[key.index].ID()
+	}
+	return sampled
+}
+
 func (s *set) Weight() uint64 {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
@@ -431,6 +561,58 @@ func (s *set) string() string {
 	return sb.String()
 }
 
+// RegisterCallbackListener implements the Set interface.
+func (s *set) RegisterCallbackListener(listener SetCallbackListener) {
+	s.lock.Lock()
+	s.callbacks = append(s.callbacks, listener)
+	current := make([]change, len(s.vdrSlice))
+	for i, vdr := range s.vdrSlice {
+		current[i] = change{
+			vdrID:     vdr.ID(),
+			newWeight: s.vdrWeights[i],
+		}
+	}
+
+	// The listeners are notified without holding [s.lock], but
+	// [s.callbackLock] is acquired first so that the listener is notified of
+	// the current validators before any later changes
+	s.callbackLock.Lock()
+	s.lock.Unlock()
+	defer s.callbackLock.Unlock()
+
+	for _, c := range current {
+		listener.OnValidatorAdded(c.vdrID, c.newWeight)
+	}
+}
+
+// unlockAndNotify releases [s.lock] and then notifies the listeners of
+// [changes], unless the changes failed with [err].
+// Assumes [s.lock] is held
+func (s *set) unlockAndNotify(changes []change, err error) {
+	if err != nil || len(changes) == 0 {
+		s.lock.Unlock()
+		return
+	}
+	callbacks := s.callbacks
+
+	s.callbackLock.Lock()
+	s.lock.Unlock()
+	defer s.callbackLock.Unlock()
+
+	for _, c := range changes {
+		for _, callback := range callbacks {
+			switch {
+			case c.oldWeight == 0:
+				callback.OnValidatorAdded(c.vdrID, c.newWeight)
+			case c.newWeight == 0:
+				callback.OnValidatorRemoved(c.vdrID, c.oldWeight)
+			default:
+				callback.OnValidatorWeightChanged(c.vdrID, c.oldWeight, c.newWeight)
+			}
+		}
+	}
+}
+
 func (s *set) MaskValidator(vdrID ids.ShortID) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
@@ -5,6 +5,7 @@ package validators
 
 import (
 	"math"
+	"math/rand"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -95,6 +96,60 @@ func TestSamplerDuplicate(t *testing.T) {
 	assert.Equal(t, vdr1, sampled[0].ID(), "should have sampled vdr1")
 }
 
+func TestSampleWithoutReplacementDistinct(t *testing.T) {
+	vdr0 := ids.ShortID{0}
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+
+	s := NewTestSet(map[ids.ShortID]uint64{
+		vdr0: 1,
+		vdr1: math.MaxInt64 - 2,
+		vdr2: 1,
+	})
+	rng := rand.New(rand.NewSource(0)) // #nosec G404
+
+	for i := 0; i < 100; i++ {
+		sampled := s.SampleWithoutReplacement(2, rng)
+		assert.Len(t, sampled, 2, "should have sampled two validators")
+		assert.NotEqual(t, sampled[0], sampled[1], "should have sampled distinct validators")
+	}
+
+	sampled := s.SampleWithoutReplacement(4, rng)
+	assert.Len(t, sampled, 3, "should have sampled every validator")
+	assert.ElementsMatch(t, []ids.ShortID{vdr0, vdr1, vdr2}, sampled, "should have sampled every validator")
+
+	err := s.MaskValidator(vdr1)
+	assert.NoError(t, err)
+
+	sampled = s.SampleWithoutReplacement(3, rng)
+	assert.ElementsMatch(t, []ids.ShortID{vdr0, vdr2}, sampled, "shouldn't have sampled a masked validator")
+}
+
+func TestSampleWithoutReplacementProportional(t *testing.T) {
+	vdr0 := ids.ShortID{0}
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+
+	s := NewTestSet(map[ids.ShortID]uint64{
+		vdr0: 1,
+		vdr1: 3,
+		vdr2: 6,
+	})
+	rng := rand.New(rand.NewSource(0)) // #nosec G404
+
+	const numDraws = 10000
+	counts := make(map[ids.ShortID]int)
+	for i := 0; i < numDraws; i++ {
+		sampled := s.SampleWithoutReplacement(1, rng)
+		assert.Len(t, sampled, 1, "should have sampled one validator")
+		counts[sampled[0]]++
+	}
+
+	assert.InDelta(t, .1, float64(counts[vdr0])/numDraws, .02, "vdr0 sampled disproportionately")
+	assert.InDelta(t, .3, float64(counts[vdr1])/numDraws, .02, "vdr1 sampled disproportionately")
+	assert.InDelta(t, .6, float64(counts[vdr2])/numDraws, .02, "vdr2 sampled disproportionately")
+}
+
 func TestSamplerContains(t *testing.T) {
 	vdr := ids.GenerateTestShortID()
 
@@ -255,3 +310,123 @@ func TestSamplerMasked(t *testing.T) {
 		assert.Equal(t, expected, result, "wrong string returned")
 	}
 }
+
+type callbackEvent struct {
+	validatorID          ids.ShortID
+	oldWeight, newWeight uint64
+}
+
+type callbackListener struct {
+	added, removed, changed []callbackEvent
+}
+
+func (c *callbackListener) OnValidatorAdded(validatorID ids.ShortID, weight uint64) {
+	c.added = append(c.added, callbackEvent{validatorID: validatorID, newWeight: weight})
+}
+
+func (c *callbackListener) OnValidatorRemoved(validatorID ids.ShortID, weight uint64) {
+	c.removed = append(c.removed, callbackEvent{validatorID: validatorID, oldWeight: weight})
+}
+
+func (c *callbackListener) OnValidatorWeightChanged(validatorID ids.ShortID, oldWeight, newWeight uint64) {
+	c.changed = append(c.changed, callbackEvent{validatorID: validatorID, oldWeight: oldWeight, newWeight: newWeight})
+}
+
+func TestSetRegisterCallbackListener(t *testing.T) {
+	vdr0 := ids.ShortID{0}
+	vdr1 := ids.ShortID{1}
+
+	s := NewSet()
+	err := s.AddWeight(vdr0, 1)
+	assert.NoError(t, err)
+
+	listener := &callbackListener{}
+	s.RegisterCallbackListener(listener)
+	assert.Equal(t, []callbackEvent{{validatorID: vdr0, newWeight: 1}}, listener.added, "should have been notified of the existing validator")
+
+	err = s.AddWeight(vdr1, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, callbackEvent{validatorID: vdr1, newWeight: 10}, listener.added[1], "should have been notified of the new validator")
+
+	err = s.AddWeight(vdr1, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, []callbackEvent{{validatorID: vdr1, oldWeight: 10, newWeight: 15}}, listener.changed, "should have been notified of the added weight")
+
+	err = s.RemoveWeight(vdr1, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, callbackEvent{validatorID: vdr1, oldWeight: 15, newWeight: 10}, listener.changed[1], "should have been notified of the removed weight")
+
+	err = s.RemoveWeight(vdr1, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []callbackEvent{{validatorID: vdr1, oldWeight: 10}}, listener.removed, "should have been notified of the removed validator")
+	assert.Len(t, listener.changed, 2, "removing a validator shouldn't be reported as a weight change")
+}
+
+func TestSetCallbackListenerSet(t *testing.T) {
+	vdr0 := ids.ShortID{0}
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+
+	s := NewSet()
+	err := s.Set([]Validator{
+		NewValidator(vdr0, 1),
+		NewValidator(vdr1, 2),
+	})
+	assert.NoError(t, err)
+
+	listener := &callbackListener{}
+	s.RegisterCallbackListener(listener)
+	assert.Len(t, listener.added, 2, "should have been notified of the existing validators")
+
+	err = s.Set([]Validator{
+		NewValidator(vdr1, 3),
+		NewValidator(vdr2, 4),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, callbackEvent{validatorID: vdr2, newWeight: 4}, listener.added[2], "should have been notified of the new validator")
+	assert.Equal(t, []callbackEvent{{validatorID: vdr1, oldWeight: 2, newWeight: 3}}, listener.changed, "should have been notified of the changed weight")
+	assert.Equal(t, []callbackEvent{{validatorID: vdr0, oldWeight: 1}}, listener.removed, "should have been notified of the removed validator")
+}
+
+type reentrantListener struct {
+	callbackListener
+	s       Set
+	weights []uint64
+}
+
+func (r *reentrantListener) OnValidatorAdded(validatorID ids.ShortID, weight uint64) {
+	r.callbackListener.OnValidatorAdded(validatorID, weight)
+	r.weights = append(r.weights, r.s.Weight())
+}
+
+func TestSetCallbackListenerCallsIntoSet(t *testing.T) {
+	vdr0 := ids.ShortID{0}
+	vdr1 := ids.ShortID{1}
+
+	s := NewSet()
+	err := s.AddWeight(vdr0, 1)
+	assert.NoError(t, err)
+
+	listener := &reentrantListener{s: s}
+	s.RegisterCallbackListener(listener)
+
+	err = s.AddWeight(vdr1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{1, 3}, listener.weights, "listener should have observed the set after each change was applied")
+}
+
+func TestSetCallbackListenerFailedAddWeight(t *testing.T) {
+	vdr0 := ids.ShortID{0}
+	vdr1 := ids.ShortID{1}
+
+	s := NewSet()
+	err := s.AddWeight(vdr0, math.MaxInt64)
+	assert.NoError(t, err)
+
+	listener := &callbackListener{}
+	s.RegisterCallbackListener(listener)
+
+	err = s.AddWeight(vdr1, 1)
+	assert.Error(t, err, "should have errored due to the sampler rejecting the total weight")
+	assert.Len(t, listener.added, 1, "shouldn't have been notified of a failed change")
+}
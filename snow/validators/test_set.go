@@ -0,0 +1,30 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package validators
+
+import (
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+// NewTestSet returns a new set of validators with the provided [weights].
+// Validators are added in order of their IDs, so the returned set is
+// deterministic. Panics if the total weight overflows.
+func NewTestSet(weights map[ids.ShortID]uint64) Set {
+	vdrIDs := make([]ids.ShortID, 0, len(weights))
+	for vdrID := range weights {
+		vdrIDs = append(vdrIDs, vdrID)
+	}
+	ids.SortShortIDs(vdrIDs)
+
+	vdrs := make([]Validator, len(vdrIDs))
+	for i, vdrID := range vdrIDs {
+		vdrs[i] = NewValidator(vdrID, weights[vdrID])
+	}
+
+	s := NewSet()
+	if err := s.Set(vdrs); err != nil {
+		panic(err)
+	}
+	return s
+}
@@ -0,0 +1,49 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package validators
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+func TestNewTestSet(t *testing.T) {
+	vdr0 := ids.ShortID{0}
+	vdr1 := ids.ShortID{1}
+	vdr2 := ids.ShortID{2}
+
+	s := NewTestSet(map[ids.ShortID]uint64{
+		vdr0: 1,
+		vdr1: 10,
+		vdr2: 100,
+	})
+
+	assert.Equal(t, 3, s.Len(), "wrong number of validators")
+	assert.Equal(t, uint64(111), s.Weight(), "wrong total weight")
+
+	weight, ok := s.GetWeight(vdr0)
+	assert.True(t, ok, "should have contained vdr0")
+	assert.Equal(t, uint64(1), weight, "wrong weight for vdr0")
+
+	weight, ok = s.GetWeight(vdr1)
+	assert.True(t, ok, "should have contained vdr1")
+	assert.Equal(t, uint64(10), weight, "wrong weight for vdr1")
+
+	weight, ok = s.GetWeight(vdr2)
+	assert.True(t, ok, "should have contained vdr2")
+	assert.Equal(t, uint64(100), weight, "wrong weight for vdr2")
+}
+
+func TestNewTestSetOverflow(t *testing.T) {
+	assert.Panics(t, func() {
+		NewTestSet(map[ids.ShortID]uint64{
+			{0}: math.MaxUint64,
+			{1}: 1,
+		})
+	}, "should have panicked due to overflow")
+}
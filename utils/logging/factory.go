@@ -3,13 +3,29 @@
 
 package logging
 
-import "path/filepath"
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+)
 
 // Factory ...
 type Factory interface {
 	Make() (Logger, error)
 	MakeChain(chainID string, subdir string) (Logger, error)
 	MakeSubdir(subdir string) (Logger, error)
+
+	// SetLogLevel sets the log level of the logger with [name]
+	SetLogLevel(name string, level Level) error
+
+	// SetDisplayLevel sets the display level of the logger with [name]
+	SetDisplayLevel(name string, level Level) error
+
+	// GetLoggerNames returns the names of the loggers that have been made,
+	// in sorted order
+	GetLoggerNames() []string
+
 	Close()
 }
 
@@ -17,23 +33,27 @@ type Factory interface {
 type factory struct {
 	config Config
 
-	loggers []Logger
+	lock sync.RWMutex
+
+	// loggers maps the names of the loggers that have been made to the most
+	// recently made logger with that name
+	loggers map[string]Logger
+
+	// made is every logger that has been made, so they can all be stopped
+	made []Logger
 }
 
 // NewFactory ...
 func NewFactory(config Config) Factory {
 	return &factory{
-		config: config,
+		config:  config,
+		loggers: make(map[string]Logger),
 	}
 }
 
 // Make ...
 func (f *factory) Make() (Logger, error) {
-	l, err := New(f.config)
-	if err == nil {
-		f.loggers = append(f.loggers, l)
-	}
-	return l, err
+	return f.make("main", f.config)
 }
 
 // MakeChain ...
@@ -42,11 +62,7 @@ func (f *factory) MakeChain(chainID string, subdir string) (Logger, error) {
 	config.MsgPrefix = chainID + " Chain"
 	config.Directory = filepath.Join(config.Directory, "chain", chainID, subdir)
 
-	log, err := New(config)
-	if err == nil {
-		f.loggers = append(f.loggers, log)
-	}
-	return log, err
+	return f.make(filepath.Join(chainID, subdir), config)
 }
 
 // MakeSubdir ...
@@ -54,17 +70,70 @@ func (f *factory) MakeSubdir(subdir string) (Logger, error) {
 	config := f.config
 	config.Directory = filepath.Join(config.Directory, subdir)
 
+	return f.make(subdir, config)
+}
+
+func (f *factory) make(name string, config Config) (Logger, error) {
 	log, err := New(config)
-	if err == nil {
-		f.loggers = append(f.loggers, log)
+	if err != nil {
+		return log, err
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.loggers[name] = log
+	f.made = append(f.made, log)
+	return log, nil
+}
+
+// SetLogLevel ...
+func (f *factory) SetLogLevel(name string, level Level) error {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	log, ok := f.loggers[name]
+	if !ok {
+		return fmt.Errorf("logger with name %q not found", name)
 	}
-	return log, err
+	log.SetLogLevel(level)
+	return nil
+}
+
+// SetDisplayLevel ...
+func (f *factory) SetDisplayLevel(name string, level Level) error {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	log, ok := f.loggers[name]
+	if !ok {
+		return fmt.Errorf("logger with name %q not found", name)
+	}
+	log.SetDisplayLevel(level)
+	return nil
+}
+
+// GetLoggerNames ...
+func (f *factory) GetLoggerNames() []string {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	names := make([]string, 0, len(f.loggers))
+	for name := range f.loggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
 // Close ...
 func (f *factory) Close() {
-	for _, log := range f.loggers {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for _, log := range f.made {
 		log.Stop()
 	}
-	f.loggers = nil
+	f.loggers = make(map[string]Logger)
+	f.made = nil
 }
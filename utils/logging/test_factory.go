@@ -17,3 +17,12 @@ func (NoFactory) MakeSubdir(string) (Logger, error) { return NoLog{}, nil }
 
 // Close ...
 func (NoFactory) Close() {}
+
+// SetLogLevel ...
+func (NoFactory) SetLogLevel(string, Level) error { return nil }
+
+// SetDisplayLevel ...
+func (NoFactory) SetDisplayLevel(string, Level) error { return nil }
+
+// GetLoggerNames ...
+func (NoFactory) GetLoggerNames() []string { return nil }
@@ -33,4 +33,15 @@ var (
 		float64(time.Second),
 		// anything larger than a second will be bucketed together
 	}
+	SecondsBuckets = []float64{
+		1,   // 1 second
+		2,   // 2 seconds
+		5,   // 5 seconds
+		10,  // 10 seconds
+		30,  // 30 seconds
+		60,  // 1 minute
+		120, // 2 minutes
+		300, // 5 minutes
+		// anything larger than 5 minutes will be bucketed together
+	}
 )